@@ -0,0 +1,407 @@
+package blaster
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// BalancingStrategy names one of the built-in load balancing strategies
+type BalancingStrategy string
+
+const (
+	// RoundRobin hands out endpoints in order, wrapping around at the end
+	RoundRobin BalancingStrategy = "round-robin"
+
+	// Random picks an endpoint at random
+	Random BalancingStrategy = "random"
+
+	// LeastOutstanding picks the endpoint with the fewest in-flight requests
+	LeastOutstanding BalancingStrategy = "least-outstanding"
+
+	// Weighted picks an endpoint at random, proportional to its weight
+	Weighted BalancingStrategy = "weighted"
+)
+
+// maxSharedBalancing is the number of endpoint lists, and of resolvers
+// that are not a BalancerSource, whose balancing state is kept between
+// clients.  The least recently used are dropped past it.
+const maxSharedBalancing = 256
+
+var (
+	errNoEndpoints = errors.New("no endpoints available for request")
+
+	pkgBalancingMu     sync.Mutex
+	pkgStaticResolvers = newLRU(maxSharedBalancing)
+	pkgBalancers       = newLRU(maxSharedBalancing)
+)
+
+// Endpoint is a single upstream instance that requests can be balanced across
+type Endpoint struct {
+	// URL is the base url of the instance.  The path of the client
+	// endpoint is appended to it when a request is launched.
+	URL *url.URL
+
+	// Weight is the relative share of traffic the instance receives
+	// when the Weighted strategy is used.  Anything below 1 counts as 1.
+	Weight int
+
+	// number of requests currently in flight to this instance
+	outstanding int64
+}
+
+// NewEndpoint parses the raw url into an endpoint with the given weight
+func NewEndpoint(rawurl string, weight int) (*Endpoint, error) {
+	u, err := url.ParseRequestURI(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Endpoint{URL: u, Weight: weight}, nil
+}
+
+// Outstanding returns the number of requests currently in flight to the endpoint
+func (e *Endpoint) Outstanding() int64 {
+	return atomic.LoadInt64(&e.outstanding)
+}
+
+// String implements fmt.Stringer
+func (e *Endpoint) String() string {
+	return e.URL.String()
+}
+
+// acquire marks a request to this endpoint as in flight
+func (e *Endpoint) acquire() {
+	atomic.AddInt64(&e.outstanding, 1)
+}
+
+// release marks a request to this endpoint as finished
+func (e *Endpoint) release() {
+	atomic.AddInt64(&e.outstanding, -1)
+}
+
+// weight normalizes the endpoint weight
+func (e *Endpoint) weight() int {
+	if e.Weight < 1 {
+		return 1
+	}
+
+	return e.Weight
+}
+
+// staticResolver always resolves to the same set of endpoints
+type staticResolver struct {
+	endpoints []*Endpoint
+	balancers balancerSet
+}
+
+// NewStaticResolver returns a resolver that always resolves to the given endpoints,
+// regardless of the called service
+func NewStaticResolver(endpoints ...*Endpoint) EndpointResolver {
+	return &staticResolver{endpoints: endpoints}
+}
+
+// Resolve implements EndpointResolver
+func (r *staticResolver) Resolve(ctx context.Context, service string) ([]*Endpoint, error) {
+	return r.endpoints, nil
+}
+
+// Balancer implements BalancerSource
+func (r *staticResolver) Balancer(strategy BalancingStrategy) Balancer {
+	return r.balancers.get(strategy)
+}
+
+// roundRobinBalancer hands out endpoints in order
+type roundRobinBalancer struct {
+	next uint64
+}
+
+// Pick implements Balancer
+func (b *roundRobinBalancer) Pick(ctx context.Context, candidates []*Endpoint) (*Endpoint, error) {
+	if len(candidates) == 0 {
+		return nil, errNoEndpoints
+	}
+
+	n := atomic.AddUint64(&b.next, 1) - 1
+
+	return candidates[n%uint64(len(candidates))], nil
+}
+
+// randomBalancer picks endpoints at random
+type randomBalancer struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// Pick implements Balancer
+func (b *randomBalancer) Pick(ctx context.Context, candidates []*Endpoint) (*Endpoint, error) {
+	if len(candidates) == 0 {
+		return nil, errNoEndpoints
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return candidates[b.rnd.Intn(len(candidates))], nil
+}
+
+// leastOutstandingBalancer picks the endpoint with the fewest requests in flight.
+// Ties are broken by rotating the starting point, so idle endpoints share the load.
+type leastOutstandingBalancer struct {
+	next uint64
+}
+
+// Pick implements Balancer
+func (b *leastOutstandingBalancer) Pick(ctx context.Context, candidates []*Endpoint) (*Endpoint, error) {
+	if len(candidates) == 0 {
+		return nil, errNoEndpoints
+	}
+
+	start := int((atomic.AddUint64(&b.next, 1) - 1) % uint64(len(candidates)))
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		candidate := candidates[(start+i)%len(candidates)]
+		if candidate.Outstanding() < best.Outstanding() {
+			best = candidate
+		}
+	}
+
+	return best, nil
+}
+
+// weightedBalancer picks endpoints at random, proportional to their weight
+type weightedBalancer struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// Pick implements Balancer
+func (b *weightedBalancer) Pick(ctx context.Context, candidates []*Endpoint) (*Endpoint, error) {
+	if len(candidates) == 0 {
+		return nil, errNoEndpoints
+	}
+
+	total := 0
+	for _, candidate := range candidates {
+		total += candidate.weight()
+	}
+
+	b.mu.Lock()
+	n := b.rnd.Intn(total)
+	b.mu.Unlock()
+
+	for _, candidate := range candidates {
+		n -= candidate.weight()
+		if n < 0 {
+			return candidate, nil
+		}
+	}
+
+	return candidates[len(candidates)-1], nil
+}
+
+// NewBalancer returns a new balancer for one of the built-in strategies.
+// An unknown or empty strategy falls back to RoundRobin.
+func NewBalancer(strategy BalancingStrategy) Balancer {
+	switch strategy {
+	case Random:
+		return &randomBalancer{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	case LeastOutstanding:
+		return &leastOutstandingBalancer{}
+	case Weighted:
+		return &weightedBalancer{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	case ConsistentHash:
+		return NewConsistentHashBalancer(nil)
+	default:
+		return &roundRobinBalancer{}
+	}
+}
+
+// balancerKey identifies a balancer shared between clients
+type balancerKey struct {
+	resolver EndpointResolver
+	strategy BalancingStrategy
+}
+
+// balancerSet keeps a balancer per strategy, for a BalancerSource
+type balancerSet struct {
+	mu        sync.Mutex
+	balancers map[BalancingStrategy]Balancer
+}
+
+// get returns the balancer for the strategy, creating it if needed
+func (s *balancerSet) get(strategy BalancingStrategy) Balancer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.balancers == nil {
+		s.balancers = map[BalancingStrategy]Balancer{}
+	}
+	balancer, ok := s.balancers[strategy]
+	if !ok {
+		balancer = NewBalancer(strategy)
+		s.balancers[strategy] = balancer
+	}
+
+	return balancer
+}
+
+// sharedStaticResolver returns the same resolver for the same list of
+// endpoints, so that in-flight counts survive between clients.  Clients
+// are usually built per request, so anything stateful has to live here.
+func sharedStaticResolver(endpoints []string) (EndpointResolver, error) {
+	key := strings.Join(endpoints, ",")
+
+	pkgBalancingMu.Lock()
+	defer pkgBalancingMu.Unlock()
+
+	if resolver, ok := pkgStaticResolvers.get(key); ok {
+		return resolver.(EndpointResolver), nil
+	}
+
+	eps := make([]*Endpoint, 0, len(endpoints))
+	for _, raw := range endpoints {
+		ep, err := NewEndpoint(raw, 1)
+		if err != nil {
+			return nil, err
+		}
+		eps = append(eps, ep)
+	}
+
+	resolver := NewStaticResolver(eps...)
+	pkgStaticResolvers.add(key, resolver)
+
+	return resolver, nil
+}
+
+// sharedBalancer returns the same balancer for the same resolver and
+// strategy, so that round robin positions survive between clients.
+// Resolvers that are a BalancerSource keep their balancers themselves.
+func sharedBalancer(resolver EndpointResolver, strategy BalancingStrategy) Balancer {
+	if source, ok := resolver.(BalancerSource); ok {
+		return source.Balancer(strategy)
+	}
+
+	// resolvers that cannot be used as a map key get their own balancer
+	if !reflect.TypeOf(resolver).Comparable() {
+		return NewBalancer(strategy)
+	}
+
+	key := balancerKey{resolver: resolver, strategy: strategy}
+
+	pkgBalancingMu.Lock()
+	defer pkgBalancingMu.Unlock()
+
+	if balancer, ok := pkgBalancers.get(key); ok {
+		return balancer.(Balancer)
+	}
+
+	balancer := NewBalancer(strategy)
+	pkgBalancers.add(key, balancer)
+
+	return balancer
+}
+
+// lru is a map that drops its least recently used entries past its size.
+// It is not safe for concurrent use.
+type lru struct {
+	size    int
+	order   *list.List
+	entries map[interface{}]*list.Element
+}
+
+// lruEntry is an element of the lru order
+type lruEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+// newLRU returns an empty lru of the given size
+func newLRU(size int) *lru {
+	return &lru{size: size, order: list.New(), entries: map[interface{}]*list.Element{}}
+}
+
+// get returns the value of the key, marking it as recently used
+func (l *lru) get(key interface{}) (interface{}, bool) {
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(element)
+
+	return element.Value.(*lruEntry).value, true
+}
+
+// add sets the value of the key, dropping the least recently used entry
+// when the lru is full
+func (l *lru) add(key, value interface{}) {
+	if element, ok := l.entries[key]; ok {
+		element.Value.(*lruEntry).value = value
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value})
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// len returns the number of entries
+func (l *lru) len() int {
+	return l.order.Len()
+}
+
+// joinEndpoint builds the url for a request to an instance by appending
+// the path and query of the client endpoint to the base url of the instance
+func joinEndpoint(base *url.URL, target *url.URL) *url.URL {
+	u := *base
+	if target.Path != "" {
+		u.Path = strings.TrimSuffix(base.Path, "/") + "/" + strings.TrimPrefix(target.Path, "/")
+	}
+	if target.RawQuery != "" {
+		u.RawQuery = target.RawQuery
+	}
+
+	return &u
+}
+
+// remainingEndpoints filters out the endpoints that have already been tried
+func remainingEndpoints(endpoints []*Endpoint, tried map[*Endpoint]bool) []*Endpoint {
+	remaining := make([]*Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if !tried[ep] {
+			remaining = append(remaining, ep)
+		}
+	}
+
+	return remaining
+}
+
+// isConnectionError determines whether an error happened while establishing
+// the connection, in which case the request never reached the endpoint and
+// is safe to send somewhere else
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
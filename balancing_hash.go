@@ -0,0 +1,100 @@
+package blaster
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+)
+
+// ConsistentHash sends requests with the same hash key to the same endpoint.
+// See WithHashKey and ClientOptions.HashKeyHeader for setting the key.
+const ConsistentHash BalancingStrategy = "consistent-hash"
+
+// hashKeyContextKey is the context key for the hash key
+type hashKeyContextKey struct{}
+
+// WithHashKey returns a context that carries the key used by the ConsistentHash
+// strategy, e.g. a tenant id.  Requests with the same key land on the same endpoint.
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKeyContextKey{}, key)
+}
+
+// HashKeyFromContext returns the hash key carried by the context, if any
+func HashKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(hashKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// consistentHashBalancer uses rendezvous (highest random weight) hashing.
+// Every endpoint is scored against the key and the highest score wins.
+// When an endpoint joins or leaves, only the keys it wins or won move.
+// Since ejected and unhealthy endpoints never reach the balancer, a key
+// whose endpoint is out of rotation falls back to its next best endpoint.
+type consistentHashBalancer struct {
+	keyFunc  func(ctx context.Context) (string, bool)
+	fallback Balancer
+}
+
+// NewConsistentHashBalancer returns a rendezvous hashing balancer that takes
+// its key from keyFunc.  If keyFunc is nil, the key is read from the context,
+// see WithHashKey.  Requests without a key are balanced round robin.
+func NewConsistentHashBalancer(keyFunc func(ctx context.Context) (string, bool)) Balancer {
+	if keyFunc == nil {
+		keyFunc = HashKeyFromContext
+	}
+
+	return &consistentHashBalancer{
+		keyFunc:  keyFunc,
+		fallback: &roundRobinBalancer{},
+	}
+}
+
+// Pick implements Balancer
+func (b *consistentHashBalancer) Pick(ctx context.Context, candidates []*Endpoint) (*Endpoint, error) {
+	if len(candidates) == 0 {
+		return nil, errNoEndpoints
+	}
+
+	key, ok := b.keyFunc(ctx)
+	if !ok {
+		return b.fallback.Pick(ctx, candidates)
+	}
+
+	var (
+		best      *Endpoint
+		bestScore float64
+	)
+	for _, candidate := range candidates {
+		if score := rendezvousScore(key, candidate); best == nil || score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+
+	return best, nil
+}
+
+// rendezvousScore scores an endpoint against a key.  The hash is mapped
+// onto (0,1) so that heavier endpoints win proportionally more keys.
+func rendezvousScore(key string, ep *Endpoint) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(ep.URL.String()))
+
+	// the top 53 bits fit a float64 exactly; +0.5 keeps us off 0 and 1
+	u := (float64(mix64(h.Sum64())>>11) + 0.5) / (1 << 53)
+
+	return float64(ep.weight()) / -math.Log(u)
+}
+
+// mix64 is the splitmix64 finalizer.  FNV spreads similar inputs, like urls
+// that differ in the last digit of a port, poorly on its own.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,160 @@
+package blaster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConsistentHash", func() {
+	var (
+		ctx       context.Context
+		endpoints []*Endpoint
+		balancer  Balancer
+		keys      []string
+	)
+
+	pickAll := func(candidates []*Endpoint) map[string]*Endpoint {
+		picks := map[string]*Endpoint{}
+		for _, key := range keys {
+			ep, err := balancer.Pick(WithHashKey(ctx, key), candidates)
+			Expect(err).To(BeNil())
+			picks[key] = ep
+		}
+		return picks
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		balancer = NewBalancer(ConsistentHash)
+
+		endpoints = []*Endpoint{}
+		for i := 0; i < 5; i++ {
+			ep, _ := NewEndpoint(fmt.Sprintf("http://10.0.0.1:808%d", i), 1)
+			endpoints = append(endpoints, ep)
+		}
+
+		keys = []string{}
+		for i := 0; i < 1000; i++ {
+			keys = append(keys, fmt.Sprintf("tenant-%d", i))
+		}
+	})
+
+	It("sends the same key to the same endpoint", func() {
+		first := pickAll(endpoints)
+		Expect(pickAll(endpoints)).To(Equal(first))
+	})
+
+	It("spreads keys across every endpoint", func() {
+		counts := map[*Endpoint]int{}
+		for _, ep := range pickAll(endpoints) {
+			counts[ep]++
+		}
+		Expect(counts).To(HaveLen(5))
+		for _, count := range counts {
+			Expect(count).To(BeNumerically("~", 200, 60))
+		}
+	})
+
+	It("only moves the keys of an endpoint that leaves", func() {
+		before := pickAll(endpoints)
+		after := pickAll(append([]*Endpoint{endpoints[0]}, endpoints[2:]...))
+
+		for key, ep := range before {
+			if ep != endpoints[1] {
+				Expect(after[key]).To(Equal(ep))
+			} else {
+				Expect(after[key]).ToNot(Equal(endpoints[1]))
+			}
+		}
+	})
+
+	It("only moves keys to an endpoint that joins", func() {
+		before := pickAll(endpoints[:4])
+		after := pickAll(endpoints)
+
+		for key, ep := range after {
+			if ep != endpoints[4] {
+				Expect(before[key]).To(Equal(ep))
+			}
+		}
+	})
+
+	It("balances requests without a key round robin", func() {
+		first, _ := balancer.Pick(ctx, endpoints)
+		second, _ := balancer.Pick(ctx, endpoints)
+		Expect(first).ToNot(Equal(second))
+	})
+
+	Context("client", func() {
+		var servers []*httptest.Server
+
+		BeforeEach(func() {
+			pkgRequireHeaders = false
+			servers = []*httptest.Server{}
+			endpoints = []*Endpoint{}
+			for i := 0; i < 3; i++ {
+				code := http.StatusOK + i
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(code)
+				}))
+				servers = append(servers, server)
+				ep, _ := NewEndpoint(server.URL, 1)
+				endpoints = append(endpoints, ep)
+			}
+		})
+
+		AfterEach(func() {
+			for _, server := range servers {
+				server.Close()
+			}
+		})
+
+		It("routes on the hash key header", func() {
+			resolver := NewStaticResolver(endpoints...)
+			codes := map[int]bool{}
+			for i := 0; i < 5; i++ {
+				c, err := New(ClientOptions{
+					Endpoint:          "/",
+					EndpointResolver:  resolver,
+					BalancingStrategy: ConsistentHash,
+					HashKeyHeader:     "X-Tenant-ID",
+					Headers:           map[string]string{"X-Tenant-ID": "tenant-42"},
+				})
+				Expect(err).To(BeNil())
+				code, err := c.Get(ctx)
+				Expect(err).To(BeNil())
+				codes[code] = true
+			}
+			Expect(codes).To(HaveLen(1))
+		})
+
+		It("falls back to the next endpoint when the chosen one is ejected", func() {
+			resolver := NewStaticResolver(endpoints...)
+			detector := NewOutlierDetector(OutlierDetection{ConsecutiveErrors: 1, BaseEjectionTime: time.Minute})
+			tenantCtx := WithHashKey(ctx, "tenant-42")
+
+			chosen, _ := balancer.Pick(tenantCtx, endpoints)
+			detector.Observe(endpoints, chosen, http.StatusBadGateway, nil, time.Millisecond)
+			Expect(detector.Ejected(chosen)).To(BeTrue())
+
+			for i := 0; i < 3; i++ {
+				c, err := New(ClientOptions{
+					Endpoint:          "/",
+					EndpointResolver:  resolver,
+					BalancingStrategy: ConsistentHash,
+					OutlierDetector:   detector,
+				})
+				Expect(err).To(BeNil())
+				_, err = c.Get(tenantCtx)
+				Expect(err).To(BeNil())
+				Expect(c.SelectedEndpoint().Host).ToNot(Equal(chosen.URL.Host))
+			}
+		})
+	})
+})
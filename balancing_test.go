@@ -0,0 +1,235 @@
+package blaster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// countingResolver is a resolver that is not a BalancerSource
+type countingResolver struct{ id int }
+
+func (r *countingResolver) Resolve(ctx context.Context, service string) ([]*Endpoint, error) {
+	return nil, nil
+}
+
+var _ = Describe("Balancing", func() {
+	var (
+		ctx       context.Context
+		endpoints []*Endpoint
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+
+		endpoints = []*Endpoint{}
+		for _, raw := range []string{"http://one:8080", "http://two:8080", "http://three:8080"} {
+			ep, err := NewEndpoint(raw, 1)
+			Expect(err).To(BeNil())
+			endpoints = append(endpoints, ep)
+		}
+	})
+
+	// region balancers
+	Describe("balancers", func() {
+		Context("round robin", func() {
+			It("hands out endpoints in order", func() {
+				b := NewBalancer(RoundRobin)
+				for i := 0; i < 6; i++ {
+					ep, err := b.Pick(ctx, endpoints)
+					Expect(err).To(BeNil())
+					Expect(ep).To(Equal(endpoints[i%3]))
+				}
+			})
+		})
+		Context("least outstanding", func() {
+			It("picks the endpoint with the fewest requests in flight", func() {
+				endpoints[0].acquire()
+				endpoints[0].acquire()
+				endpoints[2].acquire()
+
+				b := NewBalancer(LeastOutstanding)
+				for i := 0; i < 3; i++ {
+					ep, err := b.Pick(ctx, endpoints)
+					Expect(err).To(BeNil())
+					Expect(ep).To(Equal(endpoints[1]))
+				}
+			})
+		})
+		Context("weighted", func() {
+			It("favors the heavier endpoint", func() {
+				endpoints[0].Weight = 1000
+
+				b := NewBalancer(Weighted)
+				picks := map[*Endpoint]int{}
+				for i := 0; i < 100; i++ {
+					ep, err := b.Pick(ctx, endpoints)
+					Expect(err).To(BeNil())
+					picks[ep]++
+				}
+				Expect(picks[endpoints[0]]).To(BeNumerically(">", 90))
+			})
+		})
+		Context("random", func() {
+			It("only picks from the candidates", func() {
+				b := NewBalancer(Random)
+				for i := 0; i < 10; i++ {
+					ep, err := b.Pick(ctx, endpoints)
+					Expect(err).To(BeNil())
+					Expect(endpoints).To(ContainElement(ep))
+				}
+			})
+		})
+		Context("no candidates", func() {
+			It("returns an error", func() {
+				for _, strategy := range []BalancingStrategy{RoundRobin, Random, LeastOutstanding, Weighted} {
+					_, err := NewBalancer(strategy).Pick(ctx, nil)
+					Expect(err).To(Equal(errNoEndpoints))
+				}
+			})
+		})
+	})
+	// endregion
+
+	// region shared state
+	Describe("shared balancing state", func() {
+		It("keeps the balancers on the resolvers that are a BalancerSource", func() {
+			before := pkgBalancers.len()
+			for i := 0; i < maxSharedBalancing+10; i++ {
+				resolver := NewStaticResolver(endpoints...)
+				Expect(sharedBalancer(resolver, RoundRobin)).To(BeIdenticalTo(sharedBalancer(resolver, RoundRobin)))
+				Expect(sharedBalancer(resolver, Random)).ToNot(BeIdenticalTo(sharedBalancer(resolver, RoundRobin)))
+			}
+			Expect(pkgBalancers.len()).To(Equal(before))
+		})
+
+		It("stays bounded for new resolvers and endpoint lists", func() {
+			for i := 0; i < maxSharedBalancing+10; i++ {
+				resolver := &countingResolver{id: i}
+				Expect(sharedBalancer(resolver, RoundRobin)).To(BeIdenticalTo(sharedBalancer(resolver, RoundRobin)))
+
+				_, err := New(ClientOptions{Endpoint: "/health", Endpoints: []string{fmt.Sprintf("http://10.0.0.%d:8080", i%250), fmt.Sprintf("http://host-%d:8080", i)}})
+				Expect(err).To(BeNil())
+			}
+			Expect(pkgBalancers.len()).To(Equal(maxSharedBalancing))
+			Expect(pkgStaticResolvers.len()).To(Equal(maxSharedBalancing))
+		})
+
+		It("drops the least recently used entries", func() {
+			l := newLRU(2)
+			l.add("a", 1)
+			l.add("b", 2)
+			l.get("a")
+			l.add("c", 3)
+			_, ok := l.get("b")
+			Expect(ok).To(BeFalse())
+			value, ok := l.get("a")
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal(1))
+			Expect(l.len()).To(Equal(2))
+		})
+	})
+	// endregion
+
+	// region joinEndpoint
+	Describe("joinEndpoint", func() {
+		It("appends the path and query to the base url", func() {
+			base, _ := url.Parse("http://one:8080/api/")
+			target, _ := url.ParseRequestURI("/v1/users?limit=1")
+			Expect(joinEndpoint(base, target).String()).To(Equal("http://one:8080/api/v1/users?limit=1"))
+		})
+		It("keeps the base url when there is no path", func() {
+			base, _ := url.Parse("http://one:8080")
+			Expect(joinEndpoint(base, &url.URL{}).String()).To(Equal("http://one:8080"))
+		})
+	})
+	// endregion
+
+	// region requests
+	Describe("requests", func() {
+		var (
+			serverA *httptest.Server
+			serverB *httptest.Server
+			deadURL string
+		)
+
+		BeforeEach(func() {
+			serverA = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			serverB = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			}))
+
+			// grab a free port, then close it so nothing is listening there
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			deadURL = "http://" + l.Addr().String()
+			l.Close()
+		})
+
+		AfterEach(func() {
+			serverA.Close()
+			serverB.Close()
+		})
+
+		Context("round robin across clients", func() {
+			It("alternates between endpoints", func() {
+				opts := ClientOptions{
+					Endpoint:  "/health",
+					Endpoints: []string{serverA.URL, serverB.URL},
+				}
+
+				codes := []int{}
+				for i := 0; i < 4; i++ {
+					c, err := New(opts)
+					Expect(err).To(BeNil())
+					code, err := c.Get(ctx)
+					Expect(err).To(BeNil())
+					Expect(c.SelectedEndpoint().Path).To(Equal("/health"))
+					codes = append(codes, code)
+				}
+				Expect(codes).To(ContainElement(http.StatusOK))
+				Expect(codes).To(ContainElement(http.StatusAccepted))
+				Expect(codes[0]).ToNot(Equal(codes[1]))
+			})
+		})
+
+		Context("failover", func() {
+			It("moves on to the next endpoint when one is unreachable", func() {
+				dead, _ := NewEndpoint(deadURL, 1)
+				live, _ := NewEndpoint(serverA.URL, 1)
+				c, err := New(ClientOptions{
+					EndpointResolver: NewStaticResolver(dead, live),
+				})
+				Expect(err).To(BeNil())
+
+				code, err := c.Get(ctx)
+				Expect(err).To(BeNil())
+				Expect(code).To(Equal(http.StatusOK))
+				Expect(c.SelectedEndpoint().String()).To(Equal(serverA.URL))
+				Expect(dead.Outstanding()).To(BeZero())
+				Expect(live.Outstanding()).To(BeZero())
+			})
+			It("fails once every endpoint has been tried", func() {
+				dead, _ := NewEndpoint(deadURL, 1)
+				c, err := New(ClientOptions{
+					EndpointResolver: NewStaticResolver(dead),
+				})
+				Expect(err).To(BeNil())
+
+				code, err := c.Get(ctx)
+				Expect(err).ToNot(BeNil())
+				Expect(code).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+	// endregion
+})
@@ -0,0 +1,13 @@
+package blastertest
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBlastertest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Blastertest Suite")
+}
@@ -0,0 +1,216 @@
+package blastertest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster"
+)
+
+// Clock is a blaster.Clock that only moves when told to, so durations,
+// injected latency, outlier windows, TTLs and the ticks of health checks,
+// resolver watches and statsd flushes can be tested without waiting.
+//
+//	clock := blastertest.NewClock(time.Time{})
+//	c, _ := blaster.New(blaster.ClientOptions{Endpoint: url, Clock: clock})
+//	go c.Get(ctx)          // with 2s of injected latency
+//	clock.BlockUntil(1)    // the request waits on the clock
+//	clock.Advance(2 * time.Second)
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	tickers []*fakeTicker
+	changed chan struct{}
+}
+
+// Clock implements blaster.Clock
+var _ blaster.Clock = (*Clock)(nil)
+
+// NewClock returns a clock stopped at start, or at a fixed date if start
+// is zero
+func NewClock(start time.Time) *Clock {
+	if start.IsZero() {
+		start = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return &Clock{
+		now:     start,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now implements blaster.Clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Since implements blaster.Clock
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// NewTimer implements blaster.Clock.  The timer fires once the clock has
+// been advanced by d.
+func (c *Clock) NewTimer(d time.Duration) blaster.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+
+	c.timers = append(c.timers, t)
+	c.notify()
+	return t
+}
+
+// NewTicker implements blaster.Clock.  The ticker ticks each time the
+// clock has been advanced by d, at most once per Advance, like a
+// time.Ticker drops the ticks its reader missed.
+func (c *Clock) NewTicker(d time.Duration) blaster.Ticker {
+	if d <= 0 {
+		panic("blastertest: non-positive interval for NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTicker{clock: c, every: d, next: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	c.notify()
+	return t
+}
+
+// Advance moves the clock forward, firing the timers and ticking the
+// tickers that are due
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+
+	for _, t := range c.tickers {
+		if t.next.After(c.now) {
+			continue
+		}
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.every)
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+	}
+	c.notify()
+}
+
+// Waiters is the number of timers that have not fired yet, plus the
+// number of tickers that have not been stopped
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers) + len(c.tickers)
+}
+
+// BlockUntil waits until n timers and tickers are waiting on the clock,
+// e.g. for a request started in another goroutine to reach its injected
+// latency, or for a health checker to wait for its next round
+func (c *Clock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		waiters, changed := len(c.timers)+len(c.tickers), c.changed
+		c.mu.Unlock()
+
+		if waiters >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// notify wakes up BlockUntil.  Must be called with the lock held.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// stopTimer removes the timer, reporting whether it was waiting
+func (c *Clock) stopTimer(timer *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, t := range c.timers {
+		if t == timer {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.notify()
+			return true
+		}
+	}
+
+	return false
+}
+
+// stopTicker removes the ticker
+func (c *Clock) stopTicker(ticker *fakeTicker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, t := range c.tickers {
+		if t == ticker {
+			c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+			c.notify()
+			return
+		}
+	}
+}
+
+// fakeTimer is a timer of a fake Clock
+type fakeTimer struct {
+	clock *Clock
+	at    time.Time
+	c     chan time.Time
+}
+
+// C implements blaster.Timer
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop implements blaster.Timer
+func (t *fakeTimer) Stop() bool {
+	return t.clock.stopTimer(t)
+}
+
+// fakeTicker is a ticker of a fake Clock
+type fakeTicker struct {
+	clock *Clock
+	every time.Duration
+	next  time.Time
+	c     chan time.Time
+}
+
+// C implements blaster.Ticker
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop implements blaster.Ticker
+func (t *fakeTicker) Stop() {
+	t.clock.stopTicker(t)
+}
@@ -0,0 +1,114 @@
+package blastertest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clock", func() {
+	var clock *Clock
+
+	BeforeEach(func() {
+		clock = NewClock(time.Time{})
+	})
+
+	It("only moves when advanced", func() {
+		start := clock.Now()
+		Expect(start).To(Equal(time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)))
+
+		clock.Advance(time.Minute)
+		Expect(clock.Since(start)).To(Equal(time.Minute))
+		Expect(NewClock(start.Add(time.Hour)).Since(start)).To(Equal(time.Hour))
+	})
+
+	It("fires the timers that are due", func() {
+		early, late := clock.NewTimer(time.Second), clock.NewTimer(time.Minute)
+		stopped := clock.NewTimer(time.Second)
+		Expect(clock.Waiters()).To(Equal(3))
+		Expect(stopped.Stop()).To(BeTrue())
+		Expect(stopped.Stop()).To(BeFalse())
+
+		clock.Advance(time.Second)
+		Expect(early.C()).To(Receive(Equal(clock.Now())))
+		Expect(late.C()).ToNot(Receive())
+		Expect(clock.Waiters()).To(Equal(1))
+		Expect(early.Stop()).To(BeFalse())
+
+		Expect(clock.NewTimer(0).C()).To(Receive())
+	})
+
+	It("ticks the tickers at most once per advance", func() {
+		ticker, stopped := clock.NewTicker(time.Second), clock.NewTicker(time.Second)
+		stopped.Stop()
+		Expect(clock.Waiters()).To(Equal(1))
+
+		clock.Advance(500 * time.Millisecond)
+		Expect(ticker.C()).ToNot(Receive())
+		clock.Advance(3 * time.Second)
+		Expect(ticker.C()).To(Receive(Equal(clock.Now())))
+		Expect(ticker.C()).ToNot(Receive())
+		Expect(stopped.C()).ToNot(Receive())
+
+		// the next tick is due a second after the last one that was due
+		clock.Advance(500 * time.Millisecond)
+		Expect(ticker.C()).To(Receive())
+		ticker.Stop()
+		Expect(clock.Waiters()).To(BeZero())
+	})
+
+	It("drives the probe interval of health checkers", func() {
+		var probes int32
+		provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&probes, 1)
+		}))
+		defer provider.Close()
+		ep, _ := blaster.NewEndpoint(provider.URL, 1)
+
+		checker := blaster.NewHealthChecker(blaster.NewStaticResolver(ep), "user-service", blaster.HealthCheck{
+			Interval: time.Minute,
+			Clock:    clock,
+		})
+		Expect(checker.Start(context.Background())).To(Succeed())
+		defer checker.Stop()
+
+		clock.BlockUntil(1)
+		Eventually(func() int32 { return atomic.LoadInt32(&probes) }).Should(Equal(int32(1)))
+		Consistently(func() int32 { return atomic.LoadInt32(&probes) }).Should(Equal(int32(1)))
+
+		clock.Advance(time.Minute)
+		Eventually(func() int32 { return atomic.LoadInt32(&probes) }).Should(Equal(int32(2)))
+	})
+
+	It("waits out injected latency without sleeping", func() {
+		server := NewServer(&recordingT{})
+		defer server.Close()
+		server.Expect(http.MethodGet, "/users/42").Respond(http.StatusOK, nil)
+
+		injector, _ := blaster.NewFaultInjector(blaster.FaultConfig{Enabled: true, Rules: []blaster.FaultRule{{
+			Latency: &blaster.LatencyFault{Percent: 100, MeanMS: 5000},
+		}}})
+		options := server.ClientOptions("/users/42")
+		options.FaultInjector = injector
+		options.Clock = clock
+		c, _ := blaster.New(options)
+
+		done := make(chan int)
+		go func() {
+			statusCode, _ := c.Get(context.Background())
+			done <- statusCode
+		}()
+
+		clock.BlockUntil(1)
+		Consistently(done).ShouldNot(Receive())
+		clock.Advance(5 * time.Second)
+		Eventually(done).Should(Receive(Equal(http.StatusOK)))
+		Expect(c.Duration()).To(Equal(5 * time.Second))
+	})
+})
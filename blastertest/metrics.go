@@ -0,0 +1,244 @@
+package blastertest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster"
+)
+
+// MetricType is the kind of a recorded metric
+type MetricType string
+
+const (
+	// MetricTiming is recorded by Timing
+	MetricTiming MetricType = "timing"
+
+	// MetricCount is recorded by Incr
+	MetricCount MetricType = "count"
+
+	// MetricGauge is recorded by Gauge
+	MetricGauge MetricType = "gauge"
+
+	// MetricHistogram is recorded by Histogram
+	MetricHistogram MetricType = "histogram"
+)
+
+// Metric is a metric recorded by a MetricsRecorder
+type Metric struct {
+	Type MetricType
+	Name string
+
+	// Value is 1 for counts, and the duration in milliseconds for timings
+	Value float64
+
+	// Duration is the duration of timings
+	Duration time.Duration
+
+	Tags []string
+}
+
+// Tag returns the value of the key:value tag with the key
+func (m Metric) Tag(key string) (string, bool) {
+	for _, tag := range m.Tags {
+		if strings.HasPrefix(tag, key+":") {
+			return tag[len(key)+1:], true
+		}
+	}
+
+	return "", false
+}
+
+// String renders the metric as name{tags}=value
+func (m Metric) String() string {
+	return fmt.Sprintf("%s %s{%s}=%g", m.Type, m.Name, strings.Join(m.Tags, ","), m.Value)
+}
+
+// MetricsRecorder is an in-memory statsd client that records the metrics
+// sent to it, to be queried in tests.  Plug it in with SetStatsdDelegate,
+// or with ClientOptions.Metrics through Metrics.
+//
+//	recorder := blastertest.NewMetricsRecorder()
+//	c.SetMetrics(recorder.Metrics("api"))
+//	...
+//	recorder.Timings("api").ForRoute("/users/{id}").WithStatus(503).Len() // 1
+type MetricsRecorder struct {
+	mu      sync.Mutex
+	metrics []Metric
+}
+
+// MetricsRecorder is a statsd client with gauges and histograms
+var _ blaster.StatsdClientPrototype = (*MetricsRecorder)(nil)
+
+// NewMetricsRecorder creates an empty recorder
+func NewMetricsRecorder() *MetricsRecorder {
+	return &MetricsRecorder{}
+}
+
+// Metrics returns blaster.Metrics that report to the recorder, as
+// blaster.NewStatsdMetrics does
+func (r *MetricsRecorder) Metrics(stat string, tags ...string) blaster.Metrics {
+	return blaster.NewStatsdMetrics(r, stat, tags)
+}
+
+// Incr implements blaster.StatsdClientPrototype
+func (r *MetricsRecorder) Incr(name string, tags []string, rate float64) error {
+	r.record(Metric{Type: MetricCount, Name: name, Value: 1, Tags: tags})
+	return nil
+}
+
+// Timing implements blaster.StatsdClientPrototype
+func (r *MetricsRecorder) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	r.record(Metric{
+		Type:     MetricTiming,
+		Name:     name,
+		Value:    float64(value) / float64(time.Millisecond),
+		Duration: value,
+		Tags:     tags,
+	})
+	return nil
+}
+
+// Gauge records a gauge, as the DataDog client does
+func (r *MetricsRecorder) Gauge(name string, value float64, tags []string, rate float64) error {
+	r.record(Metric{Type: MetricGauge, Name: name, Value: value, Tags: tags})
+	return nil
+}
+
+// Histogram records a histogram value, as the DataDog client does
+func (r *MetricsRecorder) Histogram(name string, value float64, tags []string, rate float64) error {
+	r.record(Metric{Type: MetricHistogram, Name: name, Value: value, Tags: tags})
+	return nil
+}
+
+// record keeps a copy of the metric
+func (r *MetricsRecorder) record(m Metric) {
+	m.Tags = append([]string(nil), m.Tags...)
+
+	r.mu.Lock()
+	r.metrics = append(r.metrics, m)
+	r.mu.Unlock()
+}
+
+// All returns every recorded metric
+func (r *MetricsRecorder) All() MetricQuery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return MetricQuery(append([]Metric(nil), r.metrics...))
+}
+
+// Timings returns the timings with the name
+func (r *MetricsRecorder) Timings(name string) MetricQuery {
+	return r.All().filter(func(m Metric) bool { return m.Type == MetricTiming && m.Name == name })
+}
+
+// Counts returns the counts with the name
+func (r *MetricsRecorder) Counts(name string) MetricQuery {
+	return r.All().filter(func(m Metric) bool { return m.Type == MetricCount && m.Name == name })
+}
+
+// Gauges returns the gauges with the name
+func (r *MetricsRecorder) Gauges(name string) MetricQuery {
+	return r.All().filter(func(m Metric) bool { return m.Type == MetricGauge && m.Name == name })
+}
+
+// Histograms returns the histogram values with the name
+func (r *MetricsRecorder) Histograms(name string) MetricQuery {
+	return r.All().filter(func(m Metric) bool { return m.Type == MetricHistogram && m.Name == name })
+}
+
+// Reset forgets the recorded metrics
+func (r *MetricsRecorder) Reset() {
+	r.mu.Lock()
+	r.metrics = nil
+	r.mu.Unlock()
+}
+
+// MetricQuery is a selection of recorded metrics, in the order they were
+// recorded.  Its methods narrow it down or summarize it.
+type MetricQuery []Metric
+
+// WithTag keeps the metrics with the tag, e.g. "response-code:503"
+func (q MetricQuery) WithTag(tag string) MetricQuery {
+	return q.filter(func(m Metric) bool {
+		for _, t := range m.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithTags keeps the metrics with all the tags
+func (q MetricQuery) WithTags(tags ...string) MetricQuery {
+	for _, tag := range tags {
+		q = q.WithTag(tag)
+	}
+
+	return q
+}
+
+// ForRoute keeps the metrics of the route mask
+func (q MetricQuery) ForRoute(routeMask string) MetricQuery {
+	return q.WithTag("route:" + routeMask)
+}
+
+// ForService keeps the metrics of the called service
+func (q MetricQuery) ForService(calledService string) MetricQuery {
+	return q.WithTag("called-service:" + calledService)
+}
+
+// WithStatus keeps the metrics of the response status code
+func (q MetricQuery) WithStatus(statusCode int) MetricQuery {
+	return q.WithTag(fmt.Sprintf("response-code:%d", statusCode))
+}
+
+// Len is the number of metrics
+func (q MetricQuery) Len() int {
+	return len(q)
+}
+
+// Sum adds up the values of the metrics
+func (q MetricQuery) Sum() float64 {
+	var sum float64
+	for _, m := range q {
+		sum += m.Value
+	}
+
+	return sum
+}
+
+// Values lists the values of the metrics
+func (q MetricQuery) Values() []float64 {
+	values := make([]float64, len(q))
+	for i, m := range q {
+		values[i] = m.Value
+	}
+
+	return values
+}
+
+// Last returns the metric recorded last, e.g. the current value of a gauge
+func (q MetricQuery) Last() (Metric, bool) {
+	if len(q) == 0 {
+		return Metric{}, false
+	}
+
+	return q[len(q)-1], true
+}
+
+// filter keeps the metrics that match
+func (q MetricQuery) filter(match func(Metric) bool) MetricQuery {
+	var kept MetricQuery
+	for _, m := range q {
+		if match(m) {
+			kept = append(kept, m)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,90 @@
+package blastertest
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MetricsRecorder", func() {
+	var (
+		ctx      context.Context
+		server   *Server
+		recorder *MetricsRecorder
+	)
+
+	// get sends a GET for the route
+	get := func(path, routeMask string, metrics blaster.Metrics) {
+		opts := server.ClientOptions(path)
+		opts.RouteMask = routeMask
+		opts.CalledService = "user-service"
+		opts.Metrics = metrics
+		c, _ := blaster.New(opts)
+		c.Get(ctx)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = NewServer(&recordingT{})
+		recorder = NewMetricsRecorder()
+		server.Expect(http.MethodGet, "/users/:id").Respond(http.StatusOK, user{ID: "42"})
+		server.Expect(http.MethodGet, "/teams/:id").Respond(http.StatusServiceUnavailable, nil).AnyTimes()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("records the metrics of requests for querying", func() {
+		metrics := recorder.Metrics("api", "env:test")
+		get("/users/42", "/users/{id}", metrics)
+		get("/teams/7", "/teams/{id}", metrics)
+		get("/teams/8", "/teams/{id}", metrics)
+
+		Expect(recorder.Timings("api").Len()).To(Equal(3))
+		Expect(recorder.Timings("api").ForRoute("/teams/{id}").WithStatus(503).Len()).To(Equal(2))
+		Expect(recorder.Timings("api").ForRoute("/users/{id}").WithStatus(503).Len()).To(Equal(0))
+		Expect(recorder.Timings("api").WithTags("env:test", "called-service:user-service").Len()).To(Equal(3))
+
+		timing, ok := recorder.Timings("api").ForService("user-service").ForRoute("/users/{id}").Last()
+		Expect(ok).To(BeTrue())
+		Expect(timing.Duration).To(BeNumerically(">", 0))
+		Expect(timing.Value).To(Equal(float64(timing.Duration) / float64(time.Millisecond)))
+		responseType, _ := timing.Tag("response-type")
+		Expect(responseType).To(Equal("2xx"))
+
+		gauge, ok := recorder.Gauges("api.in_flight").ForRoute("/teams/{id}").Last()
+		Expect(ok).To(BeTrue())
+		Expect(gauge.Value).To(Equal(0.0))
+		Expect(recorder.Histograms("api.response_size").ForRoute("/users/{id}").Sum()).To(BeNumerically(">", 0))
+	})
+
+	It("records what a statsd delegate reports", func() {
+		opts := server.ClientOptions("/users/42")
+		c, _ := blaster.New(opts)
+		c.SetStatsdDelegate(recorder, "legacy", []string{"team:core"})
+		c.Get(ctx)
+
+		Expect(recorder.Timings("legacy").WithTag("team:core").WithStatus(200).Len()).To(Equal(1))
+		Expect(recorder.All().Len()).To(BeNumerically(">=", 1))
+	})
+
+	It("sums counts and forgets everything on reset", func() {
+		recorder.Incr("api.retry", []string{"route:/a"}, 1)
+		recorder.Incr("api.retry", []string{"route:/a"}, 1)
+		recorder.Incr("api.retry", []string{"route:/b"}, 1)
+
+		Expect(recorder.Counts("api.retry").ForRoute("/a").Sum()).To(Equal(2.0))
+		Expect(recorder.Counts("api.retry").Values()).To(Equal([]float64{1, 1, 1}))
+		Expect(recorder.Counts("api.error").Len()).To(Equal(0))
+		_, ok := recorder.Counts("api.error").Last()
+		Expect(ok).To(BeFalse())
+
+		recorder.Reset()
+		Expect(recorder.All().Len()).To(Equal(0))
+	})
+})
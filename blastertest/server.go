@@ -0,0 +1,548 @@
+// Package blastertest provides helpers for testing code that makes requests
+// with blaster, without MOCKING_HTTP or global http interceptors.
+package blastertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/joelhill/go-rest-http-blaster"
+)
+
+// TestingT is the part of *testing.T, or of GinkgoT(), that failures are
+// reported to
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+	Helper()
+}
+
+// Request is a request received by the server
+type Request struct {
+	*http.Request
+
+	// Params holds the values of the path template parameters,
+	// e.g. id for /users/:id
+	Params map[string]string
+
+	// Body is the request body
+	Body []byte
+}
+
+// Response is a response returned by the server.  Body is written as is
+// when it is a []byte or a string, and encoded as JSON otherwise.  JSON
+// bodies get an application/json Content-Type unless Header sets one.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   interface{}
+}
+
+// Call is a request received by the server
+type Call struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+
+	// Expectation is the expectation the call matched, or nil if it
+	// matched none
+	Expectation *Expectation
+}
+
+// Server is an in-process http server that answers requests according to
+// the expectations registered on it.  Requests that match no expectation
+// are answered with 501 Not Implemented and reported by Verify.
+//
+// When t has a Cleanup function, as *testing.T does, Finish is called
+// at the end of the test.  Otherwise call Finish yourself, e.g. in an
+// AfterEach.
+type Server struct {
+	// URL is the base url of the server
+	URL string
+
+	t      TestingT
+	server *httptest.Server
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+	inOrder      bool
+	pact         *blaster.Pact
+}
+
+// NewServer starts a server
+func NewServer(t TestingT) *Server {
+	s := &Server{t: t}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = s.server.URL
+
+	if c, ok := t.(interface{ Cleanup(func()) }); ok {
+		c.Cleanup(s.Finish)
+	}
+
+	return s
+}
+
+// ClientOptions returns options for a blaster client that sends requests
+// to the path on this server
+func (s *Server) ClientOptions(path string) blaster.ClientOptions {
+	return blaster.ClientOptions{Endpoint: s.URL + path}
+}
+
+// Expect registers an expectation for requests with the method whose path
+// matches the template.  Parameters in the template start with a colon,
+// e.g. /users/:id.  The expectation is met by exactly one call, unless
+// Times or AnyTimes says otherwise, and answers 200 with no body unless
+// told otherwise.
+func (s *Server) Expect(method, pathTemplate string) *Expectation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := &Expectation{
+		method:   method,
+		template: splitPath(pathTemplate),
+		name:     fmt.Sprintf("%s %s", method, pathTemplate),
+		times:    1,
+		query:    url.Values{},
+		header:   http.Header{},
+		respond: func(*Request) Response {
+			return Response{Status: http.StatusOK}
+		},
+	}
+	s.expectations = append(s.expectations, e)
+
+	return e
+}
+
+// InOrder requires the expectations to be met in the order they were
+// registered
+func (s *Server) InOrder() *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inOrder = true
+	return s
+}
+
+// RecordPact adds an interaction to the pact for each expectation, from
+// the first call that meets it.  The interaction keeps the headers the
+// expectation requires and the Content-Type, and is described by the
+// expectation, see Expectation.Describe and Expectation.Given.  Write the
+// pact once the tests are done, see blaster.Pact.WriteFile.
+func (s *Server) RecordPact(pact *blaster.Pact) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pact = pact
+	return s
+}
+
+// Calls returns the requests received so far
+func (s *Server) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Call(nil), s.calls...)
+}
+
+// Verify reports the expectations that were not met, the requests that
+// matched no expectation and, with InOrder, the calls that came out of order
+func (s *Server) Verify() {
+	s.t.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.expectations {
+		if e.anyTimes {
+			continue
+		}
+		if e.calls != e.times {
+			s.t.Errorf("blastertest: expected %s to be called %d time(s), was called %d time(s)", e.name, e.times, e.calls)
+		}
+	}
+
+	last := -1
+	for _, call := range s.calls {
+		if call.Expectation == nil {
+			s.t.Errorf("blastertest: unexpected request %s %s", call.Method, call.Path)
+			continue
+		}
+		if !s.inOrder {
+			continue
+		}
+		index := s.indexOf(call.Expectation)
+		if index < last {
+			s.t.Errorf("blastertest: %s was called after %s", call.Expectation.name, s.expectations[last].name)
+		}
+		if index > last {
+			last = index
+		}
+	}
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Finish verifies the expectations and shuts the server down
+func (s *Server) Finish() {
+	s.t.Helper()
+
+	s.Verify()
+	s.Close()
+}
+
+// indexOf returns the position of the expectation
+func (s *Server) indexOf(e *Expectation) int {
+	for i, candidate := range s.expectations {
+		if candidate == e {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// serve answers a request with the first expectation it matches
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mu.Lock()
+	call := Call{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header,
+		Body:   body,
+	}
+	var (
+		matched *Expectation
+		params  map[string]string
+	)
+	for _, e := range s.expectations {
+		if e.exhausted() {
+			continue
+		}
+		if p, ok := e.matches(r, body); ok {
+			matched, params = e, p
+			break
+		}
+	}
+	if matched != nil {
+		matched.calls++
+		call.Expectation = matched
+	}
+	s.calls = append(s.calls, call)
+	// the first call that meets an expectation makes its interaction
+	var pact *blaster.Pact
+	if matched != nil && matched.calls == 1 {
+		pact = s.pact
+	}
+	s.mu.Unlock()
+
+	if matched == nil {
+		http.Error(w, fmt.Sprintf("blastertest: no expectation matched %s %s", r.Method, r.URL.Path), http.StatusNotImplemented)
+		return
+	}
+
+	response := matched.respond(&Request{Request: r, Params: params, Body: body})
+	status, header, responseBody, err := encodeResponse(response)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("blastertest: unable to encode response body: %s", err), http.StatusInternalServerError)
+		return
+	}
+	if pact != nil {
+		pact.AddInteraction(matched.interaction(r, body, status, header, responseBody))
+	}
+
+	for k, values := range header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(status)
+	w.Write(responseBody)
+}
+
+// encodeResponse returns the status, headers and encoded body of the
+// response
+func encodeResponse(response Response) (int, http.Header, []byte, error) {
+	var body []byte
+	switch b := response.Body.(type) {
+	case nil:
+	case []byte:
+		body = b
+	case string:
+		body = []byte(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		body = encoded
+	}
+
+	header := http.Header{}
+	for k, values := range response.Header {
+		header[k] = append([]string(nil), values...)
+	}
+	if len(body) > 0 && header.Get("Content-Type") == "" && json.Valid(body) {
+		header.Set("Content-Type", "application/json")
+	}
+
+	status := response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	return status, header, body, nil
+}
+
+// Expectation describes the requests the server expects and how it
+// answers them
+type Expectation struct {
+	method   string
+	template []string
+	name     string
+	query    url.Values
+	header   http.Header
+	body     func([]byte) bool
+	respond  func(*Request) Response
+	times    int
+	anyTimes bool
+	calls    int
+
+	description string
+	state       string
+}
+
+// WithQuery requires the query parameter to have the value
+func (e *Expectation) WithQuery(key, value string) *Expectation {
+	e.query.Add(key, value)
+	return e
+}
+
+// WithHeader requires the header to have the value
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// WithJSONBody requires the body to be JSON equal to expected, which is
+// encoded first unless it is a []byte or a string.  The order of object
+// keys does not matter.
+func (e *Expectation) WithJSONBody(expected interface{}) *Expectation {
+	want, err := decodeJSON(expected)
+	if err != nil {
+		panic(fmt.Sprintf("blastertest: invalid expected JSON body: %s", err))
+	}
+
+	return e.WithBody(func(body []byte) bool {
+		got, err := decodeJSON(body)
+		return err == nil && reflect.DeepEqual(got, want)
+	})
+}
+
+// WithBody requires the body to satisfy the matcher
+func (e *Expectation) WithBody(matcher func(body []byte) bool) *Expectation {
+	e.body = matcher
+	return e
+}
+
+// Describe sets the description of the pact interaction of the
+// expectation, which defaults to its method and path template
+func (e *Expectation) Describe(description string) *Expectation {
+	e.description = description
+	return e
+}
+
+// Given sets the provider state of the pact interaction of the
+// expectation, e.g. "user 42 exists"
+func (e *Expectation) Given(state string) *Expectation {
+	e.state = state
+	return e
+}
+
+// Times sets the number of calls that meet the expectation
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	e.anyTimes = false
+	return e
+}
+
+// AnyTimes lets the expectation be met by any number of calls, including none
+func (e *Expectation) AnyTimes() *Expectation {
+	e.anyTimes = true
+	return e
+}
+
+// Respond answers with the status and body.  The body is written as is
+// when it is a []byte or a string, and encoded as JSON otherwise.
+func (e *Expectation) Respond(status int, body interface{}) *Expectation {
+	return e.RespondWith(func(*Request) Response {
+		return Response{Status: status, Body: body}
+	})
+}
+
+// RespondTemplate answers with the status and a body rendered from a
+// text/template, which is given the Request, e.g. {"id":"{{.Params.id}}"}
+func (e *Expectation) RespondTemplate(status int, text string) *Expectation {
+	tmpl := template.Must(template.New(e.name).Parse(text))
+	return e.RespondWith(func(r *Request) Response {
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, r); err != nil {
+			return Response{Status: http.StatusInternalServerError, Body: fmt.Sprintf("blastertest: %s", err)}
+		}
+		return Response{Status: status, Body: b.Bytes()}
+	})
+}
+
+// RespondWith answers with the response built by the function
+func (e *Expectation) RespondWith(respond func(*Request) Response) *Expectation {
+	e.respond = respond
+	return e
+}
+
+// interaction describes a call that meets the expectation, and its
+// response, as a pact interaction
+func (e *Expectation) interaction(r *http.Request, body []byte, status int, header http.Header, responseBody []byte) blaster.PactInteraction {
+	description := e.description
+	if description == "" {
+		description = e.name
+	}
+
+	names := []string{"Content-Type"}
+	for name := range e.header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return blaster.PactInteraction{
+		Description:   description,
+		ProviderState: e.state,
+		Request: blaster.PactRequest{
+			Method:  r.Method,
+			Path:    r.URL.Path,
+			Query:   r.URL.RawQuery,
+			Headers: blaster.PactHeaders(r.Header, names...),
+			Body:    blaster.PactBody(body),
+		},
+		Response: blaster.PactResponse{
+			Status:  status,
+			Headers: blaster.PactHeaders(header, "Content-Type"),
+			Body:    blaster.PactBody(responseBody),
+		},
+	}
+}
+
+// exhausted tells whether the expectation was met by all the calls it takes
+func (e *Expectation) exhausted() bool {
+	return !e.anyTimes && e.calls >= e.times
+}
+
+// matches tells whether the request meets the expectation, and returns
+// the path parameters
+func (e *Expectation) matches(r *http.Request, body []byte) (map[string]string, bool) {
+	if r.Method != e.method {
+		return nil, false
+	}
+
+	params, ok := matchPath(e.template, splitPath(r.URL.Path))
+	if !ok {
+		return nil, false
+	}
+
+	query := r.URL.Query()
+	for key, values := range e.query {
+		for _, value := range values {
+			if !contains(query[key], value) {
+				return nil, false
+			}
+		}
+	}
+
+	for key, values := range e.header {
+		for _, value := range values {
+			if !contains(r.Header[key], value) {
+				return nil, false
+			}
+		}
+	}
+
+	if e.body != nil && !e.body(body) {
+		return nil, false
+	}
+
+	return params, true
+}
+
+// splitPath splits a path into its segments
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// matchPath matches the segments of a path against a template
+func matchPath(template, path []string) (map[string]string, bool) {
+	if len(template) != len(path) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, segment := range template {
+		if strings.HasPrefix(segment, ":") {
+			params[segment[1:]] = path[i]
+			continue
+		}
+		if segment != path[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// decodeJSON decodes a JSON document, encoding v first unless it is
+// already a []byte or a string
+func decodeJSON(v interface{}) (interface{}, error) {
+	var raw []byte
+	switch b := v.(type) {
+	case []byte:
+		raw = b
+	case string:
+		raw = []byte(b)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		raw = encoded
+	}
+
+	var doc interface{}
+	err := json.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+// contains tells whether the value is one of values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,220 @@
+package blastertest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// recordingT keeps the failures reported to it
+type recordingT struct {
+	errors  []string
+	cleanup []func()
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Cleanup(f func()) {
+	t.cleanup = append(t.cleanup, f)
+}
+
+type user struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+var _ = Describe("Server", func() {
+	var (
+		ctx    context.Context
+		t      *recordingT
+		server *Server
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		t = &recordingT{}
+		server = NewServer(t)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// region matching
+
+	It("answers with the canned response of the matching expectation", func() {
+		server.Expect(http.MethodGet, "/users/:id").Respond(http.StatusOK, user{ID: "42", Name: "joel"})
+
+		var got user
+		c, _ := blaster.New(server.ClientOptions("/users/42"))
+		c.WillSaturate(&got)
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(got).To(Equal(user{ID: "42", Name: "joel"}))
+
+		server.Verify()
+		Expect(t.errors).To(BeEmpty())
+	})
+
+	It("renders templated responses from the path parameters", func() {
+		server.Expect(http.MethodGet, "/users/:id").RespondTemplate(http.StatusOK, `{"id":"{{.Params.id}}","name":"{{.URL.Query.Get "name"}}"}`)
+
+		var got user
+		c, _ := blaster.New(server.ClientOptions("/users/7?name=ann"))
+		c.WillSaturate(&got)
+		c.Get(ctx)
+		Expect(got).To(Equal(user{ID: "7", Name: "ann"}))
+	})
+
+	It("matches on query, headers and JSON body", func() {
+		server.Expect(http.MethodPost, "/users").
+			WithQuery("dry_run", "true").
+			WithHeader("X-Tenant", "acme").
+			WithJSONBody(`{"name":"joel","id":"1"}`).
+			Respond(http.StatusCreated, nil)
+
+		c, _ := blaster.New(server.ClientOptions("/users?dry_run=true"))
+		c.SetHeader("X-Tenant", "acme")
+		statusCode, _ := c.Post(ctx, user{ID: "1", Name: "joel"})
+		Expect(statusCode).To(Equal(http.StatusCreated))
+
+		c, _ = blaster.New(server.ClientOptions("/users?dry_run=true"))
+		c.SetHeader("X-Tenant", "other")
+		statusCode, _ = c.Post(ctx, user{ID: "1", Name: "joel"})
+		Expect(statusCode).To(Equal(http.StatusNotImplemented))
+	})
+
+	It("lets a function build the response", func() {
+		server.Expect(http.MethodDelete, "/users/:id").RespondWith(func(r *Request) Response {
+			return Response{Status: http.StatusAccepted, Header: http.Header{"X-Deleted": {r.Params["id"]}}}
+		})
+
+		c, _ := blaster.New(server.ClientOptions("/users/9"))
+		c.Delete(ctx, nil)
+		Expect(c.Response().StatusCode).To(Equal(http.StatusAccepted))
+		Expect(c.Response().Header.Get("X-Deleted")).To(Equal("9"))
+	})
+
+	It("records the calls", func() {
+		server.Expect(http.MethodPut, "/users/:id")
+
+		c, _ := blaster.New(server.ClientOptions("/users/3?force=1"))
+		c.Put(ctx, user{Name: "bo"})
+
+		calls := server.Calls()
+		Expect(calls).To(HaveLen(1))
+		Expect(calls[0].Method).To(Equal(http.MethodPut))
+		Expect(calls[0].Path).To(Equal("/users/3"))
+		Expect(calls[0].Query.Get("force")).To(Equal("1"))
+		Expect(calls[0].Body).To(MatchJSON(`{"id":"","name":"bo"}`))
+		Expect(calls[0].Expectation).ToNot(BeNil())
+	})
+
+	// endregion
+
+	// region verification
+
+	It("reports expectations that were not met", func() {
+		server.Expect(http.MethodGet, "/users").Times(2)
+		server.Expect(http.MethodGet, "/health").AnyTimes()
+
+		c, _ := blaster.New(server.ClientOptions("/users"))
+		c.Get(ctx)
+
+		server.Verify()
+		Expect(t.errors).To(ConsistOf("blastertest: expected GET /users to be called 2 time(s), was called 1 time(s)"))
+	})
+
+	It("reports unexpected requests", func() {
+		c, _ := blaster.New(server.ClientOptions("/nope"))
+		statusCode, _ := c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusNotImplemented))
+
+		server.Verify()
+		Expect(t.errors).To(ConsistOf("blastertest: unexpected request GET /nope"))
+	})
+
+	It("reports calls that came out of order", func() {
+		server.InOrder()
+		server.Expect(http.MethodPost, "/login")
+		server.Expect(http.MethodGet, "/profile")
+
+		c, _ := blaster.New(server.ClientOptions("/profile"))
+		c.Get(ctx)
+		c, _ = blaster.New(server.ClientOptions("/login"))
+		c.Post(ctx, nil)
+
+		server.Verify()
+		Expect(t.errors).To(ConsistOf("blastertest: POST /login was called after GET /profile"))
+	})
+
+	It("accepts calls in any order by default", func() {
+		server.Expect(http.MethodPost, "/login")
+		server.Expect(http.MethodGet, "/profile")
+
+		c, _ := blaster.New(server.ClientOptions("/profile"))
+		c.Get(ctx)
+		c, _ = blaster.New(server.ClientOptions("/login"))
+		c.Post(ctx, nil)
+
+		server.Verify()
+		Expect(t.errors).To(BeEmpty())
+	})
+
+	It("finishes at the end of the test", func() {
+		server.Expect(http.MethodGet, "/users")
+		Expect(t.cleanup).To(HaveLen(1))
+
+		t.cleanup[0]()
+		Expect(t.errors).To(HaveLen(1))
+	})
+
+	// endregion
+
+	// region pact
+
+	It("records the pact of the expectations from their first call", func() {
+		pact := blaster.NewPact("order-service", "user-service")
+		server.RecordPact(pact)
+		server.Expect(http.MethodGet, "/users/:id").
+			WithHeader("X-Tenant", "acme").
+			Describe("a request for a user").
+			Given("user 42 exists").
+			Respond(http.StatusOK, user{ID: "42", Name: "joel"}).
+			AnyTimes()
+		server.Expect(http.MethodPost, "/users").Respond(http.StatusCreated, nil)
+
+		for _, id := range []string{"42", "43"} {
+			c, _ := blaster.New(server.ClientOptions("/users/" + id))
+			c.SetHeader("X-Tenant", "acme")
+			c.Get(ctx)
+		}
+		c, _ := blaster.New(server.ClientOptions("/users"))
+		c.Post(ctx, user{Name: "joel"})
+
+		Expect(pact.Interactions).To(HaveLen(2))
+		get := pact.Interactions[0]
+		Expect(get.Description).To(Equal("a request for a user"))
+		Expect(get.ProviderState).To(Equal("user 42 exists"))
+		Expect(get.Request.Path).To(Equal("/users/42"))
+		Expect(get.Request.Headers).To(Equal(map[string]string{"Content-Type": "application/json", "X-Tenant": "acme"}))
+		Expect(get.Response.Status).To(Equal(http.StatusOK))
+		Expect(string(get.Response.Body)).To(MatchJSON(`{"id":"42","name":"joel"}`))
+
+		post := pact.Interactions[1]
+		Expect(post.Description).To(Equal("POST /users"))
+		Expect(string(post.Request.Body)).To(MatchJSON(`{"id":"","name":"joel"}`))
+		Expect(post.Response.Status).To(Equal(http.StatusCreated))
+	})
+
+	// endregion
+})
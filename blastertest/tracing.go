@@ -0,0 +1,110 @@
+package blastertest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// TraceRecorder records the opentracing spans of requests in memory, with
+// a mocktracer, to be queried in tests.
+//
+//	recorder := blastertest.NewTraceRecorder()
+//	blaster.SetDefaults(&blaster.Defaults{TracerProviderFunc: recorder.TracerProviderFunc})
+//	...
+//	recorder.Spans().WithTag("http.status_code", 503).WithError().Len() // 1
+type TraceRecorder struct {
+	tracer   *mocktracer.MockTracer
+	provider func(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span)
+}
+
+// NewTraceRecorder creates an empty recorder
+func NewTraceRecorder() *TraceRecorder {
+	tracer := mocktracer.New()
+
+	return &TraceRecorder{
+		tracer:   tracer,
+		provider: blaster.NewTracerProvider(tracer),
+	}
+}
+
+// TracerProviderFunc is a Defaults.TracerProviderFunc that starts the
+// spans with the mocktracer of the recorder
+func (r *TraceRecorder) TracerProviderFunc(ctx context.Context, operationName string, req *http.Request) (*http.Request, opentracing.Span) {
+	return r.provider(ctx, operationName, req)
+}
+
+// Tracer returns the mocktracer, e.g. to start the parent span of a test
+func (r *TraceRecorder) Tracer() *mocktracer.MockTracer {
+	return r.tracer
+}
+
+// Spans returns the finished spans
+func (r *TraceRecorder) Spans() SpanQuery {
+	return SpanQuery(r.tracer.FinishedSpans())
+}
+
+// Reset forgets the finished spans
+func (r *TraceRecorder) Reset() {
+	r.tracer.Reset()
+}
+
+// SpanQuery is a selection of finished spans, in the order they finished.
+// Its methods narrow it down.
+type SpanQuery []*mocktracer.MockSpan
+
+// Named keeps the spans with the operation name
+func (q SpanQuery) Named(operationName string) SpanQuery {
+	return q.filter(func(s *mocktracer.MockSpan) bool { return s.OperationName == operationName })
+}
+
+// WithTag keeps the spans with the tag.  Values are compared as they
+// print, so 503 matches a uint16 status code.
+func (q SpanQuery) WithTag(key string, value interface{}) SpanQuery {
+	want := fmt.Sprint(value)
+	return q.filter(func(s *mocktracer.MockSpan) bool {
+		got := s.Tag(key)
+		return got != nil && fmt.Sprint(got) == want
+	})
+}
+
+// WithError keeps the spans flagged with error=true
+func (q SpanQuery) WithError() SpanQuery {
+	return q.WithTag("error", true)
+}
+
+// ChildrenOf keeps the spans whose parent is the span
+func (q SpanQuery) ChildrenOf(parent opentracing.Span) SpanQuery {
+	ctx, ok := parent.Context().(mocktracer.MockSpanContext)
+	return q.filter(func(s *mocktracer.MockSpan) bool { return ok && s.ParentID == ctx.SpanID })
+}
+
+// Len is the number of spans
+func (q SpanQuery) Len() int {
+	return len(q)
+}
+
+// Last returns the span that finished last
+func (q SpanQuery) Last() (*mocktracer.MockSpan, bool) {
+	if len(q) == 0 {
+		return nil, false
+	}
+
+	return q[len(q)-1], true
+}
+
+// filter keeps the spans that match
+func (q SpanQuery) filter(match func(*mocktracer.MockSpan) bool) SpanQuery {
+	var kept SpanQuery
+	for _, s := range q {
+		if match(s) {
+			kept = append(kept, s)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,62 @@
+package blastertest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/opentracing/opentracing-go"
+)
+
+var _ = Describe("TraceRecorder", func() {
+	var (
+		ctx      context.Context
+		server   *Server
+		recorder *TraceRecorder
+		received http.Header
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		server = NewServer(&recordingT{})
+		recorder = NewTraceRecorder()
+		blaster.SetDefaults(&blaster.Defaults{TracerProviderFunc: recorder.TracerProviderFunc})
+		server.Expect(http.MethodGet, "/users/:id").RespondWith(func(r *Request) Response {
+			received = r.Header
+			return Response{Status: http.StatusServiceUnavailable}
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+		blaster.SetDefaults(&blaster.Defaults{})
+	})
+
+	It("records the spans of requests for querying", func() {
+		parent := recorder.Tracer().StartSpan("handler")
+		opts := server.ClientOptions("/users/42")
+		opts.RouteMask = "/users/{id}"
+		c, _ := blaster.New(opts)
+		c.Get(opentracing.ContextWithSpan(ctx, parent))
+		parent.Finish()
+
+		endpoint, _ := url.Parse(opts.Endpoint)
+		spans := recorder.Spans().Named("GET " + endpoint.Host)
+		Expect(spans.Len()).To(Equal(1))
+		Expect(spans.WithTag("http.status_code", 503).WithError().ChildrenOf(parent).Len()).To(Equal(1))
+		Expect(spans.WithTag("http.route", "/users/{id}").WithTag("span.kind", "client").Len()).To(Equal(1))
+		Expect(spans.WithTag("http.status_code", 200).Len()).To(Equal(0))
+		Expect(recorder.Spans().Named("handler").Len()).To(Equal(1))
+
+		span, ok := spans.Last()
+		Expect(ok).To(BeTrue())
+		Expect(received.Get("Mockpfx-Ids-Spanid")).To(Equal(fmt.Sprint(span.SpanContext.SpanID)))
+
+		recorder.Reset()
+		Expect(recorder.Spans().Len()).To(Equal(0))
+	})
+})
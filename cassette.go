@@ -0,0 +1,338 @@
+package blaster
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNoCassetteEntry is returned in replay mode for requests that match
+// no unused entry of the cassette
+var ErrNoCassetteEntry = errors.New("no matching cassette entry")
+
+// CassetteMode tells whether a cassette records or replays
+type CassetteMode int
+
+const (
+	// CassetteReplay serves the recorded responses, and fails requests
+	// that were not recorded
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends requests on and records them, replacing the
+	// cassette
+	CassetteRecord
+)
+
+// CassetteMatch selects what a request must share with a recorded one to
+// be served its response
+type CassetteMatch struct {
+	// Method compares the http method
+	Method bool
+
+	// URL compares the full url, including the query
+	URL bool
+
+	// Body compares a hash of the request body
+	Body bool
+
+	// Headers are compared by value, after redaction
+	Headers []string
+}
+
+// CassetteConfig configures a cassette
+type CassetteConfig struct {
+	// Mode defaults to CassetteReplay
+	Mode CassetteMode
+
+	// Match defaults to the method, url and body
+	Match *CassetteMatch
+
+	// Redaction blanks out secrets before they are written.  Requests are
+	// matched after redaction, so replay with the Redaction the cassette
+	// was recorded with.
+	Redaction Redaction
+
+	// Transport sends the requests in record mode.  Defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// Cassette is an http.RoundTripper that records request and response
+// pairs to a JSON lines file, or replays them from it, so integration
+// tests can run offline and deterministically.  Plug it in through
+// ClientOptions.Transport.  Each recorded entry is served once.
+type Cassette struct {
+	path   string
+	config CassetteConfig
+	match  CassetteMatch
+
+	mu      sync.Mutex
+	entries []cassetteEntry
+	used    []bool
+	file    *os.File
+}
+
+// cassetteEntry is a line of the cassette
+type cassetteEntry struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+// cassetteRequest is a recorded request
+type cassetteRequest struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header"`
+	Body     string      `json:"body,omitempty"`
+	BodyHash string      `json:"body_sha256"`
+
+	// BodyEncoding is base64 for bodies that are not valid UTF-8
+	BodyEncoding string `json:"body_encoding,omitempty"`
+}
+
+// cassetteResponse is a recorded response
+type cassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body,omitempty"`
+
+	// BodyEncoding is base64 for bodies that are not valid UTF-8
+	BodyEncoding string `json:"body_encoding,omitempty"`
+}
+
+// base64BodyEncoding marks bodies stored in base64.  JSON strings only hold
+// UTF-8, so binary bodies such as gzip or protobuf would be corrupted as is.
+const base64BodyEncoding = "base64"
+
+// encodeBody returns the body as it is stored, and its encoding
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), base64BodyEncoding
+}
+
+// decodeBody returns the body that was stored with the encoding
+func decodeBody(body, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(body), nil
+	case base64BodyEncoding:
+		return base64.StdEncoding.DecodeString(body)
+	default:
+		return nil, fmt.Errorf("unknown cassette body encoding %q", encoding)
+	}
+}
+
+// NewCassette opens the cassette at path.  In replay mode the file must
+// exist, in record mode it is created or truncated.
+func NewCassette(path string, config CassetteConfig) (*Cassette, error) {
+	c := &Cassette{
+		path:   path,
+		config: config,
+		match:  CassetteMatch{Method: true, URL: true, Body: true},
+	}
+	if config.Match != nil {
+		c.match = *config.Match
+	}
+	if c.config.Transport == nil {
+		c.config.Transport = http.DefaultTransport
+	}
+
+	if config.Mode == CassetteRecord {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		c.file = file
+		return c, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry cassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("cassette %s line %d: %s", path, line, err)
+		}
+		c.entries = append(c.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	c.used = make([]bool, len(c.entries))
+
+	return c, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *Cassette) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+		request.Body.Close()
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	recorded := c.recordRequest(request, body)
+	if c.config.Mode == CassetteRecord {
+		return c.record(request, recorded)
+	}
+
+	return c.replay(request, recorded)
+}
+
+// Unused returns the number of entries that were not replayed
+func (c *Cassette) Unused() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	unused := 0
+	for _, used := range c.used {
+		if !used {
+			unused++
+		}
+	}
+
+	return unused
+}
+
+// Close closes the cassette file in record mode
+func (c *Cassette) Close() error {
+	if c.file == nil {
+		return nil
+	}
+
+	return c.file.Close()
+}
+
+// recordRequest describes the request as it is written to the cassette
+func (c *Cassette) recordRequest(request *http.Request, body []byte) cassetteRequest {
+	// the hash is of the redacted body, so it gives nothing away either
+	body = c.config.Redaction.body(body)
+	hash := sha256.Sum256(body)
+	stored, encoding := encodeBody(body)
+	return cassetteRequest{
+		Method:       request.Method,
+		URL:          c.config.Redaction.url(request.URL),
+		Header:       c.config.Redaction.headers(request.Header),
+		Body:         stored,
+		BodyHash:     hex.EncodeToString(hash[:]),
+		BodyEncoding: encoding,
+	}
+}
+
+// record sends the request on and appends the pair to the cassette
+func (c *Cassette) record(request *http.Request, recorded cassetteRequest) (*http.Response, error) {
+	response, err := c.config.Transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// the length no longer holds once body fields are redacted
+	header := c.config.Redaction.headers(response.Header)
+	header.Del("Content-Length")
+
+	stored, encoding := encodeBody(c.config.Redaction.body(body))
+	line, err := json.Marshal(cassetteEntry{
+		Request: recorded,
+		Response: cassetteResponse{
+			StatusCode:   response.StatusCode,
+			Header:       header,
+			Body:         stored,
+			BodyEncoding: encoding,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.file.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// replay serves the first unused entry that matches the request
+func (c *Cassette) replay(request *http.Request, recorded cassetteRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, entry := range c.entries {
+		if c.used[i] || !c.matches(entry.Request, recorded) {
+			continue
+		}
+		body, err := decodeBody(entry.Response.Body, entry.Response.BodyEncoding)
+		if err != nil {
+			return nil, err
+		}
+		c.used[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", entry.Response.StatusCode, http.StatusText(entry.Response.StatusCode)),
+			StatusCode:    entry.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        entry.Response.Header,
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       request,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%s %s: %w in %s", request.Method, recorded.URL, ErrNoCassetteEntry, c.path)
+}
+
+// matches tells whether the request shares what is matched with the
+// recorded one
+func (c *Cassette) matches(recorded, request cassetteRequest) bool {
+	if c.match.Method && recorded.Method != request.Method {
+		return false
+	}
+	if c.match.URL && recorded.URL != request.URL {
+		return false
+	}
+	if c.match.Body && recorded.BodyHash != request.BodyHash {
+		return false
+	}
+	for _, name := range c.match.Headers {
+		if recorded.Header.Get(name) != request.Header.Get(name) {
+			return false
+		}
+	}
+
+	return true
+}
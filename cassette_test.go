@@ -0,0 +1,226 @@
+package blaster
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cassette", func() {
+	var (
+		ctx    context.Context
+		dir    string
+		path   string
+		server *httptest.Server
+		hits   int
+	)
+
+	type account struct {
+		ID       string `json:"id"`
+		Password string `json:"password,omitempty"`
+		Token    string `json:"token,omitempty"`
+	}
+
+	// record sends a GET and a POST through a recording cassette
+	record := func(config CassetteConfig) {
+		config.Mode = CassetteRecord
+		cassette, err := NewCassette(path, config)
+		Expect(err).To(BeNil())
+		defer cassette.Close()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/accounts/1", Transport: cassette})
+		c.SetHeader("Authorization", "Bearer secret")
+		c.SetHeader("X-Tenant", "acme")
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+
+		c, _ = New(ClientOptions{Endpoint: server.URL + "/accounts", Transport: cassette})
+		statusCode, err = c.Post(ctx, account{ID: "2", Password: "hunter2"})
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusCreated))
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		hits = 0
+		dir, _ = ioutil.TempDir("", "cassette")
+		path = filepath.Join(dir, "accounts.jsonl")
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Set-Cookie", "session=secret")
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"id":"2","token":"tok-123"}`))
+				return
+			}
+			w.Write([]byte(`{"id":"1"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	// region record
+
+	It("writes each pair as a line with secrets redacted", func() {
+		record(CassetteConfig{Redaction: Redaction{Fields: []string{"password", "token"}}})
+		Expect(hits).To(Equal(2))
+
+		raw, _ := ioutil.ReadFile(path)
+		lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(ContainSubstring(`"method":"GET"`))
+		Expect(lines[0]).To(ContainSubstring(`"Authorization":["[REDACTED]"]`))
+		Expect(lines[0]).To(ContainSubstring(`"Set-Cookie":["[REDACTED]"]`))
+		Expect(lines[1]).To(ContainSubstring(`"status_code":201`))
+		Expect(string(raw)).ToNot(ContainSubstring("secret"))
+		Expect(string(raw)).ToNot(ContainSubstring("hunter2"))
+		Expect(string(raw)).ToNot(ContainSubstring("tok-123"))
+	})
+
+	It("redacts query parameters and hashes the redacted body", func() {
+		redaction := Redaction{Fields: []string{"password"}, QueryParams: []string{"api_key"}}
+		cassette, _ := NewCassette(path, CassetteConfig{Mode: CassetteRecord, Redaction: redaction})
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/accounts?api_key=key-123&page=2", Transport: cassette})
+		c.Post(ctx, account{ID: "2", Password: "hunter2"})
+		cassette.Close()
+
+		raw, _ := ioutil.ReadFile(path)
+		Expect(string(raw)).ToNot(ContainSubstring("key-123"))
+		Expect(string(raw)).To(ContainSubstring("page=2"))
+		redactedHash := sha256.Sum256([]byte(`{"id":"2","password":"[REDACTED]"}`))
+		Expect(string(raw)).To(ContainSubstring(hex.EncodeToString(redactedHash[:])))
+
+		// the same request, with other secrets, matches once redacted
+		cassette, _ = NewCassette(path, CassetteConfig{Redaction: redaction})
+		c, _ = New(ClientOptions{Endpoint: server.URL + "/accounts?api_key=key-456&page=2", Transport: cassette})
+		statusCode, err := c.Post(ctx, account{ID: "2", Password: "hunter3"})
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusCreated))
+	})
+
+	It("leaves the default http client alone", func() {
+		cassette, _ := NewCassette(path, CassetteConfig{Mode: CassetteRecord})
+		defer cassette.Close()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL, Transport: cassette})
+		Expect(c.client).ToNot(BeIdenticalTo(http.DefaultClient))
+		Expect(c.client.Transport).To(Equal(cassette))
+		Expect(http.DefaultClient.Transport).To(BeNil())
+	})
+
+	// endregion
+
+	// region replay
+
+	It("serves the recorded responses offline", func() {
+		redaction := Redaction{Fields: []string{"password", "token"}}
+		record(CassetteConfig{Redaction: redaction})
+		server.Close()
+
+		cassette, err := NewCassette(path, CassetteConfig{Redaction: redaction})
+		Expect(err).To(BeNil())
+
+		var got account
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/accounts", Transport: cassette, WillSaturate: &got})
+		statusCode, err := c.Post(ctx, account{ID: "2", Password: "hunter2"})
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusCreated))
+		Expect(got).To(Equal(account{ID: "2", Token: redactedValue}))
+
+		c, _ = New(ClientOptions{Endpoint: server.URL + "/accounts/1", Transport: cassette, WillSaturate: &got})
+		statusCode, err = c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(c.Response().Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(cassette.Unused()).To(BeZero())
+		Expect(hits).To(Equal(2))
+	})
+
+	It("replays binary bodies byte for byte", func() {
+		binary := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe, 0x00, 0x80}
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(binary)
+		})
+
+		cassette, _ := NewCassette(path, CassetteConfig{Mode: CassetteRecord})
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/blobs", Transport: cassette, KeepRawResponse: true})
+		c.SetContentType("application/octet-stream")
+		c.Post(ctx, binary)
+		cassette.Close()
+
+		raw, _ := ioutil.ReadFile(path)
+		Expect(string(raw)).To(ContainSubstring(`"body_encoding":"base64"`))
+
+		cassette, _ = NewCassette(path, CassetteConfig{})
+		c, _ = New(ClientOptions{Endpoint: server.URL + "/blobs", Transport: cassette, KeepRawResponse: true})
+		c.SetContentType("application/octet-stream")
+		statusCode, err := c.Post(ctx, binary)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(c.RawResponse()).To(Equal(binary))
+		Expect(c.Response().Body).To(Equal(binary))
+		Expect(hits).To(Equal(1))
+	})
+
+	It("fails requests that were not recorded", func() {
+		record(CassetteConfig{})
+		cassette, _ := NewCassette(path, CassetteConfig{})
+
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/accounts", Transport: cassette})
+		_, err := c.Post(ctx, account{ID: "3"})
+		Expect(errors.Is(err, ErrNoCassetteEntry)).To(BeTrue())
+	})
+
+	It("serves each entry once", func() {
+		record(CassetteConfig{})
+		cassette, _ := NewCassette(path, CassetteConfig{})
+
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/accounts/1", Transport: cassette})
+		_, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		_, err = c.Get(ctx)
+		Expect(errors.Is(err, ErrNoCassetteEntry)).To(BeTrue())
+		Expect(cassette.Unused()).To(Equal(1))
+	})
+
+	It("matches on the configured rules", func() {
+		record(CassetteConfig{})
+		cassette, _ := NewCassette(path, CassetteConfig{Match: &CassetteMatch{Method: true, Headers: []string{"X-Tenant"}}})
+
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/elsewhere", Transport: cassette})
+		c.SetHeader("X-Tenant", "other")
+		_, err := c.Get(ctx)
+		Expect(errors.Is(err, ErrNoCassetteEntry)).To(BeTrue())
+
+		c, _ = New(ClientOptions{Endpoint: server.URL + "/elsewhere", Transport: cassette})
+		c.SetHeader("X-Tenant", "acme")
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+	})
+
+	It("requires the cassette to exist for replay", func() {
+		_, err := NewCassette(filepath.Join(dir, "missing.jsonl"), CassetteConfig{})
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	// endregion
+})
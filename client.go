@@ -17,7 +17,9 @@ import (
 	"time"
 
 	"github.com/InVisionApp/go-logger"
+	"github.com/newrelic/go-agent"
 	"github.com/opentracing/opentracing-go"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -56,8 +58,70 @@ type ClientOptions struct {
 	Headers                    map[string]string
 	KeepRawResponse            bool
 	Logger                     log.Logger
+
+	// Endpoints is a set of base urls to balance requests across.  When
+	// it is set, Endpoint only needs to hold the path and query.
+	Endpoints []string
+
+	// EndpointResolver resolves the called service to the base urls to
+	// balance requests across.  It takes precedence over Endpoints.
+	EndpointResolver EndpointResolver
+
+	// BalancingStrategy picks one of the built-in balancers.  Defaults to RoundRobin.
+	BalancingStrategy BalancingStrategy
+
+	// Balancer is a custom balancer.  It takes precedence over BalancingStrategy.
+	Balancer Balancer
+
+	// HashKeyHeader names a request header whose value is used as the key
+	// for the ConsistentHash strategy, unless the context already carries
+	// one, see WithHashKey
+	HashKeyHeader string
+
+	// OutlierDetector takes misbehaving endpoints out of rotation.  It
+	// should be shared by all clients of the called service.
+	OutlierDetector *OutlierDetector
+
+	// HealthChecker actively probes the endpoints of the called service.
+	// Only healthy endpoints are balanced across.  The caller owns its
+	// lifecycle, see HealthChecker.Start and HealthChecker.Stop.
+	HealthChecker *HealthChecker
+
+	// Metrics records request metrics.  Defaults to Defaults.Metrics.
+	Metrics Metrics
+
+	// RequestLogging logs every request as a structured line.
+	// Defaults to Defaults.RequestLogging.
+	RequestLogging *RequestLogging
+
+	// NRTxnName names the New Relic transaction the request is made in,
+	// see SetNRTxnName
+	NRTxnName string
+
+	// Hooks are called on the lifecycle events of the request.
+	// Defaults to Defaults.Hooks.
+	Hooks *Hooks
+
+	// Transport sends the requests instead of the transport of the
+	// default http client, e.g. a Cassette
+	Transport http.RoundTripper
+
+	// FaultInjector injects faults into requests for chaos testing.
+	// Defaults to Defaults.FaultInjector.
+	FaultInjector *FaultInjector
+
+	// ContractValidation validates bodies against a JSON Schema or
+	// OpenAPI contract.  Defaults to Defaults.ContractValidation.
+	ContractValidation *ContractValidation
+
+	// Clock measures the durations and waits out injected latency.
+	// Defaults to Defaults.Clock.
+	Clock Clock
 }
 
+// Client implements IClient
+var _ IClient = (*Client)(nil)
+
 // Client encapsulates the http Request functionality
 type Client struct {
 	// prototype will be saturated when the Request succeeds.
@@ -122,6 +186,57 @@ type Client struct {
 	// openTracingSpan gets attached right before request is made
 	openTracingSpan opentracing.Span
 
+	// otelSpan is the OpenTelemetry span of the current attempt
+	otelSpan oteltrace.Span
+
+	// metrics records request metrics
+	metrics Metrics
+
+	// timings records the network timings of the current attempt
+	timings *timingRecorder
+
+	// response is the outcome of the request, once it was launched
+	response *Response
+
+	// requestLogging configures the structured request log line
+	requestLogging *RequestLogging
+
+	// requestBody is the encoded payload that was sent
+	requestBody []byte
+
+	// request is the last attempt as it was sent
+	request *Request
+
+	// nrTxnName names the New Relic transaction
+	nrTxnName string
+
+	// nrSegment is the New Relic external segment of the current attempt
+	nrSegment *newrelic.ExternalSegment
+
+	// hooks are called on the lifecycle events of the request
+	hooks *Hooks
+
+	// errorClass is the kind of error the request failed with
+	errorClass string
+
+	// contextTags are the statsd tags carried by the request context
+	contextTags []string
+
+	// faults injects faults into requests, if set
+	faults *FaultInjector
+
+	// fault names the faults injected into the current attempt, if any
+	fault string
+
+	// contractValidation validates bodies against a contract, if set
+	contractValidation *ContractValidation
+
+	// clock measures the durations and waits out injected latency
+	clock Clock
+
+	// requestErr is the error the request failed with, if any
+	requestErr error
+
 	// status code gets tacked on after the request
 	statusCode int
 
@@ -130,6 +245,24 @@ type Client struct {
 
 	// mask of the route
 	routeMask string
+
+	// resolves the base urls to balance across, if any
+	resolver EndpointResolver
+
+	// picks one of the resolved base urls for each attempt
+	balancer Balancer
+
+	// the url the request was actually sent to
+	selectedEndpoint *url.URL
+
+	// takes misbehaving endpoints out of rotation, if set
+	outliers *OutlierDetector
+
+	// keeps unhealthy endpoints out of rotation, if set
+	healthChecker *HealthChecker
+
+	// header holding the key for consistent hashing, if set
+	hashKeyHeader string
 }
 
 type gzreadCloser struct {
@@ -171,8 +304,50 @@ func (c *Client) statsdReportDuration() {
 			fmt.Sprintf("called-service:%s", c.calledService),
 			fmt.Sprintf("route:%s", c.routeMask),
 		}
-		tags = append(c.statsdTags, tags...)
-		c.statsdClient.Timing(c.statsdStat, c.duration, tags, pkgStatsdRate)
+		c.statsdClient.Timing(c.statsdStat, c.duration, c.tagsWith(tags...), pkgStatsdRate)
+	}
+}
+
+// reports an endpoint leaving or returning to rotation
+func (c *Client) reportOutlierEvent(event OutlierEvent) {
+	fields := map[string]interface{}{
+		"type":           NAME,
+		"endpoint":       event.Endpoint.URL.Host,
+		"called_service": c.calledService,
+	}
+	tags := []string{
+		fmt.Sprintf("endpoint:%s", event.Endpoint.URL.Host),
+		fmt.Sprintf("called-service:%s", c.calledService),
+	}
+
+	stat := "outlier.returned"
+	if event.Ejected {
+		stat = "outlier.ejected"
+		fields["reason"] = event.Reason
+		fields["ejection_duration"] = event.Duration.String()
+		tags = append(tags, fmt.Sprintf("reason:%s", event.Reason))
+		c.logger.WithFields(fields).Warn("endpoint ejected")
+	} else {
+		c.logger.WithFields(fields).Info("endpoint returned to rotation")
+	}
+
+	if c.statsdClient != nil {
+		c.statsdClient.Incr(fmt.Sprintf("%s.%s", c.statsdStat, stat), c.tagsWith(tags...), pkgStatsdRate)
+	}
+}
+
+// feeds the outcome of an attempt to the outlier detector
+func (c *Client) observeOutlier(resolved []*Endpoint, ep *Endpoint, response *http.Response, responseErr error, latency time.Duration) {
+	if c.outliers == nil {
+		return
+	}
+
+	statusCode := 0
+	if response != nil {
+		statusCode = response.StatusCode
+	}
+	if event := c.outliers.Observe(resolved, ep, statusCode, responseErr, latency); event != nil {
+		c.reportOutlierEvent(*event)
 	}
 }
 
@@ -241,16 +416,33 @@ func (c *Client) processOutgoingPayload(payload interface{}) ([]byte, error) {
 }
 
 // begin tracking request
-func (c *Client) immediatePreflight(ctx context.Context, request *http.Request) {
+func (c *Client) immediatePreflight(ctx context.Context, request *http.Request, attempt int) *http.Request {
 	// if tracing is enabled, wrap the request with the tracing provider
 	if pkgTracerProviderFunc != nil {
-		var span opentracing.Span
 		// The openTracingSpan name needs to be sufficiently generic to avoid a grouping issue in Lightstep (breaking their search).
 		// It should not be the full URL, URI or Path, as that often inclues IDs.
 		// Note that 'url' is recorded, but as a tag on the openTracingSpan, from https://github.com/InVisionApp/opentracing-go-helpers
-		request, span = pkgTracerProviderFunc(ctx, fmt.Sprintf("%s %s", c.method, c.endpoint.Host), request)
+		wrapped, span := pkgTracerProviderFunc(ctx, fmt.Sprintf("%s %s", c.method, c.operationHost()), request)
+		if wrapped != nil {
+			request = wrapped
+		}
 		c.openTracingSpan = span
+		if span != nil {
+			c.enrichOpenTracingSpan(request, attempt)
+		}
 	}
+
+	return request
+}
+
+// operationHost is the host used to name the operation.  When balancing,
+// the chosen instance varies per request, so the called service is used.
+func (c *Client) operationHost() string {
+	if c.resolver != nil && c.calledService != "" {
+		return c.calledService
+	}
+
+	return c.endpoint.Host
 }
 
 // process response
@@ -298,13 +490,19 @@ func (c *Client) processResponseData(payload []byte, contentType string) error {
 	return nil
 }
 
-// close tracking
+// close tracking.  Spans are finished on every path, the duration
+// is only reported for requests that were actually launched.
 func (c *Client) cleanup() {
+	if c.response != nil {
+		c.response.Duration = c.duration
+		c.logRequest()
+	}
+	c.hookOutcome()
+	c.endOTelSpan()
+	c.finishOpenTracingSpan()
 	if !c.internalError {
 		c.statsdReportDuration()
-		if c.openTracingSpan != nil {
-			c.openTracingSpan.Finish()
-		}
+		c.reportMetricDuration()
 	}
 }
 
@@ -314,6 +512,11 @@ func (c *Client) failBeforeRequest(err error) (int, error) {
 		"error_message": err.Error(),
 		"type":          NAME,
 	}).Error("request failed")
+	c.recordOTelError(err)
+	c.recordOpenTracingError(err)
+	c.reportMetricError(MetricErrorRequest)
+	c.requestErr = err
+	c.errorClass = MetricErrorRequest
 	c.statusCode = http.StatusInternalServerError
 	c.internalError = true
 	return c.statusCode, err
@@ -325,10 +528,46 @@ func (c *Client) failAfterRequest(err error) (int, error) {
 		"error_message": err.Error(),
 		"type":          NAME,
 	}).Error("request failed")
+	c.recordOTelError(err)
+	c.recordOpenTracingError(err)
+	c.reportMetricError(metricErrorKind(err))
+	c.requestErr = err
+	c.errorClass = metricErrorKind(err)
 	c.statusCode = http.StatusInternalServerError
 	return c.statusCode, err
 }
 
+// nextTarget picks the url for the next attempt.  Without a resolver
+// this is always the client endpoint, and the returned endpoint is nil.
+func (c *Client) nextTarget(ctx context.Context, candidates []*Endpoint, tried map[*Endpoint]bool) (*url.URL, *Endpoint, error) {
+	if c.resolver == nil {
+		return c.endpoint, nil, nil
+	}
+
+	ep, err := c.balancer.Pick(ctx, remainingEndpoints(candidates, tried))
+	if err != nil {
+		return nil, nil, err
+	}
+	tried[ep] = true
+
+	return joinEndpoint(ep.URL, c.endpoint), ep, nil
+}
+
+// prepareRequest creates the internal HTTP request for a single attempt
+func (c *Client) prepareRequest(target *url.URL, payloadBytes []byte) (*http.Request, error) {
+	request, createRequestErr := http.NewRequest(c.method, target.String(), ioutil.NopCloser(bytes.NewReader(payloadBytes)))
+	if createRequestErr != nil {
+		return nil, createRequestErr
+	}
+
+	// make sure that request conforms to REQ014 if its required
+	if req014Err := c.conformsToReq014(request); req014Err != nil {
+		return nil, req014Err
+	}
+
+	return request, nil
+}
+
 // doInternal will perform the actual request.  This function
 // is either called from within a circuit breaker, or directly
 // from Do.
@@ -337,43 +576,138 @@ func (c *Client) doInternal(ctx context.Context, payload interface{}) (int, erro
 	// set headers that depend on context values
 	c.applyContextDependentHeaders(ctx)
 
+	if c.clock == nil {
+		c.clock = clockOrDefault(nil)
+	}
+
 	// start the clock and report the duration when this function exits
 	defer func(c *Client, begin time.Time) {
-		c.duration = time.Now().Sub(begin)
+		c.duration = c.clock.Since(begin)
 		c.cleanup()
-	}(c, time.Now())
+	}(c, c.clock.Now())
 
 	// process outgoing payload
 	payloadBytes, payloadErr := c.processOutgoingPayload(payload)
 	if payloadErr != nil {
 		return c.failBeforeRequest(payloadErr)
 	}
+	c.requestBody = payloadBytes
+	if contractErr := c.checkRequestContract(payloadBytes); contractErr != nil {
+		return c.failBeforeRequest(contractErr)
+	}
 
-	// create the internal HTTP request
-	request, createRequestErr := http.NewRequest(c.method, c.endpoint.String(), ioutil.NopCloser(bytes.NewReader(payloadBytes)))
-	if createRequestErr != nil {
-		return c.failBeforeRequest(createRequestErr)
+	// resolve the endpoints to balance across, if any
+	var candidates []*Endpoint
+	if c.resolver != nil {
+		var resolveErr error
+		if candidates, resolveErr = c.resolver.Resolve(ctx, c.resolveName()); resolveErr != nil {
+			return c.failBeforeRequest(resolveErr)
+		}
 	}
 
-	// make sure that request conforms to REQ014 if its required
-	if req014Err := c.conformsToReq014(request); req014Err != nil {
-		return c.failBeforeRequest(req014Err)
+	// leave out the endpoints that have been ejected or are unhealthy.  The
+	// outlier detector sees every resolved endpoint, since it forgets the
+	// ones it is not given.
+	resolved := candidates
+	if c.outliers != nil && len(candidates) > 0 {
+		var events []OutlierEvent
+		candidates, events = c.outliers.Available(candidates)
+		for _, event := range events {
+			c.reportOutlierEvent(event)
+		}
+	}
+	if c.healthChecker != nil && len(candidates) > 0 {
+		candidates = c.healthChecker.Available(candidates)
 	}
 
-	c.logger.WithFields(map[string]interface{}{
-		"type": NAME,
-	}).Debugf("launching %s request to %s", c.method, c.endpoint.Host)
+	// carry the hash key from the header over to the balancer
+	if _, ok := HashKeyFromContext(ctx); !ok && c.hashKeyHeader != "" && c.headers[c.hashKeyHeader] != "" {
+		ctx = WithHashKey(ctx, c.headers[c.hashKeyHeader])
+	}
+
+	var (
+		response    *http.Response
+		responseErr error
+		attempts    int
+		tried       = make(map[*Endpoint]bool, len(candidates))
+	)
+	for attempt := 1; ; attempt++ {
+		target, ep, pickErr := c.nextTarget(ctx, candidates, tried)
+		if pickErr != nil {
+			return c.failBeforeRequest(pickErr)
+		}
+
+		// create the internal HTTP request
+		request, prepareErr := c.prepareRequest(target, payloadBytes)
+		if prepareErr != nil {
+			return c.failBeforeRequest(prepareErr)
+		}
+
+		c.selectedEndpoint = target
+		c.logger.WithFields(map[string]interface{}{
+			"type":     NAME,
+			"endpoint": target.Host,
+		}).Debugf("launching %s request to %s", c.method, target.Host)
+
+		// RUN IT
+		c.startOTelSpan(ctx, request, attempt)
+		request = c.immediatePreflight(ctx, request, attempt)
+		request = c.traceTimings(request)
+		c.startNewRelicSegment(ctx, request)
+		fault := c.planFault()
+		c.recordRequest(request, payloadBytes)
+		c.hookRequest(attempt)
+		if ep != nil {
+			ep.acquire()
+		}
+		if c.metrics != nil {
+			c.metrics.InFlight(c.requestLabels(), 1)
+		}
+		attemptBegin := c.clock.Now()
+		// --------------------------------------------
+		// --------------------------------------------
+		response, responseErr = c.send(ctx, request, fault)
+		// --------------------------------------------
+		// --------------------------------------------
+		c.endNewRelicSegment(response)
+		if c.metrics != nil {
+			c.metrics.InFlight(c.requestLabels(), -1)
+		}
+		if ep != nil {
+			ep.release()
+			c.observeOutlier(resolved, ep, response, responseErr, c.clock.Since(attemptBegin))
+		}
+		if response != nil {
+			c.setOTelResponse(response.StatusCode)
+			c.setOpenTracingResponse(response.StatusCode)
+		}
+
+		// fail over to the next endpoint if this one could not be reached
+		if responseErr == nil || ep == nil || !isConnectionError(responseErr) || len(tried) >= len(candidates) {
+			attempts = attempt
+			break
+		}
+		c.reportTimings()
 
-	// RUN IT
-	c.immediatePreflight(ctx, request)
-	// --------------------------------------------
-	// --------------------------------------------
-	response, responseErr := c.client.Do(request)
-	// --------------------------------------------
-	// --------------------------------------------
+		c.logger.WithFields(map[string]interface{}{
+			"error_message": responseErr.Error(),
+			"type":          NAME,
+			"endpoint":      target.Host,
+		}).Warn("endpoint unreachable, failing over")
+		c.recordOTelError(responseErr)
+		c.recordOpenTracingError(responseErr)
+		c.endOTelSpan()
+		c.finishOpenTracingSpan()
+		if c.metrics != nil {
+			c.metrics.Retry(c.requestLabels())
+		}
+		c.hookRetry(attempt, c.clock.Since(attemptBegin), responseErr)
+	}
 
 	// request error
 	if responseErr != nil {
+		c.reportTimings()
+		c.buildResponse(nil, nil, attempts)
 		switch responseErr.(type) {
 		case net.Error:
 			if responseErr.(net.Error).Timeout() {
@@ -412,7 +746,16 @@ func (c *Client) doInternal(ctx context.Context, payload interface{}) (int, erro
 
 	// get response body
 	// ReadAll is called previously and would throw an error in http.Client.Do
+	bodyStart := c.clock.Now()
 	body, _ := ioutil.ReadAll(response.Body)
+	c.timings.body(bodyStart, c.clock.Now())
+	c.reportTimings()
+	c.buildResponse(response, body, attempts)
+	c.reportMetricSizes(len(payloadBytes), len(body))
+
+	if contractErr := c.checkResponseContract(response.StatusCode, body); contractErr != nil {
+		return c.failAfterRequest(contractErr)
+	}
 
 	// process response
 	if processResponseErr := c.processResponseData(body, response.Header.Get(contentTypeHeader)); processResponseErr != nil {
@@ -425,8 +768,9 @@ func (c *Client) doInternal(ctx context.Context, payload interface{}) (int, erro
 	}
 
 	c.logger.WithFields(map[string]interface{}{
-		"type": NAME,
-	}).Debugf("%s request to %s returned code %d", c.method, c.endpoint.Host, c.statusCode)
+		"type":     NAME,
+		"endpoint": c.selectedEndpoint.Host,
+	}).Debugf("%s request to %s returned code %d", c.method, c.selectedEndpoint.Host, c.statusCode)
 
 	return c.statusCode, nil
 }
@@ -452,6 +796,14 @@ func (c *Client) Do(ctx context.Context, method string, payload interface{}) (in
 		c.logger = log.NewNoop()
 	}
 
+	// log the fields carried by the context for this request only
+	if fields := LogFieldsFromContext(ctx); len(fields) > 0 {
+		logger := c.logger
+		c.logger = logger.WithFields(fields)
+		defer func() { c.logger = logger }()
+	}
+
+	c.resetRequestState()
 	c.method = method
 	if c.endpoint == nil {
 		err := errors.New("endpoint for request not set")
@@ -464,6 +816,8 @@ func (c *Client) Do(ctx context.Context, method string, payload interface{}) (in
 		return http.StatusInternalServerError, err
 	}
 
+	c.applyContextTags(ctx)
+
 	if c.cb == nil {
 		return c.doInternal(ctx, payload)
 	}
@@ -480,12 +834,33 @@ func (c *Client) Do(ctx context.Context, method string, payload interface{}) (in
 			"error_message": "circuit breaker open or half-open",
 			"type":          NAME,
 		}).Warn("request blocked")
+		if c.metrics != nil {
+			c.metrics.BreakerRejection(c.metricLabels())
+		}
+		c.hookBreakerRejection(err)
 		sc = http.StatusFailedDependency
 	}
 
 	return sc.(int), err
 }
 
+// resetRequestState forgets the outcome of the previous request, so a
+// reused client does not log, hook or report it again
+func (c *Client) resetRequestState() {
+	c.fault = ""
+	c.requestErr = nil
+	c.errorClass = ""
+	c.response = nil
+	c.request = nil
+	c.requestBody = nil
+	c.internalError = false
+	c.statusCode = 0
+	c.responseIsError = false
+	c.rawresponse = nil
+	c.timings = nil
+	c.duration = 0
+}
+
 // KeepRawResponse will cause the raw bytes from the http response
 // to be retained
 func (c *Client) KeepRawResponse() {
@@ -505,7 +880,11 @@ func (c *Client) SetTimeoutMS(timeout int) {
 		timeout = 0
 	}
 
-	c.client.Timeout = time.Duration(timeout) * time.Millisecond
+	// under MOCKING_HTTP the http client is the shared http.DefaultClient,
+	// so the timeout goes on a copy
+	client := *c.client
+	client.Timeout = time.Duration(timeout) * time.Millisecond
+	c.client = &client
 }
 
 // SetLogger will set the client's internal logger.
@@ -561,7 +940,9 @@ func (c *Client) SetCircuitBreaker(cb CircuitBreakerPrototype) {
 	c.cb = cb
 }
 
-// SetStatsdDelegate will set the statsd client, the stat, and tags
+// SetStatsdDelegate will set the statsd client, the stat, and tags.  The
+// client only reports the request duration to it, see NewStatsdMetrics
+// for more, but not both on the same stat, or the duration is sent twice.
 func (c *Client) SetStatsdDelegate(sdClient StatsdClientPrototype, stat string, tags []string) {
 	c.statsdClient = sdClient
 	c.statsdTags = tags
@@ -599,6 +980,67 @@ func (c *Client) Duration() time.Duration {
 	return c.duration
 }
 
+// SelectedEndpoint returns the url the request was actually sent to.
+// When balancing across several endpoints, this tells which one was
+// chosen.  It is nil until the request has been launched.
+func (c *Client) SelectedEndpoint() *url.URL {
+	return c.selectedEndpoint
+}
+
+// SetMetrics sets the optional Metrics the request is recorded to
+func (c *Client) SetMetrics(metrics Metrics) {
+	c.metrics = metrics
+}
+
+// SetRequestLogging sets the optional structured logging of the request
+func (c *Client) SetRequestLogging(logging *RequestLogging) {
+	c.requestLogging = logging
+}
+
+// SetHooks sets the callbacks for the lifecycle events of the request
+func (c *Client) SetHooks(hooks *Hooks) {
+	c.hooks = hooks
+}
+
+// SetContractValidation sets the optional validation of bodies against a contract
+func (c *Client) SetContractValidation(validation *ContractValidation) {
+	c.contractValidation = validation
+}
+
+// SetFaultInjector sets the optional injector of faults for chaos testing
+func (c *Client) SetFaultInjector(injector *FaultInjector) {
+	c.faults = injector
+}
+
+// SetClock sets the clock that measures the durations and waits out
+// injected latency
+func (c *Client) SetClock(clock Clock) {
+	c.clock = clockOrDefault(clock)
+}
+
+// SetOutlierDetector sets the optional detector that takes misbehaving
+// endpoints out of rotation.  It only applies when balancing.
+func (c *Client) SetOutlierDetector(detector *OutlierDetector) {
+	c.outliers = detector
+}
+
+// SetHealthChecker sets the optional health checker that keeps unhealthy
+// endpoints out of rotation.  It only applies when balancing.
+func (c *Client) SetHealthChecker(checker *HealthChecker) {
+	c.healthChecker = checker
+}
+
+// SetEndpointResolver sets the resolver and balancer used to spread requests
+// across several endpoints.  If balancer is nil, requests are balanced round robin.
+func (c *Client) SetEndpointResolver(resolver EndpointResolver, balancer Balancer) {
+	if balancer == nil {
+		balancer = sharedBalancer(resolver, RoundRobin)
+	}
+
+	c.resolver = resolver
+	c.balancer = balancer
+}
+
 //
 // Convenience Functions
 // ========================================================
@@ -0,0 +1,124 @@
+package blaster
+
+import "time"
+
+// Clock tells the time and waits.  Clients use it to measure durations
+// and to wait out injected latency, outlier detectors for their error
+// windows and ejections, resolvers and fault injectors for their TTLs and
+// reload intervals, and health checkers, resolver watches and the statsd
+// client for their tickers, so tests can swap in a fake clock, see
+// blastertest.Clock.
+//
+// The http client timeout always runs on the wall clock, and so do
+// circuit breakers, which the caller brings, see CircuitBreakerPrototype.
+// Failover to the next endpoint is immediate, there is no backoff to wait.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+
+	// NewTimer returns a timer that fires once d has elapsed
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker returns a ticker that ticks every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a single event of a Clock, see time.Timer
+type Timer interface {
+	// C delivers the time when the timer fires
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing.  It returns false if the timer
+	// already fired or was stopped.
+	Stop() bool
+}
+
+// Ticker is the repeated event of a Clock, see time.Ticker
+type Ticker interface {
+	// C delivers the time of each tick.  Ticks are dropped when the
+	// reader falls behind.
+	C() <-chan time.Time
+
+	// Stop turns the ticker off
+	Stop()
+}
+
+// SystemClock is the Clock of the wall time
+var SystemClock Clock = systemClock{}
+
+// systemClock implements Clock with the time package
+type systemClock struct{}
+
+// Now implements Clock
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Since implements Clock
+func (systemClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// NewTimer implements Clock
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// NewTicker implements Clock
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// systemTimer implements Timer with a time.Timer
+type systemTimer struct {
+	timer *time.Timer
+}
+
+// C implements Timer
+func (t systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+// Stop implements Timer
+func (t systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// systemTicker implements Ticker with a time.Ticker
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+// C implements Ticker
+func (t systemTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+// Stop implements Ticker
+func (t systemTicker) Stop() {
+	t.ticker.Stop()
+}
+
+// clockOrDefault returns the clock if set, then the package clock, then
+// the wall clock
+func clockOrDefault(clock Clock) Clock {
+	if clock != nil {
+		return clock
+	}
+	if pkgClock != nil {
+		return pkgClock
+	}
+
+	return SystemClock
+}
+
+// isStale tells whether the interval has passed since the last check.  A
+// clock that is behind the check, e.g. a fake clock set after the first
+// check, counts as stale too.
+func isStale(clock Clock, checked time.Time, interval time.Duration) bool {
+	now := clock.Now()
+	return now.Before(checked) || now.Sub(checked) >= interval
+}
@@ -0,0 +1,152 @@
+package blaster
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// steppingClock is a Clock that only moves when told to
+type steppingClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *steppingClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *steppingClock) NewTimer(d time.Duration) Timer {
+	return SystemClock.NewTimer(0)
+}
+
+func (c *steppingClock) NewTicker(d time.Duration) Ticker {
+	return SystemClock.NewTicker(d)
+}
+
+func (c *steppingClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+var _ = Describe("Clock", func() {
+	var (
+		ctx    context.Context
+		clock  *steppingClock
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		clock = &steppingClock{now: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clock.advance(3 * time.Second)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		SetDefaults(&Defaults{})
+	})
+
+	It("measures the request duration with the clock of the client", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Clock: clock})
+		c.Get(ctx)
+		Expect(c.Duration()).To(Equal(3 * time.Second))
+		Expect(c.Response().Timings.ServerProcessing).To(Equal(3 * time.Second))
+	})
+
+	It("falls back to the clock of the defaults", func() {
+		SetDefaults(&Defaults{Clock: clock})
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.Get(ctx)
+		Expect(c.Duration()).To(Equal(3 * time.Second))
+
+		c.SetClock(nil)
+		c.Get(ctx)
+		Expect(c.Duration()).To(Equal(3 * time.Second))
+	})
+
+	It("times the ejections of the outlier detector", func() {
+		ep, _ := NewEndpoint("http://one:8080", 1)
+		endpoints := []*Endpoint{ep, {URL: ep.URL}}
+		detector := NewOutlierDetector(OutlierDetection{ConsecutiveErrors: 1, BaseEjectionTime: time.Minute, Clock: clock})
+
+		Expect(detector.Observe(endpoints, ep, http.StatusBadGateway, nil, 0)).ToNot(BeNil())
+		clock.advance(59 * time.Second)
+		Expect(detector.Ejected(ep)).To(BeTrue())
+
+		clock.advance(time.Second)
+		Expect(detector.Ejected(ep)).To(BeFalse())
+		_, events := detector.Available(endpoints)
+		Expect(events).To(HaveLen(1))
+	})
+
+	It("expires the answers of the DNS resolver", func() {
+		lookups := 0
+		r := NewDNSResolver("example.com", time.Minute)
+		r.Clock = clock
+		r.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			lookups++
+			return "", []*net.SRV{{Target: "a.example.com.", Port: 8080}}, nil
+		}
+
+		r.Resolve(ctx, "user-service")
+		clock.advance(59 * time.Second)
+		r.Resolve(ctx, "user-service")
+		Expect(lookups).To(Equal(1))
+
+		clock.advance(time.Second)
+		r.Resolve(ctx, "user-service")
+		Expect(lookups).To(Equal(2))
+	})
+
+	It("times the checks of the file resolver and the fault injector for changes", func() {
+		dir, _ := ioutil.TempDir("", "clock")
+		defer os.RemoveAll(dir)
+		services := filepath.Join(dir, "services.yaml")
+		faults := filepath.Join(dir, "faults.yaml")
+		ioutil.WriteFile(services, []byte("user-service:\n  - url: http://10.0.0.1:8080\n"), 0644)
+		ioutil.WriteFile(faults, []byte("enabled: false\n"), 0644)
+
+		r, _ := NewFileResolver(services, time.Minute)
+		r.Clock = clock
+		f, _ := NewFaultInjectorFromFile(faults, time.Minute)
+		f.Clock = clock
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: f})
+
+		// the first check on the clock picks up where the wall clock left off
+		Expect(r.Resolve(ctx, "user-service")).To(HaveLen(1))
+		c.Get(ctx)
+
+		ioutil.WriteFile(services, []byte("user-service:\n  - url: http://10.0.0.1:8080\n  - url: http://10.0.0.2:8080\n"), 0644)
+		ioutil.WriteFile(faults, []byte("enabled: true\nrules:\n  - errors:\n      - percent: 100\n        kind: status\n        status_code: 503\n"), 0644)
+		// each request takes 3 seconds on the clock
+		clock.advance(50 * time.Second)
+		Expect(r.Resolve(ctx, "user-service")).To(HaveLen(1))
+		statusCode, _ := c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusOK))
+
+		clock.advance(10 * time.Second)
+		Expect(r.Resolve(ctx, "user-service")).To(HaveLen(2))
+		statusCode, _ = c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+	})
+})
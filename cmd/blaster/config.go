@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	"gopkg.in/yaml.v2"
+)
+
+// jsonContentType is the content type the client sends by default
+const jsonContentType = "application/json"
+
+// defaultsConfig holds the blaster.Defaults that make sense from the
+// command line
+type defaultsConfig struct {
+	ServiceName    string `json:"service_name" yaml:"service_name"`
+	UserAgent      string `json:"user_agent" yaml:"user_agent"`
+	RequireHeaders bool   `json:"require_headers" yaml:"require_headers"`
+
+	// RequestSource is sent as the Request-Source header.  Each request
+	// gets a new Request-ID.
+	RequestSource string `json:"request_source" yaml:"request_source"`
+}
+
+// apply sets the library defaults
+func (d defaultsConfig) apply() {
+	defaults := &blaster.Defaults{
+		ServiceName:    d.ServiceName,
+		UserAgent:      d.UserAgent,
+		RequireHeaders: d.RequireHeaders,
+		RequestIDProviderFunc: func(ctx context.Context) (string, bool) {
+			return newID(), true
+		},
+	}
+	if d.RequestSource != "" {
+		source := d.RequestSource
+		defaults.RequestSourceProviderFunc = func(ctx context.Context) (string, bool) {
+			return source, true
+		}
+	}
+
+	blaster.SetDefaults(defaults)
+}
+
+// readConfig reads a config file into v.  Files ending in .yaml or .yml
+// are read as YAML, anything else as JSON.
+func readConfig(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, v)
+	default:
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	return nil
+}
+
+// setHeaders sets the headers on the client under their canonical names,
+// so that Content-Type goes through SetContentType whatever its case, and
+// returns the content type the body is sent with
+func setHeaders(c *blaster.Client, headers map[string]string) string {
+	contentType := jsonContentType
+	for name, value := range headers {
+		name = http.CanonicalHeaderKey(name)
+		c.SetHeader(name, value)
+		if name == "Content-Type" {
+			contentType = value
+		}
+	}
+
+	return contentType
+}
+
+// payloadFor returns the payload that sends the body as it is.  The client
+// marshals payloads sent as exactly application/json, so a JSON body must
+// not be marshaled again; with any other content type, parameters
+// included, the body is sent as bytes.
+func payloadFor(body []byte, contentType string) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	if contentType == jsonContentType {
+		return json.RawMessage(body)
+	}
+
+	return body
+}
+
+// newID returns a random version 4 uuid
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster"
+)
+
+// errorTemplate is the error class of requests the spec could not render
+const errorTemplate = "template"
+
+// loadOptions shape the load
+type loadOptions struct {
+	// rate is the number of requests started per second, as fast as the
+	// workers allow if 0
+	rate float64
+
+	// concurrency is the number of workers sending requests
+	concurrency int
+
+	// duration is how long the load lasts
+	duration time.Duration
+
+	// requests stops the load after that many requests, if not 0
+	requests int
+}
+
+// loadCommand runs `blaster load`
+func loadCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("load", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	specPath := flags.String("spec", "", "request spec file, YAML or JSON (required)")
+	rate := flags.Float64("rate", 0, "requests per second, 0 to send as fast as the workers allow")
+	concurrency := flags.Int("concurrency", 10, "number of concurrent workers")
+	duration := flags.Duration("duration", 10*time.Second, "how long to send requests for")
+	requests := flags.Int("requests", 0, "stop after this many requests, 0 for no limit")
+	output := flags.String("output", "text", "report format, text or json")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *specPath == "" || *concurrency < 1 || *rate < 0 || *duration <= 0 ||
+		(*output != "text" && *output != "json") {
+		flags.Usage()
+		return 2
+	}
+
+	s, err := loadSpec(*specPath)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	s.Defaults.apply()
+
+	r := runLoad(context.Background(), s, loadOptions{
+		rate:        *rate,
+		concurrency: *concurrency,
+		duration:    *duration,
+		requests:    *requests,
+	})
+
+	if *output == "json" {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(r)
+	} else {
+		err = r.writeText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// runLoad sends the requests of the spec until the duration is over or
+// the request limit is reached, and reports on them
+func runLoad(ctx context.Context, s *spec, opts loadOptions) *report {
+	ctx, cancel := context.WithTimeout(ctx, opts.duration)
+	defer cancel()
+
+	rec := newRecorder()
+
+	// one transport for all the clients, so that connections are reused
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = opts.concurrency
+	defer transport.CloseIdleConnections()
+	breaker := s.newBreaker()
+
+	tickets := make(chan struct{})
+	go issueTickets(ctx, tickets, opts)
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range tickets {
+				send(ctx, s, transport, breaker, rec)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return rec.report(time.Since(start))
+}
+
+// issueTickets hands out one ticket per request to the workers, paced to
+// the rate if any, and closes the channel when the load is over
+func issueTickets(ctx context.Context, tickets chan<- struct{}, opts loadOptions) {
+	defer close(tickets)
+
+	var pace <-chan time.Time
+	if opts.rate > 0 {
+		interval := time.Duration(float64(time.Second) / opts.rate)
+		if interval <= 0 {
+			interval = time.Nanosecond
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		pace = ticker.C
+	}
+
+	for n := 0; opts.requests == 0 || n < opts.requests; n++ {
+		if pace != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-pace:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case tickets <- struct{}{}:
+		}
+	}
+}
+
+// send sends one request of the spec and records its outcome
+func send(ctx context.Context, s *spec, transport http.RoundTripper, breaker blaster.CircuitBreakerPrototype, rec *recorder) {
+	req, err := s.render()
+	if err != nil {
+		rec.observe(outcome{errorClass: errorTemplate})
+		return
+	}
+
+	var o outcome
+	hooks := &blaster.Hooks{
+		OnResponse: func(e blaster.Event) {
+			o.status = e.StatusCode
+		},
+		OnError: func(e blaster.Event) {
+			o.errorClass = e.ErrorClass
+		},
+	}
+	c, err := blaster.New(blaster.ClientOptions{
+		Endpoint:       req.url,
+		RouteMask:      s.RouteMask,
+		CalledService:  s.CalledService,
+		TimeoutMS:      s.TimeoutMS,
+		CircuitBreaker: breaker,
+		Hooks:          hooks,
+		Transport:      transport,
+	})
+	if err != nil {
+		rec.observe(outcome{errorClass: blaster.MetricErrorRequest})
+		return
+	}
+
+	contentType := setHeaders(c, req.headers)
+
+	start := time.Now()
+	c.Do(ctx, s.Method, payloadFor(req.body, contentType))
+	if o.errorClass != blaster.EventErrorBreaker {
+		o.latency = time.Since(start)
+	}
+	rec.observe(o)
+}
+
+// outcome is the outcome of a request.  Requests that were sent have a
+// latency, those that got a response have a status.
+type outcome struct {
+	status     int
+	errorClass string
+	latency    time.Duration
+}
+
+// recorder collects the outcomes of the requests
+type recorder struct {
+	mu        sync.Mutex
+	requests  int
+	latencies []time.Duration
+	statuses  map[string]int
+	errors    map[string]int
+}
+
+// newRecorder creates an empty recorder
+func newRecorder() *recorder {
+	return &recorder{
+		statuses: map[string]int{},
+		errors:   map[string]int{},
+	}
+}
+
+// observe records the outcome of a request
+func (r *recorder) observe(o outcome) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+	if o.latency > 0 {
+		r.latencies = append(r.latencies, o.latency)
+	}
+	if o.status != 0 {
+		r.statuses[strconv.Itoa(o.status)]++
+	}
+	if o.errorClass != "" {
+		r.errors[o.errorClass]++
+	}
+}
+
+// report summarizes the recorded requests
+func (r *recorder) report(elapsed time.Duration) *report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep := &report{
+		Requests: r.requests,
+		Seconds:  elapsed.Seconds(),
+		Statuses: r.statuses,
+		Errors:   r.errors,
+	}
+	if rep.Seconds > 0 {
+		rep.Throughput = float64(rep.Requests) / rep.Seconds
+	}
+
+	latencies := append([]time.Duration(nil), r.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	rep.Latency = summarize(latencies)
+
+	return rep
+}
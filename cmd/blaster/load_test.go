@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("load", func() {
+	var (
+		dir    string
+		server *httptest.Server
+		mu     sync.Mutex
+		seen   []*http.Request
+		bodies []string
+		status int
+	)
+
+	// writeFile writes a fixture to the temp dir
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	// mustLoad loads a spec fixture and applies its defaults
+	mustLoad := func(name, content string) *spec {
+		s, err := loadSpec(writeFile(name, content))
+		Expect(err).To(BeNil())
+		s.Defaults.apply()
+		return s
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "blaster")
+		seen, bodies = nil, nil
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			seen = append(seen, r)
+			bodies = append(bodies, string(body))
+			code := status
+			mu.Unlock()
+			w.WriteHeader(code)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+		blaster.SetDefaults(&blaster.Defaults{})
+	})
+
+	// region spec
+
+	It("renders requests from the data feed in turn", func() {
+		writeFile("users.csv", "id,sku\n1,a\n2,b\n")
+		s := mustLoad("spec.yaml", `
+method: post
+url: `+server.URL+`/v1/users/{{.id}}/orders
+headers:
+  X-Sku: "{{.sku}}"
+body: '{"sku":"{{.sku}}"}'
+data: users.csv
+`)
+
+		r := runLoad(context.Background(), s, loadOptions{concurrency: 1, duration: 5 * time.Second, requests: 3})
+		Expect(r.Requests).To(Equal(3))
+		Expect(seen).To(HaveLen(3))
+		Expect(seen[0].Method).To(Equal(http.MethodPost))
+		Expect(seen[0].URL.Path).To(Equal("/v1/users/1/orders"))
+		Expect(seen[1].URL.Path).To(Equal("/v1/users/2/orders"))
+		Expect(seen[2].URL.Path).To(Equal("/v1/users/1/orders"))
+		Expect(seen[1].Header.Get("X-Sku")).To(Equal("b"))
+		Expect(bodies[1]).To(Equal(`{"sku":"b"}`))
+	})
+
+	It("reads JSON specs and JSON lines feeds", func() {
+		writeFile("skus.jsonl", "{\"sku\":\"a\",\"qty\":3}\n\n{\"sku\":\"b\",\"qty\":1}\n")
+		s := mustLoad("spec.json", `{
+			"method": "PUT",
+			"url": "`+server.URL+`/v1/skus/{{.sku}}",
+			"headers": {"Content-Type": "text/plain"},
+			"body": "qty={{.qty}}",
+			"data": "skus.jsonl"
+		}`)
+
+		runLoad(context.Background(), s, loadOptions{concurrency: 1, duration: 5 * time.Second, requests: 2})
+		Expect(seen[0].URL.Path).To(Equal("/v1/skus/a"))
+		Expect(seen[0].Header.Get("Content-Type")).To(Equal("text/plain"))
+		Expect(bodies).To(Equal([]string{"qty=3", "qty=1"}))
+	})
+
+	It("sends bodies as their content type says, whatever the case of the header", func() {
+		s := mustLoad("spec.yaml", `
+method: post
+url: `+server.URL+`/v1/notes
+headers:
+  content-type: text/plain
+body: hello
+`)
+
+		runLoad(context.Background(), s, loadOptions{concurrency: 2, duration: 5 * time.Second, requests: 4})
+		Expect(bodies).To(Equal([]string{"hello", "hello", "hello", "hello"}))
+		for _, r := range seen {
+			Expect(r.Header["Content-Type"]).To(Equal([]string{"text/plain"}))
+		}
+	})
+
+	It("applies the library defaults of the spec", func() {
+		s := mustLoad("spec.yaml", `
+url: `+server.URL+`/health
+defaults:
+  service_name: load-test
+  user_agent: blaster-load
+`)
+
+		runLoad(context.Background(), s, loadOptions{concurrency: 1, duration: 5 * time.Second, requests: 1})
+		Expect(seen[0].Header.Get("User-Agent")).To(Equal("blaster-load"))
+		Expect(seen[0].Header.Get("Calling-Service")).To(Equal("load-test"))
+	})
+
+	It("rejects broken specs", func() {
+		_, err := loadSpec(writeFile("empty.yaml", "method: GET\n"))
+		Expect(err).To(MatchError(ContainSubstring("url is required")))
+		_, err = loadSpec(writeFile("bad.yaml", "url: '{{.id'\n"))
+		Expect(err).ToNot(BeNil())
+		_, err = loadSpec(writeFile("feed.yaml", "url: http://localhost\ndata: missing.csv\n"))
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("counts requests the spec cannot render", func() {
+		s := mustLoad("spec.yaml", "url: "+server.URL+"/v1/users/{{.id}}\n")
+
+		r := runLoad(context.Background(), s, loadOptions{concurrency: 1, duration: 5 * time.Second, requests: 2})
+		Expect(r.Errors).To(Equal(map[string]int{errorTemplate: 2}))
+		Expect(seen).To(BeEmpty())
+	})
+
+	// endregion
+
+	// region load
+
+	It("reports statuses, error classes and latencies", func() {
+		status = http.StatusServiceUnavailable
+		s := mustLoad("spec.yaml", "url: "+server.URL+"/v1/users\n")
+
+		r := runLoad(context.Background(), s, loadOptions{concurrency: 4, duration: 5 * time.Second, requests: 20})
+		Expect(r.Requests).To(Equal(20))
+		Expect(r.Statuses).To(Equal(map[string]int{"503": 20}))
+		Expect(r.Throughput).To(BeNumerically(">", 0))
+		Expect(r.Latency.Max).To(BeNumerically(">=", r.Latency.P99))
+		Expect(r.Latency.P99).To(BeNumerically(">=", r.Latency.P50))
+		Expect(r.Latency.P50).To(BeNumerically(">=", r.Latency.Min))
+		Expect(r.Latency.Min).To(BeNumerically(">", 0))
+	})
+
+	It("paces requests to the rate", func() {
+		s := mustLoad("spec.yaml", "url: "+server.URL+"/v1/users\n")
+
+		r := runLoad(context.Background(), s, loadOptions{rate: 50, concurrency: 4, duration: 300 * time.Millisecond})
+		Expect(r.Requests).To(BeNumerically("~", 15, 4))
+	})
+
+	It("stops sending once the breaker opens", func() {
+		server.Close()
+		s := mustLoad("spec.yaml", `
+url: `+server.URL+`/v1/users
+breaker:
+  consecutive_failures: 3
+`)
+
+		r := runLoad(context.Background(), s, loadOptions{concurrency: 1, duration: 5 * time.Second, requests: 10})
+		Expect(r.Requests).To(Equal(10))
+		Expect(r.Errors).To(Equal(map[string]int{
+			blaster.MetricErrorConnection: 3,
+			blaster.EventErrorBreaker:     7,
+		}))
+		Expect(r.Statuses).To(BeEmpty())
+	})
+
+	// endregion
+
+	// region command
+
+	It("writes the report as JSON", func() {
+		path := writeFile("spec.yaml", "url: "+server.URL+"/v1/users\n")
+		var stdout, stderr bytes.Buffer
+
+		code := run([]string{"load", "-spec", path, "-requests", "5", "-concurrency", "2", "-output", "json"}, &stdout, &stderr)
+		Expect(code).To(Equal(0), stderr.String())
+		var r report
+		Expect(json.Unmarshal(stdout.Bytes(), &r)).To(Succeed())
+		Expect(r.Requests).To(Equal(5))
+		Expect(r.Statuses).To(Equal(map[string]int{"200": 5}))
+	})
+
+	It("writes the report as text", func() {
+		path := writeFile("spec.yaml", "url: "+server.URL+"/v1/users\n")
+		var stdout, stderr bytes.Buffer
+
+		Expect(run([]string{"load", "-spec", path, "-requests", "3"}, &stdout, &stderr)).To(Equal(0))
+		Expect(stdout.String()).To(ContainSubstring("Requests      3 in "))
+		Expect(stdout.String()).To(ContainSubstring("Status codes  200: 3"))
+		Expect(stdout.String()).To(ContainSubstring("Errors        none"))
+	})
+
+	It("rejects bad usage", func() {
+		var stdout, stderr bytes.Buffer
+		Expect(run(nil, &stdout, &stderr)).To(Equal(2))
+		Expect(run([]string{"fire"}, &stdout, &stderr)).To(Equal(2))
+		Expect(run([]string{"load"}, &stdout, &stderr)).To(Equal(2))
+		Expect(run([]string{"load", "-spec", "x.yaml", "-output", "xml"}, &stdout, &stderr)).To(Equal(2))
+		Expect(run([]string{"load", "-spec", filepath.Join(dir, "missing.yaml")}, &stdout, &stderr)).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("usage: blaster"))
+	})
+
+	// endregion
+
+	Describe("percentile", func() {
+		It("takes the nearest rank", func() {
+			sorted := make([]time.Duration, 100)
+			for i := range sorted {
+				sorted[i] = time.Duration(i+1) * time.Millisecond
+			}
+			Expect(percentile(sorted, 50)).To(Equal(50 * time.Millisecond))
+			Expect(percentile(sorted, 99)).To(Equal(99 * time.Millisecond))
+			Expect(percentile(sorted[:1], 90)).To(Equal(time.Millisecond))
+		})
+	})
+})
@@ -0,0 +1,66 @@
+// Command blaster drives the blaster Client from the command line.
+//
+//	blaster load -spec orders.yaml -rate 200 -duration 30s
+//
+// sends the request described by the spec at 200 requests per second for
+// 30 seconds, and reports latency percentiles, status codes, error
+// classes and throughput.
+//
+//	blaster request -config users.yaml -curl https://users.example.com/v1/users/42
+//
+// sends one request with the service defaults of the config, and prints
+// the status, headers, timings and body of the response.
+//
+//	blaster pact -provider http://localhost:8080 pacts/order-service-user-service.json
+//
+// replays the interactions of consumer pacts against the provider, and
+// reports those it breaks.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// commands are the subcommands, by name
+var commands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"load":    loadCommand,
+	"pact":    pactCommand,
+	"request": requestCommand,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the subcommand named by the first argument, and returns the
+// exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "blaster: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	return command(args[1:], stdout, stderr)
+}
+
+// usage lists the subcommands
+func usage(w io.Writer) {
+	fmt.Fprint(w, `usage: blaster <command> [flags]
+
+commands:
+  load     send a request at a rate or concurrency for a duration, and report on it
+  pact     replay pact files against a running provider and report broken interactions
+  request  send one request and print the response, its timings and its curl equivalent
+
+run "blaster <command> -h" for the flags of a command
+`)
+}
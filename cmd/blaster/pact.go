@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/joelhill/go-rest-http-blaster"
+)
+
+// providerState is posted to the state url before an interaction that
+// needs a provider state
+type providerState struct {
+	Consumer string `json:"consumer"`
+	State    string `json:"state"`
+}
+
+// pactCommand runs `blaster pact`
+func pactCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pact", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: blaster pact -provider <url> [flags] <pact file>...")
+		flags.PrintDefaults()
+	}
+	provider := flags.String("provider", "", "base url of the running provider (required)")
+	stateURL := flags.String("state-url", "", `url the provider states are posted to, as {"consumer":..., "state":...}`)
+	headers := headerFlags{}
+	flags.Var(headers, "H", `header added to every request as "Name: value", repeatable`)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *provider == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	ctx := context.Background()
+	code := 0
+	for _, path := range flags.Args() {
+		pact, err := blaster.ReadPact(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = 1
+			continue
+		}
+
+		verifier := &blaster.PactVerifier{ProviderURL: *provider, Headers: headers}
+		if *stateURL != "" {
+			verifier.StateHandler = postState(*stateURL, pact.Consumer.Name)
+		}
+
+		fmt.Fprintf(stdout, "%s -> %s\n", pact.Consumer.Name, pact.Provider.Name)
+		results, err := verifier.Verify(ctx, pact)
+		for _, result := range results {
+			status := "ok  "
+			if !result.OK() {
+				status = "FAIL"
+			}
+			fmt.Fprintf(stdout, "  %s %s", status, result.Description)
+			if result.ProviderState != "" {
+				fmt.Fprintf(stdout, " (given %s)", result.ProviderState)
+			}
+			fmt.Fprintln(stdout)
+			for _, mismatch := range result.Mismatches {
+				fmt.Fprintf(stdout, "         %s\n", mismatch)
+			}
+		}
+		if err != nil {
+			code = 1
+		}
+	}
+
+	return code
+}
+
+// postState returns a state handler that posts the provider states to
+// the url
+func postState(stateURL, consumer string) func(ctx context.Context, state string) error {
+	return func(ctx context.Context, state string) error {
+		c, err := blaster.New(blaster.ClientOptions{Endpoint: stateURL})
+		if err != nil {
+			return err
+		}
+
+		statusCode, err := c.Post(ctx, providerState{Consumer: consumer, State: state})
+		if err != nil {
+			return err
+		}
+		if statusCode >= http.StatusBadRequest {
+			return fmt.Errorf("the state url answered %d", statusCode)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pact", func() {
+	var (
+		dir            string
+		provider       *httptest.Server
+		states         []providerState
+		tenants        []string
+		name           string
+		pactPath       string
+		stdout, stderr bytes.Buffer
+	)
+
+	// verify runs `blaster pact` with the args
+	verify := func(args ...string) int {
+		return run(append([]string{"pact"}, args...), &stdout, &stderr)
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "blaster")
+		stdout.Reset()
+		stderr.Reset()
+		states, tenants, name = nil, nil, "joel"
+		provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/_states" {
+				var state providerState
+				json.NewDecoder(r.Body).Decode(&state)
+				states = append(states, state)
+				return
+			}
+			tenants = append(tenants, r.Header.Get("X-Tenant"))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"id": "42", "name": name})
+		}))
+
+		pact := blaster.NewPact("order-service", "user-service")
+		pact.AddInteraction(blaster.PactInteraction{
+			Description:   "a request for user 42",
+			ProviderState: "user 42 exists",
+			Request:       blaster.PactRequest{Method: http.MethodGet, Path: "/v1/users/42"},
+			Response: blaster.PactResponse{
+				Status:  http.StatusOK,
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    json.RawMessage(`{"name":"joel"}`),
+			},
+		})
+		pactPath, _ = pact.WriteFile(dir)
+	})
+
+	AfterEach(func() {
+		provider.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("verifies the pact files against the provider", func() {
+		code := verify("-provider", provider.URL, "-state-url", provider.URL+"/_states", "-H", "X-Tenant: acme", pactPath)
+		Expect(code).To(Equal(0), stderr.String())
+		Expect(stdout.String()).To(Equal("order-service -> user-service\n  ok   a request for user 42 (given user 42 exists)\n"))
+		Expect(states).To(Equal([]providerState{{Consumer: "order-service", State: "user 42 exists"}}))
+		Expect(tenants).To(Equal([]string{"acme"}))
+	})
+
+	It("fails with the mismatches when the provider breaks the pact", func() {
+		name = "joe"
+		Expect(verify("-provider", provider.URL, pactPath)).To(Equal(1))
+		Expect(stdout.String()).To(ContainSubstring("  FAIL a request for user 42 (given user 42 exists)\n"))
+		Expect(stdout.String()).To(ContainSubstring(`$.body.name: expected "joel", got "joe"`))
+	})
+
+	It("fails on unreadable pact files and requires a provider", func() {
+		Expect(verify("-provider", provider.URL, dir+"/missing.json")).To(Equal(1))
+		Expect(stderr.String()).ToNot(BeEmpty())
+		Expect(verify(pactPath)).To(Equal(2))
+	})
+})
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// report summarizes a load
+type report struct {
+	// Requests is the number of requests made
+	Requests int `json:"requests"`
+
+	// Seconds is how long the load lasted
+	Seconds float64 `json:"seconds"`
+
+	// Throughput is the number of requests made per second
+	Throughput float64 `json:"throughput"`
+
+	// Latency summarizes the latencies of the requests that were sent
+	Latency latency `json:"latency_ms"`
+
+	// Statuses counts the responses by status code
+	Statuses map[string]int `json:"statuses"`
+
+	// Errors counts the failed requests by error class
+	Errors map[string]int `json:"errors"`
+}
+
+// latency summarizes latencies, in milliseconds
+type latency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// summarize summarizes sorted latencies
+func summarize(sorted []time.Duration) latency {
+	if len(sorted) == 0 {
+		return latency{}
+	}
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	return latency{
+		Min:  milliseconds(sorted[0]),
+		Mean: milliseconds(total / time.Duration(len(sorted))),
+		P50:  milliseconds(percentile(sorted, 50)),
+		P90:  milliseconds(percentile(sorted, 90)),
+		P95:  milliseconds(percentile(sorted, 95)),
+		P99:  milliseconds(percentile(sorted, 99)),
+		Max:  milliseconds(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile is the nearest rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// milliseconds converts a duration to milliseconds, to the microsecond
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
+
+// writeText writes the report for people to read
+func (r *report) writeText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Requests\t%d in %.2fs, %.1f/s\n", r.Requests, r.Seconds, r.Throughput)
+	l := r.Latency
+	fmt.Fprintf(tw, "Latency (ms)\tmin %.2f  mean %.2f  p50 %.2f  p90 %.2f  p95 %.2f  p99 %.2f  max %.2f\n",
+		l.Min, l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+	fmt.Fprintf(tw, "Status codes\t%s\n", counts(r.Statuses))
+	fmt.Fprintf(tw, "Errors\t%s\n", counts(r.Errors))
+
+	return tw.Flush()
+}
+
+// counts lists counts by key, e.g. "200: 98  503: 2"
+func counts(m map[string]int) string {
+	if len(m) == 0 {
+		return "none"
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s: %d", k, m[k])
+	}
+
+	return strings.Join(parts, "  ")
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/joelhill/go-rest-http-blaster"
+)
+
+// traceparentHeader carries the W3C trace context
+const traceparentHeader = "traceparent"
+
+// requestConfig holds the settings of `blaster request` for a service,
+// e.g.
+//
+//	called_service: user-service
+//	headers:
+//	  X-Tenant: acme
+//	timeout_ms: 5000
+//	redaction:
+//	  headers: [X-Api-Key]
+//	  fields: [password]
+//	defaults:
+//	  service_name: order-service
+//	  request_source: order-service
+//	  require_headers: true
+type requestConfig struct {
+	CalledService string            `json:"called_service" yaml:"called_service"`
+	RouteMask     string            `json:"route_mask" yaml:"route_mask"`
+	Headers       map[string]string `json:"headers" yaml:"headers"`
+	TimeoutMS     int               `json:"timeout_ms" yaml:"timeout_ms"`
+
+	// Redaction applies to the curl equivalent
+	Redaction blaster.Redaction `json:"redaction" yaml:"redaction"`
+
+	// Defaults are applied to the library with blaster.SetDefaults
+	Defaults defaultsConfig `json:"defaults" yaml:"defaults"`
+}
+
+// headerFlags collects repeated -H "Name: value" flags
+type headerFlags map[string]string
+
+// String implements flag.Value
+func (h headerFlags) String() string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// Set implements flag.Value
+func (h headerFlags) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("header %q is not Name: value", value)
+	}
+	h[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+
+	return nil
+}
+
+// requestCommand runs `blaster request`
+func requestCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("request", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: blaster request [flags] <url>")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", "", "service config file, YAML or JSON")
+	method := flags.String("X", http.MethodGet, "http method")
+	headers := headerFlags{}
+	flags.Var(headers, "H", `header as "Name: value", repeatable`)
+	body := flags.String("d", "", "request body, or @file to read it from a file")
+	routeMask := flags.String("route-mask", "", "templated route of the request, e.g. /v1/users/{id}")
+	calledService := flags.String("called-service", "", "service the request is sent to")
+	timeoutMS := flags.Int("timeout-ms", 0, "request timeout in milliseconds")
+	serviceName := flags.String("service-name", "", "calling service name")
+	userAgent := flags.String("user-agent", "", "user agent")
+	requestSource := flags.String("request-source", "", "Request-Source header")
+	requireHeaders := flags.Bool("require-headers", false, "fail if Request-ID, Request-Source or Calling-Service are missing")
+	curl := flags.Bool("curl", false, "print the curl equivalent of the request")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	config := &requestConfig{}
+	if *configPath != "" {
+		if err := readConfig(*configPath, config); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	// flags that were given override the config
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "route-mask":
+			config.RouteMask = *routeMask
+		case "called-service":
+			config.CalledService = *calledService
+		case "timeout-ms":
+			config.TimeoutMS = *timeoutMS
+		case "service-name":
+			config.Defaults.ServiceName = *serviceName
+		case "user-agent":
+			config.Defaults.UserAgent = *userAgent
+		case "request-source":
+			config.Defaults.RequestSource = *requestSource
+		case "require-headers":
+			config.Defaults.RequireHeaders = *requireHeaders
+		}
+	})
+	if config.Headers == nil {
+		config.Headers = map[string]string{}
+	}
+	for name, value := range headers {
+		config.Headers[name] = value
+	}
+
+	payload := []byte(*body)
+	if strings.HasPrefix(*body, "@") {
+		var err error
+		if payload, err = ioutil.ReadFile((*body)[1:]); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	config.Defaults.apply()
+
+	return sendRequest(context.Background(), config, strings.ToUpper(*method), flags.Arg(0), payload, *curl, stdout, stderr)
+}
+
+// sendRequest sends one request and prints the exchange.  It fails only
+// if no response was received.
+func sendRequest(ctx context.Context, config *requestConfig, method, endpoint string, body []byte, curl bool, stdout, stderr io.Writer) int {
+	c, err := blaster.New(blaster.ClientOptions{
+		Endpoint:       endpoint,
+		RouteMask:      config.RouteMask,
+		CalledService:  config.CalledService,
+		TimeoutMS:      config.TimeoutMS,
+		RequestLogging: &blaster.RequestLogging{Redaction: config.Redaction},
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	contentType := setHeaders(c, config.Headers)
+
+	// trace the request, unless it continues a trace
+	var traceID string
+	if _, ok := headerValue(config.Headers, traceparentHeader); !ok {
+		traceID = randomHex(16)
+		c.SetHeader(traceparentHeader, fmt.Sprintf("00-%s-%s-01", traceID, randomHex(8)))
+	}
+
+	_, err = c.Do(ctx, method, payloadFor(body, contentType))
+
+	if curl && c.Request() != nil {
+		fmt.Fprintf(stdout, "%s\n\n", c.Request().AsCurl())
+	}
+	response := c.Response()
+	if response == nil || response.StatusCode == 0 {
+		if err == nil {
+			err = fmt.Errorf("no response received")
+		}
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	writeExchange(stdout, c.Request(), response, traceID)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+	}
+
+	return 0
+}
+
+// writeExchange prints the status, headers, timings and pretty printed
+// body of the response
+func writeExchange(w io.Writer, request *blaster.Request, response *blaster.Response, traceID string) {
+	fmt.Fprintf(w, "%d %s\n", response.StatusCode, http.StatusText(response.StatusCode))
+
+	names := make([]string, 0, len(response.Header))
+	for name := range response.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range response.Header[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+
+	t := response.Timings
+	fmt.Fprintf(w, "\nDNS %.2fms  Connect %.2fms  TLS %.2fms  TTFB %.2fms  Total %.2fms",
+		milliseconds(t.DNS), milliseconds(t.Connect), milliseconds(t.TLS),
+		milliseconds(t.TimeToFirstByte), milliseconds(response.Duration))
+	if t.ConnReused {
+		fmt.Fprint(w, "  (connection reused)")
+	}
+	fmt.Fprintln(w)
+	if request != nil {
+		fmt.Fprintf(w, "Request-ID %s", request.Header.Get("Request-ID"))
+		if traceID != "" {
+			fmt.Fprintf(w, "  Trace ID %s", traceID)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(response.Body) > 0 {
+		fmt.Fprintf(w, "\n%s\n", pretty(response.Body))
+	}
+}
+
+// pretty indents a JSON body, and leaves any other body alone
+func pretty(body []byte) []byte {
+	var b bytes.Buffer
+	if json.Indent(&b, body, "", "  ") != nil {
+		return bytes.TrimRight(body, "\n")
+	}
+
+	return b.Bytes()
+}
+
+// headerValue finds a header whatever its case
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// randomHex returns n random bytes in hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return fmt.Sprintf("%x", b)
+}
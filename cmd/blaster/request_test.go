@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("request", func() {
+	var (
+		dir            string
+		server         *httptest.Server
+		received       *http.Request
+		body           string
+		stdout, stderr bytes.Buffer
+	)
+
+	// writeFile writes a fixture to the temp dir
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	// request runs `blaster request` with the args
+	request := func(args ...string) int {
+		return run(append([]string{"request"}, args...), &stdout, &stderr)
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "blaster")
+		stdout.Reset()
+		stderr.Reset()
+		received, body = nil, ""
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := ioutil.ReadAll(r.Body)
+			received, body = r, string(data)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Served-By", "test")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"42","tags":["a"]}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+		blaster.SetDefaults(&blaster.Defaults{})
+	})
+
+	It("prints the status, headers, timings and pretty printed body", func() {
+		Expect(request(server.URL+"/v1/users/42")).To(Equal(0), stderr.String())
+
+		out := stdout.String()
+		Expect(out).To(HavePrefix("201 Created\n"))
+		Expect(out).To(ContainSubstring("\nX-Served-By: test\n"))
+		Expect(out).To(MatchRegexp(`DNS [0-9.]+ms  Connect [0-9.]+ms  TLS [0-9.]+ms  TTFB [0-9.]+ms  Total [0-9.]+ms`))
+		Expect(out).To(HaveSuffix("{\n  \"id\": \"42\",\n  \"tags\": [\n    \"a\"\n  ]\n}\n"))
+		Expect(received.Method).To(Equal(http.MethodGet))
+	})
+
+	It("sends the REQ014 headers and a trace context", func() {
+		Expect(request("-service-name", "order-service", "-request-source", "orders-cli", "-require-headers",
+			server.URL+"/v1/users/42")).To(Equal(0), stderr.String())
+
+		Expect(received.Header.Get("Calling-Service")).To(Equal("order-service"))
+		Expect(received.Header.Get("Request-Source")).To(Equal("orders-cli"))
+		requestID := received.Header.Get("Request-ID")
+		Expect(requestID).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		traceparent := received.Header.Get("traceparent")
+		Expect(traceparent).To(MatchRegexp(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`))
+		Expect(stdout.String()).To(ContainSubstring("Request-ID " + requestID + "  Trace ID " + traceparent[3:35]))
+	})
+
+	It("builds the client from a config file, with flags taking precedence", func() {
+		config := writeFile("users.yaml", `
+called_service: user-service
+headers:
+  X-Tenant: acme
+  X-Api-Key: secret
+redaction:
+  headers: [X-Api-Key]
+defaults:
+  service_name: order-service
+  user_agent: from-config
+`)
+		Expect(request("-config", config, "-user-agent", "from-flag", "-X", "post", "-H", "X-Tenant: globex",
+			"-d", `{"name":"joel"}`, "-curl", server.URL+"/v1/users")).To(Equal(0), stderr.String())
+
+		Expect(received.Method).To(Equal(http.MethodPost))
+		Expect(received.Header.Get("User-Agent")).To(Equal("from-flag"))
+		Expect(received.Header.Get("Calling-Service")).To(Equal("order-service"))
+		Expect(received.Header.Get("X-Tenant")).To(Equal("globex"))
+		Expect(received.Header.Get("X-Api-Key")).To(Equal("secret"))
+		Expect(body).To(Equal(`{"name":"joel"}`))
+
+		curl := strings.SplitN(stdout.String(), "\n", 2)[0]
+		Expect(curl).To(HavePrefix("curl -X POST '" + server.URL + "/v1/users'"))
+		Expect(curl).To(ContainSubstring(`-H 'X-Tenant: globex'`))
+		Expect(curl).ToNot(ContainSubstring("secret"))
+		Expect(curl).To(HaveSuffix(`--data-raw '{"name":"joel"}'`))
+	})
+
+	It("reads the body from a file and sends it as its content type says", func() {
+		path := writeFile("body.txt", "plain text")
+		Expect(request("-X", "PUT", "-H", "Content-Type: text/plain", "-d", "@"+path, server.URL)).To(Equal(0), stderr.String())
+
+		Expect(body).To(Equal("plain text"))
+		Expect(received.Header.Get("Content-Type")).To(Equal("text/plain"))
+	})
+
+	It("sends JSON bodies whose content type carries parameters as they are", func() {
+		for _, contentType := range []string{"application/json; charset=utf-8", "application/vnd.api+json"} {
+			Expect(request("-X", "POST", "-H", "content-type: "+contentType, "-d", `{"a":1}`, server.URL)).To(Equal(0), stderr.String())
+			Expect(body).To(Equal(`{"a":1}`))
+			Expect(received.Header["Content-Type"]).To(Equal([]string{contentType}))
+		}
+	})
+
+	It("fails when no response is received", func() {
+		server.Close()
+		Expect(request(server.URL)).To(Equal(1))
+		Expect(stderr.String()).ToNot(BeEmpty())
+		Expect(stdout.String()).To(BeEmpty())
+	})
+
+	It("rejects bad usage", func() {
+		Expect(request()).To(Equal(2))
+		Expect(request("-H", "no colon", server.URL)).To(Equal(2))
+		Expect(request("-config", filepath.Join(dir, "missing.yaml"), server.URL)).To(Equal(1))
+		Expect(request("-d", "@"+filepath.Join(dir, "missing.json"), server.URL)).To(Equal(1))
+		Expect(stderr.String()).To(ContainSubstring("usage: blaster request"))
+	})
+})
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster"
+	"github.com/sony/gobreaker"
+)
+
+// spec describes the request to blast, e.g.
+//
+//	method: POST
+//	url: http://localhost:8080/v1/users/{{.id}}/orders
+//	route_mask: /v1/users/{id}/orders
+//	headers:
+//	  X-Tenant: "{{.tenant}}"
+//	body: '{"sku":"{{.sku}}","quantity":1}'
+//	data: users.csv
+//	timeout_ms: 2000
+//	breaker:
+//	  consecutive_failures: 20
+//	  open_ms: 5000
+//
+// The url, header values and body are text/templates given a row of the
+// data feed.
+type spec struct {
+	Method        string            `json:"method" yaml:"method"`
+	URL           string            `json:"url" yaml:"url"`
+	RouteMask     string            `json:"route_mask" yaml:"route_mask"`
+	CalledService string            `json:"called_service" yaml:"called_service"`
+	Headers       map[string]string `json:"headers" yaml:"headers"`
+	Body          string            `json:"body" yaml:"body"`
+
+	// Data is a CSV file with a header row, or a JSON lines file of
+	// objects, relative to the spec.  Rows are used in turn, starting
+	// over at the end.
+	Data string `json:"data" yaml:"data"`
+
+	// TimeoutMS is the timeout of each request
+	TimeoutMS int `json:"timeout_ms" yaml:"timeout_ms"`
+
+	// Breaker wraps the requests in a circuit breaker
+	Breaker *breakerSpec `json:"breaker" yaml:"breaker"`
+
+	// Defaults are applied to the library with blaster.SetDefaults
+	Defaults defaultsConfig `json:"defaults" yaml:"defaults"`
+
+	url     *template.Template
+	headers map[string]*template.Template
+	body    *template.Template
+	rows    []map[string]string
+	next    uint64
+}
+
+// breakerSpec configures a sony/gobreaker circuit breaker
+type breakerSpec struct {
+	// ConsecutiveFailures trips the breaker, 5 if 0
+	ConsecutiveFailures uint32 `json:"consecutive_failures" yaml:"consecutive_failures"`
+
+	// OpenMS is how long the breaker stays open, 60 seconds if 0
+	OpenMS int `json:"open_ms" yaml:"open_ms"`
+
+	// HalfOpenRequests are let through while half open, 1 if 0
+	HalfOpenRequests uint32 `json:"half_open_requests" yaml:"half_open_requests"`
+}
+
+// preparedRequest is a request rendered from the spec
+type preparedRequest struct {
+	url     string
+	headers map[string]string
+	body    []byte
+}
+
+// loadSpec reads the spec file, and the data feed it names
+func loadSpec(path string) (*spec, error) {
+	s := &spec{}
+	if err := readConfig(path, s); err != nil {
+		return nil, err
+	}
+
+	if err := s.prepare(); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if s.Data != "" {
+		feed := s.Data
+		if !filepath.IsAbs(feed) {
+			feed = filepath.Join(filepath.Dir(path), feed)
+		}
+		var err error
+		if s.rows, err = loadRows(feed); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// prepare checks the spec and parses its templates
+func (s *spec) prepare() error {
+	if s.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if s.Method == "" {
+		s.Method = http.MethodGet
+	}
+	s.Method = strings.ToUpper(s.Method)
+
+	var err error
+	if s.url, err = template.New("url").Option("missingkey=error").Parse(s.URL); err != nil {
+		return err
+	}
+	if s.body, err = template.New("body").Option("missingkey=error").Parse(s.Body); err != nil {
+		return err
+	}
+	s.headers = make(map[string]*template.Template, len(s.Headers))
+	for name, value := range s.Headers {
+		if s.headers[name], err = template.New(name).Option("missingkey=error").Parse(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// render renders the next request, using the next row of the data feed
+func (s *spec) render() (*preparedRequest, error) {
+	var row map[string]string
+	if len(s.rows) > 0 {
+		n := atomic.AddUint64(&s.next, 1) - 1
+		row = s.rows[n%uint64(len(s.rows))]
+	}
+
+	url, err := execute(s.url, row)
+	if err != nil {
+		return nil, err
+	}
+	body, err := execute(s.body, row)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(s.headers))
+	for name, tmpl := range s.headers {
+		if headers[name], err = execute(tmpl, row); err != nil {
+			return nil, err
+		}
+	}
+
+	return &preparedRequest{url: url, headers: headers, body: []byte(body)}, nil
+}
+
+// execute renders a template with a row
+func execute(tmpl *template.Template, row map[string]string) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, row); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// newBreaker creates the circuit breaker of the spec, if any
+func (s *spec) newBreaker() blaster.CircuitBreakerPrototype {
+	if s.Breaker == nil {
+		return nil
+	}
+
+	failures := s.Breaker.ConsecutiveFailures
+	if failures == 0 {
+		failures = 5
+	}
+
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "blaster",
+		MaxRequests: s.Breaker.HalfOpenRequests,
+		Timeout:     time.Duration(s.Breaker.OpenMS) * time.Millisecond,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= failures
+		},
+	})
+}
+
+// loadRows reads a CSV or JSON lines data feed
+func loadRows(path string) ([]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		records, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		if len(records) < 2 {
+			return nil, fmt.Errorf("%s: no rows after the header", path)
+		}
+		rows := make([]map[string]string, 0, len(records)-1)
+		for _, record := range records[1:] {
+			row := make(map[string]string, len(record))
+			for i, name := range records[0] {
+				row[name] = record[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	var rows []map[string]string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &object); err != nil {
+			return nil, fmt.Errorf("%s line %d: %s", path, line, err)
+		}
+		row := make(map[string]string, len(object))
+		for k, v := range object {
+			row[k] = fmt.Sprint(v)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%s: no rows", path)
+	}
+
+	return rows, nil
+}
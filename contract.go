@@ -0,0 +1,520 @@
+package blaster
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v2"
+)
+
+// contractResourceURL names the loaded document within its compiler
+const contractResourceURL = "file:///contract.json"
+
+// directions of a contract violation
+const (
+	contractRequest  = "request"
+	contractResponse = "response"
+)
+
+// openAPIMethods are the operations of an OpenAPI path item
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// ContractMode tells what happens to bodies that break the contract
+type ContractMode int
+
+const (
+	// ContractOff skips validation
+	ContractOff ContractMode = iota
+	// ContractWarn logs and counts violations, and lets the request go on
+	ContractWarn
+	// ContractEnforce fails the request with a *ContractError
+	ContractEnforce
+)
+
+// ContractValidation validates request and response bodies against a
+// Contract.  Operations are looked up by the route mask and method of the
+// request; requests to operations the contract does not describe are not
+// validated.
+type ContractValidation struct {
+	// Contract describes the bodies of the called service
+	Contract *Contract
+
+	// Mode defaults to ContractOff
+	Mode ContractMode
+
+	// Requests also validates the request bodies
+	Requests bool
+}
+
+// ContractError is the error of a request or response body that breaks
+// the contract, in ContractEnforce mode
+type ContractError struct {
+	// Direction is "request" or "response"
+	Direction string
+
+	Method    string
+	RouteMask string
+
+	// StatusCode is the status code of the response, or 0 for requests
+	StatusCode int
+
+	// Violations describe what broke the contract, e.g.
+	// "/id: expected string, but got number"
+	Violations []string
+}
+
+// Error implements error
+func (e *ContractError) Error() string {
+	operation := fmt.Sprintf("%s %s", e.Method, e.RouteMask)
+	if e.StatusCode != 0 {
+		operation = fmt.Sprintf("%s (%d)", operation, e.StatusCode)
+	}
+
+	return fmt.Sprintf("%s of %s breaks the contract: %s", e.Direction, operation, strings.Join(e.Violations, "; "))
+}
+
+// Contract holds the compiled schemas of the bodies of a called service
+type Contract struct {
+	operations map[string]*contractOperation
+	any        *contractOperation
+}
+
+// contractOperation holds the schemas of one operation
+type contractOperation struct {
+	request *jsonschema.Schema
+
+	// responses are keyed by status code, status class (2XX) or default
+	responses map[string]*jsonschema.Schema
+}
+
+// NewSchemaContract loads JSON Schemas for the bodies of every operation
+// from the files at the paths, either of which may be empty.  The
+// response schema applies to 2xx responses.  Files ending in .yaml or
+// .yml are read as YAML, anything else as JSON.
+func NewSchemaContract(responseSchemaPath, requestSchemaPath string) (*Contract, error) {
+	op := &contractOperation{responses: map[string]*jsonschema.Schema{}}
+
+	if responseSchemaPath != "" {
+		schema, err := compileSchemaFile(responseSchemaPath)
+		if err != nil {
+			return nil, err
+		}
+		op.responses["2XX"] = schema
+	}
+	if requestSchemaPath != "" {
+		schema, err := compileSchemaFile(requestSchemaPath)
+		if err != nil {
+			return nil, err
+		}
+		op.request = schema
+	}
+
+	return &Contract{any: op}, nil
+}
+
+// NewOpenAPIContract loads an OpenAPI 3 document from the file at path.
+// Paths are matched against the route mask as written, e.g.
+// /v1/users/{id}, and only JSON bodies are validated.  Files ending in
+// .yaml or .yml are read as YAML, anything else as JSON.
+func NewOpenAPIContract(path string) (*Contract, error) {
+	doc, err := loadContractDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not an OpenAPI document", path)
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("%s: unsupported OpenAPI version %q", path, version)
+	}
+
+	// 3.0 schemas are a draft 4 dialect with nullable, 3.1 schemas are
+	// plain JSON Schema 2020-12
+	compiler := jsonschema.NewCompiler()
+	if strings.HasPrefix(version, "3.0") {
+		compiler.Draft = jsonschema.Draft4
+		doc = rewriteNullable(doc)
+		root = doc.(map[string]interface{})
+	}
+	if err := addContractResource(compiler, doc); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	contract := &Contract{operations: map[string]*contractOperation{}}
+	paths, _ := root["paths"].(map[string]interface{})
+	for route, item := range paths {
+		item, _ := item.(map[string]interface{})
+		for _, method := range openAPIMethods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			pointer := []string{"paths", route, method}
+			op, err := compileOperation(compiler, root, operation, pointer)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s %s: %s", path, strings.ToUpper(method), route, err)
+			}
+			contract.operations[operationKey(strings.ToUpper(method), route)] = op
+		}
+	}
+
+	return contract, nil
+}
+
+// operation returns the schemas for the route and method, if any
+func (c *Contract) operation(method, routeMask string) *contractOperation {
+	if c.any != nil {
+		return c.any
+	}
+
+	return c.operations[operationKey(method, routeMask)]
+}
+
+// operationKey identifies an operation
+func operationKey(method, routeMask string) string {
+	return fmt.Sprintf("%s %s", method, routeMask)
+}
+
+// response returns the schema for the status code, if any
+func (o *contractOperation) response(statusCode int) *jsonschema.Schema {
+	for _, key := range []string{strconv.Itoa(statusCode), fmt.Sprintf("%dXX", statusCode/100), "default"} {
+		if schema, ok := o.responses[key]; ok {
+			return schema
+		}
+	}
+
+	return nil
+}
+
+// compileOperation compiles the request and response schemas of an
+// OpenAPI operation found at the pointer
+func compileOperation(compiler *jsonschema.Compiler, root, operation map[string]interface{}, pointer []string) (*contractOperation, error) {
+	op := &contractOperation{responses: map[string]*jsonschema.Schema{}}
+
+	if body, bodyPointer := resolveLocalRef(root, operation["requestBody"], append(pointer, "requestBody")); body != nil {
+		if schemaPointer := jsonContentSchema(body, bodyPointer); schemaPointer != nil {
+			schema, err := compiler.Compile(contractPointerURL(schemaPointer))
+			if err != nil {
+				return nil, err
+			}
+			op.request = schema
+		}
+	}
+
+	responses, _ := operation["responses"].(map[string]interface{})
+	for code, response := range responses {
+		response, responsePointer := resolveLocalRef(root, response, append(pointer, "responses", code))
+		if response == nil {
+			continue
+		}
+		schemaPointer := jsonContentSchema(response, responsePointer)
+		if schemaPointer == nil {
+			continue
+		}
+		schema, err := compiler.Compile(contractPointerURL(schemaPointer))
+		if err != nil {
+			return nil, err
+		}
+		op.responses[strings.Replace(code, "xx", "XX", 1)] = schema
+	}
+
+	return op, nil
+}
+
+// resolveLocalRef follows a local $ref of a request body or response
+// object, and returns the object with its pointer
+func resolveLocalRef(root map[string]interface{}, v interface{}, pointer []string) (map[string]interface{}, []string) {
+	for i := 0; i < 10; i++ {
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object, pointer
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil, nil
+		}
+
+		pointer = nil
+		v = interface{}(root)
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			pointer = append(pointer, token)
+			parent, _ := v.(map[string]interface{})
+			v = parent[token]
+		}
+	}
+
+	return nil, nil
+}
+
+// jsonContentSchema returns the pointer to the schema of the JSON content
+// of a request body or response object, if any
+func jsonContentSchema(object map[string]interface{}, pointer []string) []string {
+	content, _ := object["content"].(map[string]interface{})
+
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+
+	for _, mediaType := range types {
+		if !strings.Contains(strings.ToLower(mediaType), "json") {
+			continue
+		}
+		media, _ := content[mediaType].(map[string]interface{})
+		if _, ok := media["schema"]; ok {
+			return append(append(append([]string(nil), pointer...), "content", mediaType), "schema")
+		}
+	}
+
+	return nil
+}
+
+// contractPointerURL returns the url of the JSON pointer within the document
+func contractPointerURL(pointer []string) string {
+	tokens := make([]string, len(pointer))
+	for i, token := range pointer {
+		token = strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+		tokens[i] = url.PathEscape(token)
+	}
+
+	return fmt.Sprintf("%s#/%s", contractResourceURL, strings.Join(tokens, "/"))
+}
+
+// compileSchemaFile compiles the JSON Schema in the file
+func compileSchemaFile(path string) (*jsonschema.Schema, error) {
+	doc, err := loadContractDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := addContractResource(compiler, doc); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	schema, err := compiler.Compile(contractResourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return schema, nil
+}
+
+// addContractResource adds the document to the compiler
+func addContractResource(compiler *jsonschema.Compiler, doc interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return compiler.AddResource(contractResourceURL, bytes.NewReader(data))
+}
+
+// loadContractDocument reads a JSON or YAML document
+func loadContractDocument(path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+		doc = jsonCompatible(doc)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return doc, nil
+}
+
+// jsonCompatible turns the maps decoded from YAML into JSON objects
+func jsonCompatible(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(t))
+		for k, value := range t {
+			object[fmt.Sprint(k)] = jsonCompatible(value)
+		}
+		return object
+	case map[string]interface{}:
+		for k, value := range t {
+			t[k] = jsonCompatible(value)
+		}
+		return t
+	case []interface{}:
+		for i, value := range t {
+			t[i] = jsonCompatible(value)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// rewriteNullable turns the OpenAPI 3.0 nullable keyword into a null type
+func rewriteNullable(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, value := range t {
+			t[k] = rewriteNullable(value)
+		}
+		if nullable, _ := t["nullable"].(bool); nullable {
+			if typ, ok := t["type"].(string); ok {
+				t["type"] = []interface{}{typ, "null"}
+			}
+		}
+		return t
+	case []interface{}:
+		for i, value := range t {
+			t[i] = rewriteNullable(value)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// contractViolations validates the body against the schema
+func contractViolations(schema *jsonschema.Schema, body []byte) []string {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return []string{"body is empty"}
+	}
+
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return []string{fmt.Sprintf("body is not valid JSON: %s", err)}
+	}
+
+	err := schema.Validate(doc)
+	if err == nil {
+		return nil
+	}
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return []string{err.Error()}
+	}
+
+	var violations []string
+	var collect func(*jsonschema.ValidationError)
+	collect = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			location := e.InstanceLocation
+			if location == "" {
+				location = "(root)"
+			}
+			violations = append(violations, fmt.Sprintf("%s: %s", location, e.Message))
+			return
+		}
+		for _, cause := range e.Causes {
+			collect(cause)
+		}
+	}
+	collect(validationErr)
+
+	return violations
+}
+
+// checkRequestContract validates the request body, if asked to
+func (c *Client) checkRequestContract(body []byte) error {
+	v := c.contractValidation
+	if v == nil || v.Mode == ContractOff || !v.Requests || v.Contract == nil {
+		return nil
+	}
+
+	op := v.Contract.operation(c.method, c.routeMask)
+	if op == nil || op.request == nil {
+		return nil
+	}
+
+	return c.contractOutcome(contractRequest, 0, contractViolations(op.request, body))
+}
+
+// checkResponseContract validates the response body
+func (c *Client) checkResponseContract(statusCode int, body []byte) error {
+	v := c.contractValidation
+	if v == nil || v.Mode == ContractOff || v.Contract == nil {
+		return nil
+	}
+
+	op := v.Contract.operation(c.method, c.routeMask)
+	if op == nil {
+		return nil
+	}
+	schema := op.response(statusCode)
+	if schema == nil {
+		return nil
+	}
+
+	return c.contractOutcome(contractResponse, statusCode, contractViolations(schema, body))
+}
+
+// contractOutcome reports the violations, and returns the error to fail
+// the request with in ContractEnforce mode
+func (c *Client) contractOutcome(direction string, statusCode int, violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	c.reportContractViolation(direction)
+
+	err := &ContractError{
+		Direction:  direction,
+		Method:     c.method,
+		RouteMask:  c.routeMask,
+		StatusCode: statusCode,
+		Violations: violations,
+	}
+	if c.contractValidation.Mode == ContractEnforce {
+		return err
+	}
+
+	c.logger.WithFields(map[string]interface{}{
+		"type":           NAME,
+		"called_service": c.calledService,
+		"route":          c.routeMask,
+		"direction":      direction,
+		"error_message":  err.Error(),
+	}).Warn("contract violation")
+
+	return nil
+}
+
+// reportContractViolation counts a contract violation
+func (c *Client) reportContractViolation(direction string) {
+	if c.statsdClient != nil {
+		c.statsdClient.Incr(fmt.Sprintf("%s.contract_violation", c.statsdStat), c.tagsWith(
+			fmt.Sprintf("http-verb:%s", c.method),
+			fmt.Sprintf("called-service:%s", c.calledService),
+			fmt.Sprintf("route:%s", c.routeMask),
+			fmt.Sprintf("direction:%s", direction),
+		), pkgStatsdRate)
+	}
+	if m, ok := c.metrics.(ContractMetrics); ok {
+		m.ContractViolation(c.metricLabels(), direction)
+	}
+}
@@ -0,0 +1,322 @@
+package blaster
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	"github.com/joelhill/go-rest-http-blaster/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// usersOpenAPI describes a user service in OpenAPI 3.0
+const usersOpenAPI = `
+openapi: 3.0.3
+info:
+  title: users
+  version: "1"
+paths:
+  /v1/users/{id}:
+    get:
+      responses:
+        200:
+          description: the user
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+        default:
+          $ref: '#/components/responses/Error'
+  /v1/users:
+    post:
+      requestBody:
+        $ref: '#/components/requestBodies/NewUser'
+      responses:
+        "201":
+          description: created
+components:
+  schemas:
+    User:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: string
+        name:
+          type: string
+        nickname:
+          type: string
+          nullable: true
+  requestBodies:
+    NewUser:
+      content:
+        application/json:
+          schema:
+            type: object
+            required: [name]
+            properties:
+              name:
+                type: string
+                minLength: 1
+  responses:
+    Error:
+      description: an error
+      content:
+        application/problem+json:
+          schema:
+            type: object
+            required: [title]
+`
+
+var _ = Describe("Contract validation", func() {
+	var (
+		ctx      context.Context
+		dir      string
+		server   *httptest.Server
+		status   int
+		body     string
+		received int32
+		contract *Contract
+	)
+
+	// writeFile writes a fixture to the temp dir
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	// newClient returns a client for the route with the given mode
+	newClient := func(method, routeMask string, mode ContractMode) *Client {
+		c, _ := New(ClientOptions{
+			Endpoint:           server.URL + "/v1/users/42",
+			RouteMask:          routeMask,
+			ContractValidation: &ContractValidation{Contract: contract, Mode: mode, Requests: true},
+		})
+		c.method = method
+		return c
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		dir, _ = ioutil.TempDir("", "contract")
+		status, body = http.StatusOK, `{"id":"42","name":"joel"}`
+		atomic.StoreInt32(&received, 0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&received, 1)
+			w.Header().Set(contentTypeHeader, jsonType)
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+
+		var err error
+		contract, err = NewOpenAPIContract(writeFile("users.yaml", usersOpenAPI))
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+		pkgContractValidation = nil
+	})
+
+	// region openapi
+
+	It("lets responses that keep the contract through", func() {
+		c := newClient(http.MethodGet, "/v1/users/{id}", ContractEnforce)
+		var got map[string]string
+		c.WillSaturate(&got)
+
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(got["name"]).To(Equal("joel"))
+	})
+
+	It("fails responses that break the contract when enforced", func() {
+		body = `{"id":42}`
+		metrics := &recordingMetrics{}
+		c := newClient(http.MethodGet, "/v1/users/{id}", ContractEnforce)
+		c.SetMetrics(metrics)
+
+		statusCode, err := c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusInternalServerError))
+		var contractErr *ContractError
+		Expect(errors.As(err, &contractErr)).To(BeTrue())
+		Expect(contractErr.Direction).To(Equal("response"))
+		Expect(contractErr.StatusCode).To(Equal(http.StatusOK))
+		Expect(contractErr.Violations).To(ConsistOf(
+			"(root): missing properties: 'name'",
+			"/id: expected string, but got number",
+		))
+		Expect(err.Error()).To(HavePrefix("response of GET /v1/users/{id} (200) breaks the contract: "))
+		Expect(metrics.errors).To(Equal([]string{MetricErrorContract}))
+		Expect(c.Response().StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("reads nullable from OpenAPI 3.0 schemas", func() {
+		body = `{"id":"42","name":"joel","nickname":null}`
+		_, err := newClient(http.MethodGet, "/v1/users/{id}", ContractEnforce).Get(ctx)
+		Expect(err).To(BeNil())
+	})
+
+	It("falls back to the default response", func() {
+		status, body = http.StatusNotFound, `{"detail":"no such user"}`
+		_, err := newClient(http.MethodGet, "/v1/users/{id}", ContractEnforce).Get(ctx)
+		var contractErr *ContractError
+		Expect(errors.As(err, &contractErr)).To(BeTrue())
+		Expect(contractErr.Violations).To(ConsistOf("(root): missing properties: 'title'"))
+	})
+
+	It("validates request bodies before sending them", func() {
+		c := newClient(http.MethodPost, "/v1/users", ContractEnforce)
+		_, err := c.Post(ctx, map[string]string{"name": ""})
+		var contractErr *ContractError
+		Expect(errors.As(err, &contractErr)).To(BeTrue())
+		Expect(contractErr.Direction).To(Equal("request"))
+		Expect(contractErr.Violations).To(ConsistOf("/name: length must be >= 1, but got 0"))
+		Expect(atomic.LoadInt32(&received)).To(Equal(int32(0)))
+
+		status, body = http.StatusCreated, ""
+		c = newClient(http.MethodPost, "/v1/users", ContractEnforce)
+		statusCode, err := c.Post(ctx, map[string]string{"name": "joel"})
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusCreated))
+	})
+
+	It("skips operations the contract does not describe", func() {
+		body = `not even json`
+		_, err := newClient(http.MethodGet, "/v1/teams/{id}", ContractEnforce).Get(ctx)
+		Expect(err).ToNot(BeAssignableToTypeOf(&ContractError{}))
+	})
+
+	It("reads OpenAPI 3.1 schemas as JSON Schema 2020-12", func() {
+		var err error
+		contract, err = NewOpenAPIContract(writeFile("users.json", `{
+			"openapi": "3.1.0",
+			"info": {"title": "users", "version": "1"},
+			"paths": {"/v1/users/{id}": {"get": {"responses": {"2XX": {"content": {"application/json": {"schema": {
+				"type": "object",
+				"properties": {"nickname": {"type": ["string", "null"]}},
+				"dependentRequired": {"nickname": ["name"]}
+			}}}}}}}}
+		}`))
+		Expect(err).To(BeNil())
+
+		body = `{"nickname":null,"name":"joel"}`
+		_, err = newClient(http.MethodGet, "/v1/users/{id}", ContractEnforce).Get(ctx)
+		Expect(err).To(BeNil())
+
+		body = `{"nickname":"jo"}`
+		_, err = newClient(http.MethodGet, "/v1/users/{id}", ContractEnforce).Get(ctx)
+		Expect(err).To(BeAssignableToTypeOf(&ContractError{}))
+	})
+
+	It("rejects documents that are not OpenAPI 3", func() {
+		_, err := NewOpenAPIContract(writeFile("swagger.yaml", "swagger: \"2.0\"\n"))
+		Expect(err).ToNot(BeNil())
+		_, err = NewOpenAPIContract(filepath.Join(dir, "missing.yaml"))
+		Expect(err).ToNot(BeNil())
+	})
+
+	// endregion
+
+	// region modes
+
+	It("logs and counts violations in warn mode", func() {
+		body = `{"id":"42"}`
+		logger := testlog.New()
+		statsd := &fakes.FakeStatsdClientPrototype{}
+		c, _ := New(ClientOptions{
+			Endpoint:           server.URL + "/v1/users/42",
+			RouteMask:          "/v1/users/{id}",
+			CalledService:      "user-service",
+			Logger:             logger,
+			ContractValidation: &ContractValidation{Contract: contract, Mode: ContractWarn},
+		})
+		c.SetStatsdDelegate(statsd, "api", nil)
+
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(string(logger.Bytes())).To(ContainSubstring("contract violation"))
+		Expect(string(logger.Bytes())).To(ContainSubstring("missing properties: 'name'"))
+
+		Expect(statsd.IncrCallCount()).To(Equal(1))
+		name, tags, _ := statsd.IncrArgsForCall(0)
+		Expect(name).To(Equal("api.contract_violation"))
+		Expect(tags).To(ContainElement("direction:response"))
+		Expect(tags).To(ContainElement("called-service:user-service"))
+	})
+
+	It("skips validation when off", func() {
+		body = `{"id":42}`
+		pkgContractValidation = &ContractValidation{Contract: contract, Mode: ContractOff}
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/v1/users/42", RouteMask: "/v1/users/{id}"})
+		_, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+	})
+
+	It("counts violations in the prometheus adapter", func() {
+		body = `{}`
+		registry := prometheus.NewRegistry()
+		m, _ := NewPrometheusMetrics(registry, "test")
+		c := newClient(http.MethodGet, "/v1/users/{id}", ContractWarn)
+		c.SetMetrics(m)
+		c.Get(ctx)
+
+		Expect(testutil.ToFloat64(m.(*prometheusMetrics).violations)).To(Equal(1.0))
+	})
+
+	// endregion
+
+	// region json schema
+
+	It("validates successful responses against a JSON Schema", func() {
+		schemaPath := writeFile("user.schema.json", `{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"type": "object",
+			"required": ["id"],
+			"properties": {"id": {"type": "string", "pattern": "^[0-9]+$"}}
+		}`)
+		var err error
+		contract, err = NewSchemaContract(schemaPath, "")
+		Expect(err).To(BeNil())
+
+		_, err = newClient(http.MethodGet, "", ContractEnforce).Get(ctx)
+		Expect(err).To(BeNil())
+
+		body = `{"id":"abc"}`
+		_, err = newClient(http.MethodGet, "", ContractEnforce).Get(ctx)
+		Expect(err).ToNot(BeNil())
+		Expect(strings.Join(err.(*ContractError).Violations, "")).To(HavePrefix("/id: "))
+
+		status, body = http.StatusBadRequest, `{"error":"bad"}`
+		statusCode, err := newClient(http.MethodGet, "", ContractEnforce).Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("reports bodies that are not JSON", func() {
+		_, err := NewSchemaContract(writeFile("broken.json", `{`), "")
+		Expect(err).ToNot(BeNil())
+
+		contract, _ = NewSchemaContract(writeFile("any.yaml", "type: object\n"), "")
+		body = `<html>`
+		_, err = newClient(http.MethodGet, "", ContractEnforce).Get(ctx)
+		Expect(err.(*ContractError).Violations[0]).To(HavePrefix("body is not valid JSON"))
+	})
+
+	// endregion
+})
@@ -0,0 +1,1768 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakeclient
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	log "github.com/InVisionApp/go-logger"
+	blaster "github.com/joelhill/go-rest-http-blaster"
+)
+
+type FakeIClient struct {
+	DeleteStub        func(context.Context, interface{}) (int, error)
+	deleteMutex       sync.RWMutex
+	deleteArgsForCall []struct {
+		arg1 context.Context
+		arg2 interface{}
+	}
+	deleteReturns struct {
+		result1 int
+		result2 error
+	}
+	deleteReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	DoStub        func(context.Context, string, interface{}) (int, error)
+	doMutex       sync.RWMutex
+	doArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 interface{}
+	}
+	doReturns struct {
+		result1 int
+		result2 error
+	}
+	doReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	DurationStub        func() time.Duration
+	durationMutex       sync.RWMutex
+	durationArgsForCall []struct {
+	}
+	durationReturns struct {
+		result1 time.Duration
+	}
+	durationReturnsOnCall map[int]struct {
+		result1 time.Duration
+	}
+	GetStub        func(context.Context) (int, error)
+	getMutex       sync.RWMutex
+	getArgsForCall []struct {
+		arg1 context.Context
+	}
+	getReturns struct {
+		result1 int
+		result2 error
+	}
+	getReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	KeepRawResponseStub        func()
+	keepRawResponseMutex       sync.RWMutex
+	keepRawResponseArgsForCall []struct {
+	}
+	PatchStub        func(context.Context, interface{}) (int, error)
+	patchMutex       sync.RWMutex
+	patchArgsForCall []struct {
+		arg1 context.Context
+		arg2 interface{}
+	}
+	patchReturns struct {
+		result1 int
+		result2 error
+	}
+	patchReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	PostStub        func(context.Context, interface{}) (int, error)
+	postMutex       sync.RWMutex
+	postArgsForCall []struct {
+		arg1 context.Context
+		arg2 interface{}
+	}
+	postReturns struct {
+		result1 int
+		result2 error
+	}
+	postReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	PutStub        func(context.Context, interface{}) (int, error)
+	putMutex       sync.RWMutex
+	putArgsForCall []struct {
+		arg1 context.Context
+		arg2 interface{}
+	}
+	putReturns struct {
+		result1 int
+		result2 error
+	}
+	putReturnsOnCall map[int]struct {
+		result1 int
+		result2 error
+	}
+	RawResponseStub        func() []byte
+	rawResponseMutex       sync.RWMutex
+	rawResponseArgsForCall []struct {
+	}
+	rawResponseReturns struct {
+		result1 []byte
+	}
+	rawResponseReturnsOnCall map[int]struct {
+		result1 []byte
+	}
+	RequestStub        func() *blaster.Request
+	requestMutex       sync.RWMutex
+	requestArgsForCall []struct {
+	}
+	requestReturns struct {
+		result1 *blaster.Request
+	}
+	requestReturnsOnCall map[int]struct {
+		result1 *blaster.Request
+	}
+	ResponseStub        func() *blaster.Response
+	responseMutex       sync.RWMutex
+	responseArgsForCall []struct {
+	}
+	responseReturns struct {
+		result1 *blaster.Response
+	}
+	responseReturnsOnCall map[int]struct {
+		result1 *blaster.Response
+	}
+	SelectedEndpointStub        func() *url.URL
+	selectedEndpointMutex       sync.RWMutex
+	selectedEndpointArgsForCall []struct {
+	}
+	selectedEndpointReturns struct {
+		result1 *url.URL
+	}
+	selectedEndpointReturnsOnCall map[int]struct {
+		result1 *url.URL
+	}
+	SetCircuitBreakerStub        func(blaster.CircuitBreakerPrototype)
+	setCircuitBreakerMutex       sync.RWMutex
+	setCircuitBreakerArgsForCall []struct {
+		arg1 blaster.CircuitBreakerPrototype
+	}
+	SetClockStub        func(blaster.Clock)
+	setClockMutex       sync.RWMutex
+	setClockArgsForCall []struct {
+		arg1 blaster.Clock
+	}
+	SetContentTypeStub        func(string)
+	setContentTypeMutex       sync.RWMutex
+	setContentTypeArgsForCall []struct {
+		arg1 string
+	}
+	SetContractValidationStub        func(*blaster.ContractValidation)
+	setContractValidationMutex       sync.RWMutex
+	setContractValidationArgsForCall []struct {
+		arg1 *blaster.ContractValidation
+	}
+	SetEndpointResolverStub        func(blaster.EndpointResolver, blaster.Balancer)
+	setEndpointResolverMutex       sync.RWMutex
+	setEndpointResolverArgsForCall []struct {
+		arg1 blaster.EndpointResolver
+		arg2 blaster.Balancer
+	}
+	SetFaultInjectorStub        func(*blaster.FaultInjector)
+	setFaultInjectorMutex       sync.RWMutex
+	setFaultInjectorArgsForCall []struct {
+		arg1 *blaster.FaultInjector
+	}
+	SetHeaderStub        func(string, string)
+	setHeaderMutex       sync.RWMutex
+	setHeaderArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	SetHealthCheckerStub        func(*blaster.HealthChecker)
+	setHealthCheckerMutex       sync.RWMutex
+	setHealthCheckerArgsForCall []struct {
+		arg1 *blaster.HealthChecker
+	}
+	SetHooksStub        func(*blaster.Hooks)
+	setHooksMutex       sync.RWMutex
+	setHooksArgsForCall []struct {
+		arg1 *blaster.Hooks
+	}
+	SetLoggerStub        func(log.Logger)
+	setLoggerMutex       sync.RWMutex
+	setLoggerArgsForCall []struct {
+		arg1 log.Logger
+	}
+	SetMetricsStub        func(blaster.Metrics)
+	setMetricsMutex       sync.RWMutex
+	setMetricsArgsForCall []struct {
+		arg1 blaster.Metrics
+	}
+	SetNRTxnNameStub        func(string)
+	setNRTxnNameMutex       sync.RWMutex
+	setNRTxnNameArgsForCall []struct {
+		arg1 string
+	}
+	SetOutlierDetectorStub        func(*blaster.OutlierDetector)
+	setOutlierDetectorMutex       sync.RWMutex
+	setOutlierDetectorArgsForCall []struct {
+		arg1 *blaster.OutlierDetector
+	}
+	SetRequestLoggingStub        func(*blaster.RequestLogging)
+	setRequestLoggingMutex       sync.RWMutex
+	setRequestLoggingArgsForCall []struct {
+		arg1 *blaster.RequestLogging
+	}
+	SetStatsdDelegateStub        func(blaster.StatsdClientPrototype, string, []string)
+	setStatsdDelegateMutex       sync.RWMutex
+	setStatsdDelegateArgsForCall []struct {
+		arg1 blaster.StatsdClientPrototype
+		arg2 string
+		arg3 []string
+	}
+	SetTimeoutMSStub        func(int)
+	setTimeoutMSMutex       sync.RWMutex
+	setTimeoutMSArgsForCall []struct {
+		arg1 int
+	}
+	StatusCodeIsErrorStub        func() bool
+	statusCodeIsErrorMutex       sync.RWMutex
+	statusCodeIsErrorArgsForCall []struct {
+	}
+	statusCodeIsErrorReturns struct {
+		result1 bool
+	}
+	statusCodeIsErrorReturnsOnCall map[int]struct {
+		result1 bool
+	}
+	TimingsStub        func() blaster.Timings
+	timingsMutex       sync.RWMutex
+	timingsArgsForCall []struct {
+	}
+	timingsReturns struct {
+		result1 blaster.Timings
+	}
+	timingsReturnsOnCall map[int]struct {
+		result1 blaster.Timings
+	}
+	WillSaturateStub        func(interface{})
+	willSaturateMutex       sync.RWMutex
+	willSaturateArgsForCall []struct {
+		arg1 interface{}
+	}
+	WillSaturateOnErrorStub        func(interface{})
+	willSaturateOnErrorMutex       sync.RWMutex
+	willSaturateOnErrorArgsForCall []struct {
+		arg1 interface{}
+	}
+	WillSaturateWithStatusCodeStub        func(int, interface{})
+	willSaturateWithStatusCodeMutex       sync.RWMutex
+	willSaturateWithStatusCodeArgsForCall []struct {
+		arg1 int
+		arg2 interface{}
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeIClient) Delete(arg1 context.Context, arg2 interface{}) (int, error) {
+	fake.deleteMutex.Lock()
+	ret, specificReturn := fake.deleteReturnsOnCall[len(fake.deleteArgsForCall)]
+	fake.deleteArgsForCall = append(fake.deleteArgsForCall, struct {
+		arg1 context.Context
+		arg2 interface{}
+	}{arg1, arg2})
+	stub := fake.DeleteStub
+	fakeReturns := fake.deleteReturns
+	fake.recordInvocation("Delete", []interface{}{arg1, arg2})
+	fake.deleteMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeIClient) DeleteCallCount() int {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	return len(fake.deleteArgsForCall)
+}
+
+func (fake *FakeIClient) DeleteCalls(stub func(context.Context, interface{}) (int, error)) {
+	fake.deleteMutex.Lock()
+	defer fake.deleteMutex.Unlock()
+	fake.DeleteStub = stub
+}
+
+func (fake *FakeIClient) DeleteArgsForCall(i int) (context.Context, interface{}) {
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	argsForCall := fake.deleteArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeIClient) DeleteReturns(result1 int, result2 error) {
+	fake.deleteMutex.Lock()
+	defer fake.deleteMutex.Unlock()
+	fake.DeleteStub = nil
+	fake.deleteReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) DeleteReturnsOnCall(i int, result1 int, result2 error) {
+	fake.deleteMutex.Lock()
+	defer fake.deleteMutex.Unlock()
+	fake.DeleteStub = nil
+	if fake.deleteReturnsOnCall == nil {
+		fake.deleteReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.deleteReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) Do(arg1 context.Context, arg2 string, arg3 interface{}) (int, error) {
+	fake.doMutex.Lock()
+	ret, specificReturn := fake.doReturnsOnCall[len(fake.doArgsForCall)]
+	fake.doArgsForCall = append(fake.doArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 interface{}
+	}{arg1, arg2, arg3})
+	stub := fake.DoStub
+	fakeReturns := fake.doReturns
+	fake.recordInvocation("Do", []interface{}{arg1, arg2, arg3})
+	fake.doMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeIClient) DoCallCount() int {
+	fake.doMutex.RLock()
+	defer fake.doMutex.RUnlock()
+	return len(fake.doArgsForCall)
+}
+
+func (fake *FakeIClient) DoCalls(stub func(context.Context, string, interface{}) (int, error)) {
+	fake.doMutex.Lock()
+	defer fake.doMutex.Unlock()
+	fake.DoStub = stub
+}
+
+func (fake *FakeIClient) DoArgsForCall(i int) (context.Context, string, interface{}) {
+	fake.doMutex.RLock()
+	defer fake.doMutex.RUnlock()
+	argsForCall := fake.doArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeIClient) DoReturns(result1 int, result2 error) {
+	fake.doMutex.Lock()
+	defer fake.doMutex.Unlock()
+	fake.DoStub = nil
+	fake.doReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) DoReturnsOnCall(i int, result1 int, result2 error) {
+	fake.doMutex.Lock()
+	defer fake.doMutex.Unlock()
+	fake.DoStub = nil
+	if fake.doReturnsOnCall == nil {
+		fake.doReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.doReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) Duration() time.Duration {
+	fake.durationMutex.Lock()
+	ret, specificReturn := fake.durationReturnsOnCall[len(fake.durationArgsForCall)]
+	fake.durationArgsForCall = append(fake.durationArgsForCall, struct {
+	}{})
+	stub := fake.DurationStub
+	fakeReturns := fake.durationReturns
+	fake.recordInvocation("Duration", []interface{}{})
+	fake.durationMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeIClient) DurationCallCount() int {
+	fake.durationMutex.RLock()
+	defer fake.durationMutex.RUnlock()
+	return len(fake.durationArgsForCall)
+}
+
+func (fake *FakeIClient) DurationCalls(stub func() time.Duration) {
+	fake.durationMutex.Lock()
+	defer fake.durationMutex.Unlock()
+	fake.DurationStub = stub
+}
+
+func (fake *FakeIClient) DurationReturns(result1 time.Duration) {
+	fake.durationMutex.Lock()
+	defer fake.durationMutex.Unlock()
+	fake.DurationStub = nil
+	fake.durationReturns = struct {
+		result1 time.Duration
+	}{result1}
+}
+
+func (fake *FakeIClient) DurationReturnsOnCall(i int, result1 time.Duration) {
+	fake.durationMutex.Lock()
+	defer fake.durationMutex.Unlock()
+	fake.DurationStub = nil
+	if fake.durationReturnsOnCall == nil {
+		fake.durationReturnsOnCall = make(map[int]struct {
+			result1 time.Duration
+		})
+	}
+	fake.durationReturnsOnCall[i] = struct {
+		result1 time.Duration
+	}{result1}
+}
+
+func (fake *FakeIClient) Get(arg1 context.Context) (int, error) {
+	fake.getMutex.Lock()
+	ret, specificReturn := fake.getReturnsOnCall[len(fake.getArgsForCall)]
+	fake.getArgsForCall = append(fake.getArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GetStub
+	fakeReturns := fake.getReturns
+	fake.recordInvocation("Get", []interface{}{arg1})
+	fake.getMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeIClient) GetCallCount() int {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	return len(fake.getArgsForCall)
+}
+
+func (fake *FakeIClient) GetCalls(stub func(context.Context) (int, error)) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = stub
+}
+
+func (fake *FakeIClient) GetArgsForCall(i int) context.Context {
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	argsForCall := fake.getArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) GetReturns(result1 int, result2 error) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = nil
+	fake.getReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) GetReturnsOnCall(i int, result1 int, result2 error) {
+	fake.getMutex.Lock()
+	defer fake.getMutex.Unlock()
+	fake.GetStub = nil
+	if fake.getReturnsOnCall == nil {
+		fake.getReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.getReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) KeepRawResponse() {
+	fake.keepRawResponseMutex.Lock()
+	fake.keepRawResponseArgsForCall = append(fake.keepRawResponseArgsForCall, struct {
+	}{})
+	stub := fake.KeepRawResponseStub
+	fake.recordInvocation("KeepRawResponse", []interface{}{})
+	fake.keepRawResponseMutex.Unlock()
+	if stub != nil {
+		fake.KeepRawResponseStub()
+	}
+}
+
+func (fake *FakeIClient) KeepRawResponseCallCount() int {
+	fake.keepRawResponseMutex.RLock()
+	defer fake.keepRawResponseMutex.RUnlock()
+	return len(fake.keepRawResponseArgsForCall)
+}
+
+func (fake *FakeIClient) KeepRawResponseCalls(stub func()) {
+	fake.keepRawResponseMutex.Lock()
+	defer fake.keepRawResponseMutex.Unlock()
+	fake.KeepRawResponseStub = stub
+}
+
+func (fake *FakeIClient) Patch(arg1 context.Context, arg2 interface{}) (int, error) {
+	fake.patchMutex.Lock()
+	ret, specificReturn := fake.patchReturnsOnCall[len(fake.patchArgsForCall)]
+	fake.patchArgsForCall = append(fake.patchArgsForCall, struct {
+		arg1 context.Context
+		arg2 interface{}
+	}{arg1, arg2})
+	stub := fake.PatchStub
+	fakeReturns := fake.patchReturns
+	fake.recordInvocation("Patch", []interface{}{arg1, arg2})
+	fake.patchMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeIClient) PatchCallCount() int {
+	fake.patchMutex.RLock()
+	defer fake.patchMutex.RUnlock()
+	return len(fake.patchArgsForCall)
+}
+
+func (fake *FakeIClient) PatchCalls(stub func(context.Context, interface{}) (int, error)) {
+	fake.patchMutex.Lock()
+	defer fake.patchMutex.Unlock()
+	fake.PatchStub = stub
+}
+
+func (fake *FakeIClient) PatchArgsForCall(i int) (context.Context, interface{}) {
+	fake.patchMutex.RLock()
+	defer fake.patchMutex.RUnlock()
+	argsForCall := fake.patchArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeIClient) PatchReturns(result1 int, result2 error) {
+	fake.patchMutex.Lock()
+	defer fake.patchMutex.Unlock()
+	fake.PatchStub = nil
+	fake.patchReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) PatchReturnsOnCall(i int, result1 int, result2 error) {
+	fake.patchMutex.Lock()
+	defer fake.patchMutex.Unlock()
+	fake.PatchStub = nil
+	if fake.patchReturnsOnCall == nil {
+		fake.patchReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.patchReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) Post(arg1 context.Context, arg2 interface{}) (int, error) {
+	fake.postMutex.Lock()
+	ret, specificReturn := fake.postReturnsOnCall[len(fake.postArgsForCall)]
+	fake.postArgsForCall = append(fake.postArgsForCall, struct {
+		arg1 context.Context
+		arg2 interface{}
+	}{arg1, arg2})
+	stub := fake.PostStub
+	fakeReturns := fake.postReturns
+	fake.recordInvocation("Post", []interface{}{arg1, arg2})
+	fake.postMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeIClient) PostCallCount() int {
+	fake.postMutex.RLock()
+	defer fake.postMutex.RUnlock()
+	return len(fake.postArgsForCall)
+}
+
+func (fake *FakeIClient) PostCalls(stub func(context.Context, interface{}) (int, error)) {
+	fake.postMutex.Lock()
+	defer fake.postMutex.Unlock()
+	fake.PostStub = stub
+}
+
+func (fake *FakeIClient) PostArgsForCall(i int) (context.Context, interface{}) {
+	fake.postMutex.RLock()
+	defer fake.postMutex.RUnlock()
+	argsForCall := fake.postArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeIClient) PostReturns(result1 int, result2 error) {
+	fake.postMutex.Lock()
+	defer fake.postMutex.Unlock()
+	fake.PostStub = nil
+	fake.postReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) PostReturnsOnCall(i int, result1 int, result2 error) {
+	fake.postMutex.Lock()
+	defer fake.postMutex.Unlock()
+	fake.PostStub = nil
+	if fake.postReturnsOnCall == nil {
+		fake.postReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.postReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) Put(arg1 context.Context, arg2 interface{}) (int, error) {
+	fake.putMutex.Lock()
+	ret, specificReturn := fake.putReturnsOnCall[len(fake.putArgsForCall)]
+	fake.putArgsForCall = append(fake.putArgsForCall, struct {
+		arg1 context.Context
+		arg2 interface{}
+	}{arg1, arg2})
+	stub := fake.PutStub
+	fakeReturns := fake.putReturns
+	fake.recordInvocation("Put", []interface{}{arg1, arg2})
+	fake.putMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeIClient) PutCallCount() int {
+	fake.putMutex.RLock()
+	defer fake.putMutex.RUnlock()
+	return len(fake.putArgsForCall)
+}
+
+func (fake *FakeIClient) PutCalls(stub func(context.Context, interface{}) (int, error)) {
+	fake.putMutex.Lock()
+	defer fake.putMutex.Unlock()
+	fake.PutStub = stub
+}
+
+func (fake *FakeIClient) PutArgsForCall(i int) (context.Context, interface{}) {
+	fake.putMutex.RLock()
+	defer fake.putMutex.RUnlock()
+	argsForCall := fake.putArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeIClient) PutReturns(result1 int, result2 error) {
+	fake.putMutex.Lock()
+	defer fake.putMutex.Unlock()
+	fake.PutStub = nil
+	fake.putReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) PutReturnsOnCall(i int, result1 int, result2 error) {
+	fake.putMutex.Lock()
+	defer fake.putMutex.Unlock()
+	fake.PutStub = nil
+	if fake.putReturnsOnCall == nil {
+		fake.putReturnsOnCall = make(map[int]struct {
+			result1 int
+			result2 error
+		})
+	}
+	fake.putReturnsOnCall[i] = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeIClient) RawResponse() []byte {
+	fake.rawResponseMutex.Lock()
+	ret, specificReturn := fake.rawResponseReturnsOnCall[len(fake.rawResponseArgsForCall)]
+	fake.rawResponseArgsForCall = append(fake.rawResponseArgsForCall, struct {
+	}{})
+	stub := fake.RawResponseStub
+	fakeReturns := fake.rawResponseReturns
+	fake.recordInvocation("RawResponse", []interface{}{})
+	fake.rawResponseMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeIClient) RawResponseCallCount() int {
+	fake.rawResponseMutex.RLock()
+	defer fake.rawResponseMutex.RUnlock()
+	return len(fake.rawResponseArgsForCall)
+}
+
+func (fake *FakeIClient) RawResponseCalls(stub func() []byte) {
+	fake.rawResponseMutex.Lock()
+	defer fake.rawResponseMutex.Unlock()
+	fake.RawResponseStub = stub
+}
+
+func (fake *FakeIClient) RawResponseReturns(result1 []byte) {
+	fake.rawResponseMutex.Lock()
+	defer fake.rawResponseMutex.Unlock()
+	fake.RawResponseStub = nil
+	fake.rawResponseReturns = struct {
+		result1 []byte
+	}{result1}
+}
+
+func (fake *FakeIClient) RawResponseReturnsOnCall(i int, result1 []byte) {
+	fake.rawResponseMutex.Lock()
+	defer fake.rawResponseMutex.Unlock()
+	fake.RawResponseStub = nil
+	if fake.rawResponseReturnsOnCall == nil {
+		fake.rawResponseReturnsOnCall = make(map[int]struct {
+			result1 []byte
+		})
+	}
+	fake.rawResponseReturnsOnCall[i] = struct {
+		result1 []byte
+	}{result1}
+}
+
+func (fake *FakeIClient) Request() *blaster.Request {
+	fake.requestMutex.Lock()
+	ret, specificReturn := fake.requestReturnsOnCall[len(fake.requestArgsForCall)]
+	fake.requestArgsForCall = append(fake.requestArgsForCall, struct {
+	}{})
+	stub := fake.RequestStub
+	fakeReturns := fake.requestReturns
+	fake.recordInvocation("Request", []interface{}{})
+	fake.requestMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeIClient) RequestCallCount() int {
+	fake.requestMutex.RLock()
+	defer fake.requestMutex.RUnlock()
+	return len(fake.requestArgsForCall)
+}
+
+func (fake *FakeIClient) RequestCalls(stub func() *blaster.Request) {
+	fake.requestMutex.Lock()
+	defer fake.requestMutex.Unlock()
+	fake.RequestStub = stub
+}
+
+func (fake *FakeIClient) RequestReturns(result1 *blaster.Request) {
+	fake.requestMutex.Lock()
+	defer fake.requestMutex.Unlock()
+	fake.RequestStub = nil
+	fake.requestReturns = struct {
+		result1 *blaster.Request
+	}{result1}
+}
+
+func (fake *FakeIClient) RequestReturnsOnCall(i int, result1 *blaster.Request) {
+	fake.requestMutex.Lock()
+	defer fake.requestMutex.Unlock()
+	fake.RequestStub = nil
+	if fake.requestReturnsOnCall == nil {
+		fake.requestReturnsOnCall = make(map[int]struct {
+			result1 *blaster.Request
+		})
+	}
+	fake.requestReturnsOnCall[i] = struct {
+		result1 *blaster.Request
+	}{result1}
+}
+
+func (fake *FakeIClient) Response() *blaster.Response {
+	fake.responseMutex.Lock()
+	ret, specificReturn := fake.responseReturnsOnCall[len(fake.responseArgsForCall)]
+	fake.responseArgsForCall = append(fake.responseArgsForCall, struct {
+	}{})
+	stub := fake.ResponseStub
+	fakeReturns := fake.responseReturns
+	fake.recordInvocation("Response", []interface{}{})
+	fake.responseMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeIClient) ResponseCallCount() int {
+	fake.responseMutex.RLock()
+	defer fake.responseMutex.RUnlock()
+	return len(fake.responseArgsForCall)
+}
+
+func (fake *FakeIClient) ResponseCalls(stub func() *blaster.Response) {
+	fake.responseMutex.Lock()
+	defer fake.responseMutex.Unlock()
+	fake.ResponseStub = stub
+}
+
+func (fake *FakeIClient) ResponseReturns(result1 *blaster.Response) {
+	fake.responseMutex.Lock()
+	defer fake.responseMutex.Unlock()
+	fake.ResponseStub = nil
+	fake.responseReturns = struct {
+		result1 *blaster.Response
+	}{result1}
+}
+
+func (fake *FakeIClient) ResponseReturnsOnCall(i int, result1 *blaster.Response) {
+	fake.responseMutex.Lock()
+	defer fake.responseMutex.Unlock()
+	fake.ResponseStub = nil
+	if fake.responseReturnsOnCall == nil {
+		fake.responseReturnsOnCall = make(map[int]struct {
+			result1 *blaster.Response
+		})
+	}
+	fake.responseReturnsOnCall[i] = struct {
+		result1 *blaster.Response
+	}{result1}
+}
+
+func (fake *FakeIClient) SelectedEndpoint() *url.URL {
+	fake.selectedEndpointMutex.Lock()
+	ret, specificReturn := fake.selectedEndpointReturnsOnCall[len(fake.selectedEndpointArgsForCall)]
+	fake.selectedEndpointArgsForCall = append(fake.selectedEndpointArgsForCall, struct {
+	}{})
+	stub := fake.SelectedEndpointStub
+	fakeReturns := fake.selectedEndpointReturns
+	fake.recordInvocation("SelectedEndpoint", []interface{}{})
+	fake.selectedEndpointMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeIClient) SelectedEndpointCallCount() int {
+	fake.selectedEndpointMutex.RLock()
+	defer fake.selectedEndpointMutex.RUnlock()
+	return len(fake.selectedEndpointArgsForCall)
+}
+
+func (fake *FakeIClient) SelectedEndpointCalls(stub func() *url.URL) {
+	fake.selectedEndpointMutex.Lock()
+	defer fake.selectedEndpointMutex.Unlock()
+	fake.SelectedEndpointStub = stub
+}
+
+func (fake *FakeIClient) SelectedEndpointReturns(result1 *url.URL) {
+	fake.selectedEndpointMutex.Lock()
+	defer fake.selectedEndpointMutex.Unlock()
+	fake.SelectedEndpointStub = nil
+	fake.selectedEndpointReturns = struct {
+		result1 *url.URL
+	}{result1}
+}
+
+func (fake *FakeIClient) SelectedEndpointReturnsOnCall(i int, result1 *url.URL) {
+	fake.selectedEndpointMutex.Lock()
+	defer fake.selectedEndpointMutex.Unlock()
+	fake.SelectedEndpointStub = nil
+	if fake.selectedEndpointReturnsOnCall == nil {
+		fake.selectedEndpointReturnsOnCall = make(map[int]struct {
+			result1 *url.URL
+		})
+	}
+	fake.selectedEndpointReturnsOnCall[i] = struct {
+		result1 *url.URL
+	}{result1}
+}
+
+func (fake *FakeIClient) SetCircuitBreaker(arg1 blaster.CircuitBreakerPrototype) {
+	fake.setCircuitBreakerMutex.Lock()
+	fake.setCircuitBreakerArgsForCall = append(fake.setCircuitBreakerArgsForCall, struct {
+		arg1 blaster.CircuitBreakerPrototype
+	}{arg1})
+	stub := fake.SetCircuitBreakerStub
+	fake.recordInvocation("SetCircuitBreaker", []interface{}{arg1})
+	fake.setCircuitBreakerMutex.Unlock()
+	if stub != nil {
+		fake.SetCircuitBreakerStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetCircuitBreakerCallCount() int {
+	fake.setCircuitBreakerMutex.RLock()
+	defer fake.setCircuitBreakerMutex.RUnlock()
+	return len(fake.setCircuitBreakerArgsForCall)
+}
+
+func (fake *FakeIClient) SetCircuitBreakerCalls(stub func(blaster.CircuitBreakerPrototype)) {
+	fake.setCircuitBreakerMutex.Lock()
+	defer fake.setCircuitBreakerMutex.Unlock()
+	fake.SetCircuitBreakerStub = stub
+}
+
+func (fake *FakeIClient) SetCircuitBreakerArgsForCall(i int) blaster.CircuitBreakerPrototype {
+	fake.setCircuitBreakerMutex.RLock()
+	defer fake.setCircuitBreakerMutex.RUnlock()
+	argsForCall := fake.setCircuitBreakerArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetClock(arg1 blaster.Clock) {
+	fake.setClockMutex.Lock()
+	fake.setClockArgsForCall = append(fake.setClockArgsForCall, struct {
+		arg1 blaster.Clock
+	}{arg1})
+	stub := fake.SetClockStub
+	fake.recordInvocation("SetClock", []interface{}{arg1})
+	fake.setClockMutex.Unlock()
+	if stub != nil {
+		fake.SetClockStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetClockCallCount() int {
+	fake.setClockMutex.RLock()
+	defer fake.setClockMutex.RUnlock()
+	return len(fake.setClockArgsForCall)
+}
+
+func (fake *FakeIClient) SetClockCalls(stub func(blaster.Clock)) {
+	fake.setClockMutex.Lock()
+	defer fake.setClockMutex.Unlock()
+	fake.SetClockStub = stub
+}
+
+func (fake *FakeIClient) SetClockArgsForCall(i int) blaster.Clock {
+	fake.setClockMutex.RLock()
+	defer fake.setClockMutex.RUnlock()
+	argsForCall := fake.setClockArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetContentType(arg1 string) {
+	fake.setContentTypeMutex.Lock()
+	fake.setContentTypeArgsForCall = append(fake.setContentTypeArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.SetContentTypeStub
+	fake.recordInvocation("SetContentType", []interface{}{arg1})
+	fake.setContentTypeMutex.Unlock()
+	if stub != nil {
+		fake.SetContentTypeStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetContentTypeCallCount() int {
+	fake.setContentTypeMutex.RLock()
+	defer fake.setContentTypeMutex.RUnlock()
+	return len(fake.setContentTypeArgsForCall)
+}
+
+func (fake *FakeIClient) SetContentTypeCalls(stub func(string)) {
+	fake.setContentTypeMutex.Lock()
+	defer fake.setContentTypeMutex.Unlock()
+	fake.SetContentTypeStub = stub
+}
+
+func (fake *FakeIClient) SetContentTypeArgsForCall(i int) string {
+	fake.setContentTypeMutex.RLock()
+	defer fake.setContentTypeMutex.RUnlock()
+	argsForCall := fake.setContentTypeArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetContractValidation(arg1 *blaster.ContractValidation) {
+	fake.setContractValidationMutex.Lock()
+	fake.setContractValidationArgsForCall = append(fake.setContractValidationArgsForCall, struct {
+		arg1 *blaster.ContractValidation
+	}{arg1})
+	stub := fake.SetContractValidationStub
+	fake.recordInvocation("SetContractValidation", []interface{}{arg1})
+	fake.setContractValidationMutex.Unlock()
+	if stub != nil {
+		fake.SetContractValidationStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetContractValidationCallCount() int {
+	fake.setContractValidationMutex.RLock()
+	defer fake.setContractValidationMutex.RUnlock()
+	return len(fake.setContractValidationArgsForCall)
+}
+
+func (fake *FakeIClient) SetContractValidationCalls(stub func(*blaster.ContractValidation)) {
+	fake.setContractValidationMutex.Lock()
+	defer fake.setContractValidationMutex.Unlock()
+	fake.SetContractValidationStub = stub
+}
+
+func (fake *FakeIClient) SetContractValidationArgsForCall(i int) *blaster.ContractValidation {
+	fake.setContractValidationMutex.RLock()
+	defer fake.setContractValidationMutex.RUnlock()
+	argsForCall := fake.setContractValidationArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetEndpointResolver(arg1 blaster.EndpointResolver, arg2 blaster.Balancer) {
+	fake.setEndpointResolverMutex.Lock()
+	fake.setEndpointResolverArgsForCall = append(fake.setEndpointResolverArgsForCall, struct {
+		arg1 blaster.EndpointResolver
+		arg2 blaster.Balancer
+	}{arg1, arg2})
+	stub := fake.SetEndpointResolverStub
+	fake.recordInvocation("SetEndpointResolver", []interface{}{arg1, arg2})
+	fake.setEndpointResolverMutex.Unlock()
+	if stub != nil {
+		fake.SetEndpointResolverStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeIClient) SetEndpointResolverCallCount() int {
+	fake.setEndpointResolverMutex.RLock()
+	defer fake.setEndpointResolverMutex.RUnlock()
+	return len(fake.setEndpointResolverArgsForCall)
+}
+
+func (fake *FakeIClient) SetEndpointResolverCalls(stub func(blaster.EndpointResolver, blaster.Balancer)) {
+	fake.setEndpointResolverMutex.Lock()
+	defer fake.setEndpointResolverMutex.Unlock()
+	fake.SetEndpointResolverStub = stub
+}
+
+func (fake *FakeIClient) SetEndpointResolverArgsForCall(i int) (blaster.EndpointResolver, blaster.Balancer) {
+	fake.setEndpointResolverMutex.RLock()
+	defer fake.setEndpointResolverMutex.RUnlock()
+	argsForCall := fake.setEndpointResolverArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeIClient) SetFaultInjector(arg1 *blaster.FaultInjector) {
+	fake.setFaultInjectorMutex.Lock()
+	fake.setFaultInjectorArgsForCall = append(fake.setFaultInjectorArgsForCall, struct {
+		arg1 *blaster.FaultInjector
+	}{arg1})
+	stub := fake.SetFaultInjectorStub
+	fake.recordInvocation("SetFaultInjector", []interface{}{arg1})
+	fake.setFaultInjectorMutex.Unlock()
+	if stub != nil {
+		fake.SetFaultInjectorStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetFaultInjectorCallCount() int {
+	fake.setFaultInjectorMutex.RLock()
+	defer fake.setFaultInjectorMutex.RUnlock()
+	return len(fake.setFaultInjectorArgsForCall)
+}
+
+func (fake *FakeIClient) SetFaultInjectorCalls(stub func(*blaster.FaultInjector)) {
+	fake.setFaultInjectorMutex.Lock()
+	defer fake.setFaultInjectorMutex.Unlock()
+	fake.SetFaultInjectorStub = stub
+}
+
+func (fake *FakeIClient) SetFaultInjectorArgsForCall(i int) *blaster.FaultInjector {
+	fake.setFaultInjectorMutex.RLock()
+	defer fake.setFaultInjectorMutex.RUnlock()
+	argsForCall := fake.setFaultInjectorArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetHeader(arg1 string, arg2 string) {
+	fake.setHeaderMutex.Lock()
+	fake.setHeaderArgsForCall = append(fake.setHeaderArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.SetHeaderStub
+	fake.recordInvocation("SetHeader", []interface{}{arg1, arg2})
+	fake.setHeaderMutex.Unlock()
+	if stub != nil {
+		fake.SetHeaderStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeIClient) SetHeaderCallCount() int {
+	fake.setHeaderMutex.RLock()
+	defer fake.setHeaderMutex.RUnlock()
+	return len(fake.setHeaderArgsForCall)
+}
+
+func (fake *FakeIClient) SetHeaderCalls(stub func(string, string)) {
+	fake.setHeaderMutex.Lock()
+	defer fake.setHeaderMutex.Unlock()
+	fake.SetHeaderStub = stub
+}
+
+func (fake *FakeIClient) SetHeaderArgsForCall(i int) (string, string) {
+	fake.setHeaderMutex.RLock()
+	defer fake.setHeaderMutex.RUnlock()
+	argsForCall := fake.setHeaderArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeIClient) SetHealthChecker(arg1 *blaster.HealthChecker) {
+	fake.setHealthCheckerMutex.Lock()
+	fake.setHealthCheckerArgsForCall = append(fake.setHealthCheckerArgsForCall, struct {
+		arg1 *blaster.HealthChecker
+	}{arg1})
+	stub := fake.SetHealthCheckerStub
+	fake.recordInvocation("SetHealthChecker", []interface{}{arg1})
+	fake.setHealthCheckerMutex.Unlock()
+	if stub != nil {
+		fake.SetHealthCheckerStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetHealthCheckerCallCount() int {
+	fake.setHealthCheckerMutex.RLock()
+	defer fake.setHealthCheckerMutex.RUnlock()
+	return len(fake.setHealthCheckerArgsForCall)
+}
+
+func (fake *FakeIClient) SetHealthCheckerCalls(stub func(*blaster.HealthChecker)) {
+	fake.setHealthCheckerMutex.Lock()
+	defer fake.setHealthCheckerMutex.Unlock()
+	fake.SetHealthCheckerStub = stub
+}
+
+func (fake *FakeIClient) SetHealthCheckerArgsForCall(i int) *blaster.HealthChecker {
+	fake.setHealthCheckerMutex.RLock()
+	defer fake.setHealthCheckerMutex.RUnlock()
+	argsForCall := fake.setHealthCheckerArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetHooks(arg1 *blaster.Hooks) {
+	fake.setHooksMutex.Lock()
+	fake.setHooksArgsForCall = append(fake.setHooksArgsForCall, struct {
+		arg1 *blaster.Hooks
+	}{arg1})
+	stub := fake.SetHooksStub
+	fake.recordInvocation("SetHooks", []interface{}{arg1})
+	fake.setHooksMutex.Unlock()
+	if stub != nil {
+		fake.SetHooksStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetHooksCallCount() int {
+	fake.setHooksMutex.RLock()
+	defer fake.setHooksMutex.RUnlock()
+	return len(fake.setHooksArgsForCall)
+}
+
+func (fake *FakeIClient) SetHooksCalls(stub func(*blaster.Hooks)) {
+	fake.setHooksMutex.Lock()
+	defer fake.setHooksMutex.Unlock()
+	fake.SetHooksStub = stub
+}
+
+func (fake *FakeIClient) SetHooksArgsForCall(i int) *blaster.Hooks {
+	fake.setHooksMutex.RLock()
+	defer fake.setHooksMutex.RUnlock()
+	argsForCall := fake.setHooksArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetLogger(arg1 log.Logger) {
+	fake.setLoggerMutex.Lock()
+	fake.setLoggerArgsForCall = append(fake.setLoggerArgsForCall, struct {
+		arg1 log.Logger
+	}{arg1})
+	stub := fake.SetLoggerStub
+	fake.recordInvocation("SetLogger", []interface{}{arg1})
+	fake.setLoggerMutex.Unlock()
+	if stub != nil {
+		fake.SetLoggerStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetLoggerCallCount() int {
+	fake.setLoggerMutex.RLock()
+	defer fake.setLoggerMutex.RUnlock()
+	return len(fake.setLoggerArgsForCall)
+}
+
+func (fake *FakeIClient) SetLoggerCalls(stub func(log.Logger)) {
+	fake.setLoggerMutex.Lock()
+	defer fake.setLoggerMutex.Unlock()
+	fake.SetLoggerStub = stub
+}
+
+func (fake *FakeIClient) SetLoggerArgsForCall(i int) log.Logger {
+	fake.setLoggerMutex.RLock()
+	defer fake.setLoggerMutex.RUnlock()
+	argsForCall := fake.setLoggerArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetMetrics(arg1 blaster.Metrics) {
+	fake.setMetricsMutex.Lock()
+	fake.setMetricsArgsForCall = append(fake.setMetricsArgsForCall, struct {
+		arg1 blaster.Metrics
+	}{arg1})
+	stub := fake.SetMetricsStub
+	fake.recordInvocation("SetMetrics", []interface{}{arg1})
+	fake.setMetricsMutex.Unlock()
+	if stub != nil {
+		fake.SetMetricsStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetMetricsCallCount() int {
+	fake.setMetricsMutex.RLock()
+	defer fake.setMetricsMutex.RUnlock()
+	return len(fake.setMetricsArgsForCall)
+}
+
+func (fake *FakeIClient) SetMetricsCalls(stub func(blaster.Metrics)) {
+	fake.setMetricsMutex.Lock()
+	defer fake.setMetricsMutex.Unlock()
+	fake.SetMetricsStub = stub
+}
+
+func (fake *FakeIClient) SetMetricsArgsForCall(i int) blaster.Metrics {
+	fake.setMetricsMutex.RLock()
+	defer fake.setMetricsMutex.RUnlock()
+	argsForCall := fake.setMetricsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetNRTxnName(arg1 string) {
+	fake.setNRTxnNameMutex.Lock()
+	fake.setNRTxnNameArgsForCall = append(fake.setNRTxnNameArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.SetNRTxnNameStub
+	fake.recordInvocation("SetNRTxnName", []interface{}{arg1})
+	fake.setNRTxnNameMutex.Unlock()
+	if stub != nil {
+		fake.SetNRTxnNameStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetNRTxnNameCallCount() int {
+	fake.setNRTxnNameMutex.RLock()
+	defer fake.setNRTxnNameMutex.RUnlock()
+	return len(fake.setNRTxnNameArgsForCall)
+}
+
+func (fake *FakeIClient) SetNRTxnNameCalls(stub func(string)) {
+	fake.setNRTxnNameMutex.Lock()
+	defer fake.setNRTxnNameMutex.Unlock()
+	fake.SetNRTxnNameStub = stub
+}
+
+func (fake *FakeIClient) SetNRTxnNameArgsForCall(i int) string {
+	fake.setNRTxnNameMutex.RLock()
+	defer fake.setNRTxnNameMutex.RUnlock()
+	argsForCall := fake.setNRTxnNameArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetOutlierDetector(arg1 *blaster.OutlierDetector) {
+	fake.setOutlierDetectorMutex.Lock()
+	fake.setOutlierDetectorArgsForCall = append(fake.setOutlierDetectorArgsForCall, struct {
+		arg1 *blaster.OutlierDetector
+	}{arg1})
+	stub := fake.SetOutlierDetectorStub
+	fake.recordInvocation("SetOutlierDetector", []interface{}{arg1})
+	fake.setOutlierDetectorMutex.Unlock()
+	if stub != nil {
+		fake.SetOutlierDetectorStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetOutlierDetectorCallCount() int {
+	fake.setOutlierDetectorMutex.RLock()
+	defer fake.setOutlierDetectorMutex.RUnlock()
+	return len(fake.setOutlierDetectorArgsForCall)
+}
+
+func (fake *FakeIClient) SetOutlierDetectorCalls(stub func(*blaster.OutlierDetector)) {
+	fake.setOutlierDetectorMutex.Lock()
+	defer fake.setOutlierDetectorMutex.Unlock()
+	fake.SetOutlierDetectorStub = stub
+}
+
+func (fake *FakeIClient) SetOutlierDetectorArgsForCall(i int) *blaster.OutlierDetector {
+	fake.setOutlierDetectorMutex.RLock()
+	defer fake.setOutlierDetectorMutex.RUnlock()
+	argsForCall := fake.setOutlierDetectorArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetRequestLogging(arg1 *blaster.RequestLogging) {
+	fake.setRequestLoggingMutex.Lock()
+	fake.setRequestLoggingArgsForCall = append(fake.setRequestLoggingArgsForCall, struct {
+		arg1 *blaster.RequestLogging
+	}{arg1})
+	stub := fake.SetRequestLoggingStub
+	fake.recordInvocation("SetRequestLogging", []interface{}{arg1})
+	fake.setRequestLoggingMutex.Unlock()
+	if stub != nil {
+		fake.SetRequestLoggingStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetRequestLoggingCallCount() int {
+	fake.setRequestLoggingMutex.RLock()
+	defer fake.setRequestLoggingMutex.RUnlock()
+	return len(fake.setRequestLoggingArgsForCall)
+}
+
+func (fake *FakeIClient) SetRequestLoggingCalls(stub func(*blaster.RequestLogging)) {
+	fake.setRequestLoggingMutex.Lock()
+	defer fake.setRequestLoggingMutex.Unlock()
+	fake.SetRequestLoggingStub = stub
+}
+
+func (fake *FakeIClient) SetRequestLoggingArgsForCall(i int) *blaster.RequestLogging {
+	fake.setRequestLoggingMutex.RLock()
+	defer fake.setRequestLoggingMutex.RUnlock()
+	argsForCall := fake.setRequestLoggingArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) SetStatsdDelegate(arg1 blaster.StatsdClientPrototype, arg2 string, arg3 []string) {
+	var arg3Copy []string
+	if arg3 != nil {
+		arg3Copy = make([]string, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.setStatsdDelegateMutex.Lock()
+	fake.setStatsdDelegateArgsForCall = append(fake.setStatsdDelegateArgsForCall, struct {
+		arg1 blaster.StatsdClientPrototype
+		arg2 string
+		arg3 []string
+	}{arg1, arg2, arg3Copy})
+	stub := fake.SetStatsdDelegateStub
+	fake.recordInvocation("SetStatsdDelegate", []interface{}{arg1, arg2, arg3Copy})
+	fake.setStatsdDelegateMutex.Unlock()
+	if stub != nil {
+		fake.SetStatsdDelegateStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeIClient) SetStatsdDelegateCallCount() int {
+	fake.setStatsdDelegateMutex.RLock()
+	defer fake.setStatsdDelegateMutex.RUnlock()
+	return len(fake.setStatsdDelegateArgsForCall)
+}
+
+func (fake *FakeIClient) SetStatsdDelegateCalls(stub func(blaster.StatsdClientPrototype, string, []string)) {
+	fake.setStatsdDelegateMutex.Lock()
+	defer fake.setStatsdDelegateMutex.Unlock()
+	fake.SetStatsdDelegateStub = stub
+}
+
+func (fake *FakeIClient) SetStatsdDelegateArgsForCall(i int) (blaster.StatsdClientPrototype, string, []string) {
+	fake.setStatsdDelegateMutex.RLock()
+	defer fake.setStatsdDelegateMutex.RUnlock()
+	argsForCall := fake.setStatsdDelegateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeIClient) SetTimeoutMS(arg1 int) {
+	fake.setTimeoutMSMutex.Lock()
+	fake.setTimeoutMSArgsForCall = append(fake.setTimeoutMSArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.SetTimeoutMSStub
+	fake.recordInvocation("SetTimeoutMS", []interface{}{arg1})
+	fake.setTimeoutMSMutex.Unlock()
+	if stub != nil {
+		fake.SetTimeoutMSStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) SetTimeoutMSCallCount() int {
+	fake.setTimeoutMSMutex.RLock()
+	defer fake.setTimeoutMSMutex.RUnlock()
+	return len(fake.setTimeoutMSArgsForCall)
+}
+
+func (fake *FakeIClient) SetTimeoutMSCalls(stub func(int)) {
+	fake.setTimeoutMSMutex.Lock()
+	defer fake.setTimeoutMSMutex.Unlock()
+	fake.SetTimeoutMSStub = stub
+}
+
+func (fake *FakeIClient) SetTimeoutMSArgsForCall(i int) int {
+	fake.setTimeoutMSMutex.RLock()
+	defer fake.setTimeoutMSMutex.RUnlock()
+	argsForCall := fake.setTimeoutMSArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) StatusCodeIsError() bool {
+	fake.statusCodeIsErrorMutex.Lock()
+	ret, specificReturn := fake.statusCodeIsErrorReturnsOnCall[len(fake.statusCodeIsErrorArgsForCall)]
+	fake.statusCodeIsErrorArgsForCall = append(fake.statusCodeIsErrorArgsForCall, struct {
+	}{})
+	stub := fake.StatusCodeIsErrorStub
+	fakeReturns := fake.statusCodeIsErrorReturns
+	fake.recordInvocation("StatusCodeIsError", []interface{}{})
+	fake.statusCodeIsErrorMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeIClient) StatusCodeIsErrorCallCount() int {
+	fake.statusCodeIsErrorMutex.RLock()
+	defer fake.statusCodeIsErrorMutex.RUnlock()
+	return len(fake.statusCodeIsErrorArgsForCall)
+}
+
+func (fake *FakeIClient) StatusCodeIsErrorCalls(stub func() bool) {
+	fake.statusCodeIsErrorMutex.Lock()
+	defer fake.statusCodeIsErrorMutex.Unlock()
+	fake.StatusCodeIsErrorStub = stub
+}
+
+func (fake *FakeIClient) StatusCodeIsErrorReturns(result1 bool) {
+	fake.statusCodeIsErrorMutex.Lock()
+	defer fake.statusCodeIsErrorMutex.Unlock()
+	fake.StatusCodeIsErrorStub = nil
+	fake.statusCodeIsErrorReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeIClient) StatusCodeIsErrorReturnsOnCall(i int, result1 bool) {
+	fake.statusCodeIsErrorMutex.Lock()
+	defer fake.statusCodeIsErrorMutex.Unlock()
+	fake.StatusCodeIsErrorStub = nil
+	if fake.statusCodeIsErrorReturnsOnCall == nil {
+		fake.statusCodeIsErrorReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.statusCodeIsErrorReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeIClient) Timings() blaster.Timings {
+	fake.timingsMutex.Lock()
+	ret, specificReturn := fake.timingsReturnsOnCall[len(fake.timingsArgsForCall)]
+	fake.timingsArgsForCall = append(fake.timingsArgsForCall, struct {
+	}{})
+	stub := fake.TimingsStub
+	fakeReturns := fake.timingsReturns
+	fake.recordInvocation("Timings", []interface{}{})
+	fake.timingsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeIClient) TimingsCallCount() int {
+	fake.timingsMutex.RLock()
+	defer fake.timingsMutex.RUnlock()
+	return len(fake.timingsArgsForCall)
+}
+
+func (fake *FakeIClient) TimingsCalls(stub func() blaster.Timings) {
+	fake.timingsMutex.Lock()
+	defer fake.timingsMutex.Unlock()
+	fake.TimingsStub = stub
+}
+
+func (fake *FakeIClient) TimingsReturns(result1 blaster.Timings) {
+	fake.timingsMutex.Lock()
+	defer fake.timingsMutex.Unlock()
+	fake.TimingsStub = nil
+	fake.timingsReturns = struct {
+		result1 blaster.Timings
+	}{result1}
+}
+
+func (fake *FakeIClient) TimingsReturnsOnCall(i int, result1 blaster.Timings) {
+	fake.timingsMutex.Lock()
+	defer fake.timingsMutex.Unlock()
+	fake.TimingsStub = nil
+	if fake.timingsReturnsOnCall == nil {
+		fake.timingsReturnsOnCall = make(map[int]struct {
+			result1 blaster.Timings
+		})
+	}
+	fake.timingsReturnsOnCall[i] = struct {
+		result1 blaster.Timings
+	}{result1}
+}
+
+func (fake *FakeIClient) WillSaturate(arg1 interface{}) {
+	fake.willSaturateMutex.Lock()
+	fake.willSaturateArgsForCall = append(fake.willSaturateArgsForCall, struct {
+		arg1 interface{}
+	}{arg1})
+	stub := fake.WillSaturateStub
+	fake.recordInvocation("WillSaturate", []interface{}{arg1})
+	fake.willSaturateMutex.Unlock()
+	if stub != nil {
+		fake.WillSaturateStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) WillSaturateCallCount() int {
+	fake.willSaturateMutex.RLock()
+	defer fake.willSaturateMutex.RUnlock()
+	return len(fake.willSaturateArgsForCall)
+}
+
+func (fake *FakeIClient) WillSaturateCalls(stub func(interface{})) {
+	fake.willSaturateMutex.Lock()
+	defer fake.willSaturateMutex.Unlock()
+	fake.WillSaturateStub = stub
+}
+
+func (fake *FakeIClient) WillSaturateArgsForCall(i int) interface{} {
+	fake.willSaturateMutex.RLock()
+	defer fake.willSaturateMutex.RUnlock()
+	argsForCall := fake.willSaturateArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) WillSaturateOnError(arg1 interface{}) {
+	fake.willSaturateOnErrorMutex.Lock()
+	fake.willSaturateOnErrorArgsForCall = append(fake.willSaturateOnErrorArgsForCall, struct {
+		arg1 interface{}
+	}{arg1})
+	stub := fake.WillSaturateOnErrorStub
+	fake.recordInvocation("WillSaturateOnError", []interface{}{arg1})
+	fake.willSaturateOnErrorMutex.Unlock()
+	if stub != nil {
+		fake.WillSaturateOnErrorStub(arg1)
+	}
+}
+
+func (fake *FakeIClient) WillSaturateOnErrorCallCount() int {
+	fake.willSaturateOnErrorMutex.RLock()
+	defer fake.willSaturateOnErrorMutex.RUnlock()
+	return len(fake.willSaturateOnErrorArgsForCall)
+}
+
+func (fake *FakeIClient) WillSaturateOnErrorCalls(stub func(interface{})) {
+	fake.willSaturateOnErrorMutex.Lock()
+	defer fake.willSaturateOnErrorMutex.Unlock()
+	fake.WillSaturateOnErrorStub = stub
+}
+
+func (fake *FakeIClient) WillSaturateOnErrorArgsForCall(i int) interface{} {
+	fake.willSaturateOnErrorMutex.RLock()
+	defer fake.willSaturateOnErrorMutex.RUnlock()
+	argsForCall := fake.willSaturateOnErrorArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeIClient) WillSaturateWithStatusCode(arg1 int, arg2 interface{}) {
+	fake.willSaturateWithStatusCodeMutex.Lock()
+	fake.willSaturateWithStatusCodeArgsForCall = append(fake.willSaturateWithStatusCodeArgsForCall, struct {
+		arg1 int
+		arg2 interface{}
+	}{arg1, arg2})
+	stub := fake.WillSaturateWithStatusCodeStub
+	fake.recordInvocation("WillSaturateWithStatusCode", []interface{}{arg1, arg2})
+	fake.willSaturateWithStatusCodeMutex.Unlock()
+	if stub != nil {
+		fake.WillSaturateWithStatusCodeStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeIClient) WillSaturateWithStatusCodeCallCount() int {
+	fake.willSaturateWithStatusCodeMutex.RLock()
+	defer fake.willSaturateWithStatusCodeMutex.RUnlock()
+	return len(fake.willSaturateWithStatusCodeArgsForCall)
+}
+
+func (fake *FakeIClient) WillSaturateWithStatusCodeCalls(stub func(int, interface{})) {
+	fake.willSaturateWithStatusCodeMutex.Lock()
+	defer fake.willSaturateWithStatusCodeMutex.Unlock()
+	fake.WillSaturateWithStatusCodeStub = stub
+}
+
+func (fake *FakeIClient) WillSaturateWithStatusCodeArgsForCall(i int) (int, interface{}) {
+	fake.willSaturateWithStatusCodeMutex.RLock()
+	defer fake.willSaturateWithStatusCodeMutex.RUnlock()
+	argsForCall := fake.willSaturateWithStatusCodeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeIClient) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.deleteMutex.RLock()
+	defer fake.deleteMutex.RUnlock()
+	fake.doMutex.RLock()
+	defer fake.doMutex.RUnlock()
+	fake.durationMutex.RLock()
+	defer fake.durationMutex.RUnlock()
+	fake.getMutex.RLock()
+	defer fake.getMutex.RUnlock()
+	fake.keepRawResponseMutex.RLock()
+	defer fake.keepRawResponseMutex.RUnlock()
+	fake.patchMutex.RLock()
+	defer fake.patchMutex.RUnlock()
+	fake.postMutex.RLock()
+	defer fake.postMutex.RUnlock()
+	fake.putMutex.RLock()
+	defer fake.putMutex.RUnlock()
+	fake.rawResponseMutex.RLock()
+	defer fake.rawResponseMutex.RUnlock()
+	fake.requestMutex.RLock()
+	defer fake.requestMutex.RUnlock()
+	fake.responseMutex.RLock()
+	defer fake.responseMutex.RUnlock()
+	fake.selectedEndpointMutex.RLock()
+	defer fake.selectedEndpointMutex.RUnlock()
+	fake.setCircuitBreakerMutex.RLock()
+	defer fake.setCircuitBreakerMutex.RUnlock()
+	fake.setClockMutex.RLock()
+	defer fake.setClockMutex.RUnlock()
+	fake.setContentTypeMutex.RLock()
+	defer fake.setContentTypeMutex.RUnlock()
+	fake.setContractValidationMutex.RLock()
+	defer fake.setContractValidationMutex.RUnlock()
+	fake.setEndpointResolverMutex.RLock()
+	defer fake.setEndpointResolverMutex.RUnlock()
+	fake.setFaultInjectorMutex.RLock()
+	defer fake.setFaultInjectorMutex.RUnlock()
+	fake.setHeaderMutex.RLock()
+	defer fake.setHeaderMutex.RUnlock()
+	fake.setHealthCheckerMutex.RLock()
+	defer fake.setHealthCheckerMutex.RUnlock()
+	fake.setHooksMutex.RLock()
+	defer fake.setHooksMutex.RUnlock()
+	fake.setLoggerMutex.RLock()
+	defer fake.setLoggerMutex.RUnlock()
+	fake.setMetricsMutex.RLock()
+	defer fake.setMetricsMutex.RUnlock()
+	fake.setNRTxnNameMutex.RLock()
+	defer fake.setNRTxnNameMutex.RUnlock()
+	fake.setOutlierDetectorMutex.RLock()
+	defer fake.setOutlierDetectorMutex.RUnlock()
+	fake.setRequestLoggingMutex.RLock()
+	defer fake.setRequestLoggingMutex.RUnlock()
+	fake.setStatsdDelegateMutex.RLock()
+	defer fake.setStatsdDelegateMutex.RUnlock()
+	fake.setTimeoutMSMutex.RLock()
+	defer fake.setTimeoutMSMutex.RUnlock()
+	fake.statusCodeIsErrorMutex.RLock()
+	defer fake.statusCodeIsErrorMutex.RUnlock()
+	fake.timingsMutex.RLock()
+	defer fake.timingsMutex.RUnlock()
+	fake.willSaturateMutex.RLock()
+	defer fake.willSaturateMutex.RUnlock()
+	fake.willSaturateOnErrorMutex.RLock()
+	defer fake.willSaturateOnErrorMutex.RUnlock()
+	fake.willSaturateWithStatusCodeMutex.RLock()
+	defer fake.willSaturateWithStatusCodeMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeIClient) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ blaster.IClient = new(FakeIClient)
@@ -0,0 +1,576 @@
+package blaster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// faultHeader is set on responses synthesized by a status fault
+const faultHeader = "X-Blaster-Fault"
+
+// FaultKind is a kind of injected fault
+type FaultKind string
+
+const (
+	// FaultLatency delays the request
+	FaultLatency FaultKind = "latency"
+	// FaultTimeout fails the request with a timeout error
+	FaultTimeout FaultKind = "timeout"
+	// FaultConnectionReset fails the request as if the endpoint reset
+	// the connection
+	FaultConnectionReset FaultKind = "connection_reset"
+	// FaultStatus answers the request with a chosen status code and body,
+	// without sending it
+	FaultStatus FaultKind = "status"
+	// FaultTruncatedBody cuts the response body short
+	FaultTruncatedBody FaultKind = "truncated_body"
+)
+
+// LatencyDistribution is how injected latencies are spread
+type LatencyDistribution string
+
+const (
+	// LatencyFixed always adds MeanMS
+	LatencyFixed LatencyDistribution = "fixed"
+	// LatencyUniform adds between MinMS and MaxMS
+	LatencyUniform LatencyDistribution = "uniform"
+	// LatencyNormal adds around MeanMS, with StdDevMS
+	LatencyNormal LatencyDistribution = "normal"
+	// LatencyExponential adds MeanMS on average, with a long tail
+	LatencyExponential LatencyDistribution = "exponential"
+)
+
+// FaultConfig configures a FaultInjector.  It can be loaded from a JSON
+// or YAML file, see NewFaultInjectorFromFile.
+type FaultConfig struct {
+	// Enabled turns fault injection on
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// Rules are tried in order, the first one that matches the request
+	// applies
+	Rules []FaultRule `json:"rules" yaml:"rules"`
+}
+
+// FaultRule describes the faults injected into the requests to a called
+// service and route
+type FaultRule struct {
+	// CalledService is the service the rule applies to, any if empty
+	CalledService string `json:"called_service" yaml:"called_service"`
+
+	// Route is the route mask the rule applies to, any if empty
+	Route string `json:"route" yaml:"route"`
+
+	// Latency delays a share of the requests
+	Latency *LatencyFault `json:"latency" yaml:"latency"`
+
+	// Errors fail a share of the requests.  A request fails with at
+	// most one of them, so their shares add up to at most 100 percent.
+	Errors []ErrorFault `json:"errors" yaml:"errors"`
+
+	// Truncate cuts the response body of a share of the requests short
+	Truncate *TruncateFault `json:"truncate" yaml:"truncate"`
+}
+
+// LatencyFault delays requests before they are sent
+type LatencyFault struct {
+	// Percent is the share of requests delayed, from 0 to 100
+	Percent float64 `json:"percent" yaml:"percent"`
+
+	// Distribution defaults to LatencyFixed
+	Distribution LatencyDistribution `json:"distribution" yaml:"distribution"`
+
+	MeanMS   int `json:"mean_ms" yaml:"mean_ms"`
+	StdDevMS int `json:"stddev_ms" yaml:"stddev_ms"`
+	MinMS    int `json:"min_ms" yaml:"min_ms"`
+
+	// MaxMS bounds the latency of every distribution, if set
+	MaxMS int `json:"max_ms" yaml:"max_ms"`
+}
+
+// ErrorFault fails requests
+type ErrorFault struct {
+	// Percent is the share of requests failed, from 0 to 100
+	Percent float64 `json:"percent" yaml:"percent"`
+
+	// Kind is FaultTimeout, FaultConnectionReset or FaultStatus
+	Kind FaultKind `json:"kind" yaml:"kind"`
+
+	// StatusCode and Body answer FaultStatus requests
+	StatusCode int    `json:"status_code" yaml:"status_code"`
+	Body       string `json:"body" yaml:"body"`
+
+	// ContentType of the Body.  Defaults to application/json when the
+	// Body is valid JSON, so it is decoded into the error prototype.
+	ContentType string `json:"content_type" yaml:"content_type"`
+}
+
+// header returns the headers of the answer to a FaultStatus request
+func (e ErrorFault) header() http.Header {
+	header := http.Header{faultHeader: {string(FaultStatus)}}
+	contentType := e.ContentType
+	if contentType == "" && json.Valid([]byte(e.Body)) {
+		contentType = jsonType
+	}
+	if contentType != "" {
+		header.Set(contentTypeHeader, contentType)
+	}
+
+	return header
+}
+
+// TruncateFault cuts response bodies short
+type TruncateFault struct {
+	// Percent is the share of responses truncated, from 0 to 100
+	Percent float64 `json:"percent" yaml:"percent"`
+
+	// Bytes is the number of bytes kept, half the body if 0
+	Bytes int `json:"bytes" yaml:"bytes"`
+}
+
+// FaultError is the error of a request failed by an injected timeout or
+// connection reset.  It is returned wrapped in a *url.Error, as the
+// errors of real requests are.
+type FaultError struct {
+	Kind FaultKind
+	err  error
+}
+
+// errFaultTimeout is the cause of an injected timeout
+var errFaultTimeout = errors.New("timeout awaiting response headers")
+
+// Error implements error
+func (e *FaultError) Error() string {
+	return fmt.Sprintf("injected fault: %s", e.err)
+}
+
+// Unwrap returns the error the fault stands for, e.g. syscall.ECONNRESET
+func (e *FaultError) Unwrap() error {
+	return e.err
+}
+
+// Timeout implements net.Error
+func (e *FaultError) Timeout() bool {
+	return e.Kind == FaultTimeout
+}
+
+// Temporary implements net.Error
+func (e *FaultError) Temporary() bool {
+	return true
+}
+
+// FaultInjector injects faults into requests for chaos testing.  Faults
+// are reported like real ones, but carry a "fault" tag in metrics, spans,
+// logs and hook events, and are recorded on the Response, so they are
+// never confused with real ones.  The configuration can be changed at
+// runtime, with Update and SetEnabled, or by editing the file of an
+// injector created with NewFaultInjectorFromFile.
+type FaultInjector struct {
+	// Clock times the checks for changes of the configuration file.
+	// Defaults to Defaults.Clock.
+	Clock Clock
+
+	path     string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	config  FaultConfig
+	checked time.Time
+	modTime time.Time
+	size    int64
+}
+
+// NewFaultInjector returns an injector with the configuration
+func NewFaultInjector(config FaultConfig) (*FaultInjector, error) {
+	f := &FaultInjector{}
+	if err := f.Update(config); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// NewFaultInjectorFromFile loads the configuration from the file at path,
+// and reloads it when the file changes.  Files ending in .yaml or .yml
+// are read as YAML, anything else as JSON.  The file is checked for
+// changes at most once per interval, which defaults to 5 seconds.  If a
+// reload fails, the last good configuration is kept.
+func NewFaultInjectorFromFile(path string, interval time.Duration) (*FaultInjector, error) {
+	if interval <= 0 {
+		interval = defaultFileCheckInterval
+	}
+
+	f := &FaultInjector{
+		path:     path,
+		interval: interval,
+	}
+	if err := f.refresh(true); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Update replaces the configuration
+func (f *FaultInjector) Update(config FaultConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.config = config
+	return nil
+}
+
+// SetEnabled turns fault injection on or off, keeping the rules
+func (f *FaultInjector) SetEnabled(enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.config.Enabled = enabled
+}
+
+// Enabled tells whether faults are injected
+func (f *FaultInjector) Enabled() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.config.Enabled
+}
+
+// Config returns the current configuration
+func (f *FaultInjector) Config() FaultConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.config
+}
+
+// refresh re-reads the file if it changed since it was last read
+func (f *FaultInjector) refresh(force bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.checked = clockOrDefault(f.Clock).Now()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	if !force && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	var config FaultConfig
+	switch strings.ToLower(filepath.Ext(f.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	default:
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return err
+	}
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	f.config = config
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+
+	return nil
+}
+
+// rule returns the first rule that matches the request, if enabled
+func (f *FaultInjector) rule(calledService, routeMask string) *FaultRule {
+	if f.path != "" {
+		f.mu.RLock()
+		stale := isStale(clockOrDefault(f.Clock), f.checked, f.interval)
+		f.mu.RUnlock()
+
+		if stale {
+			// keep the last good configuration if the file went bad
+			f.refresh(false)
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.config.Enabled {
+		return nil
+	}
+	for i := range f.config.Rules {
+		rule := f.config.Rules[i]
+		if (rule.CalledService == "" || rule.CalledService == calledService) && (rule.Route == "" || rule.Route == routeMask) {
+			return &rule
+		}
+	}
+
+	return nil
+}
+
+// plan draws the faults injected into one attempt
+func (f *FaultInjector) plan(calledService, routeMask string) *faultPlan {
+	rule := f.rule(calledService, routeMask)
+	if rule == nil {
+		return nil
+	}
+
+	plan := &faultPlan{}
+	if rule.Latency != nil && roll(rule.Latency.Percent) {
+		plan.latency = rule.Latency.sample()
+		plan.kinds = append(plan.kinds, FaultLatency)
+	}
+
+	// the error shares are laid end to end, so one draw picks at most one
+	draw, offset := rand.Float64()*100, 0.0
+	for i := range rule.Errors {
+		offset += rule.Errors[i].Percent
+		if draw < offset {
+			plan.err = &rule.Errors[i]
+			plan.kinds = append(plan.kinds, plan.err.Kind)
+			break
+		}
+	}
+
+	if plan.err == nil && rule.Truncate != nil && roll(rule.Truncate.Percent) {
+		plan.truncate = rule.Truncate
+		plan.kinds = append(plan.kinds, FaultTruncatedBody)
+	}
+
+	if len(plan.kinds) == 0 {
+		return nil
+	}
+
+	return plan
+}
+
+// validate checks the configuration before it is applied
+func (config FaultConfig) validate() error {
+	for i, rule := range config.Rules {
+		if l := rule.Latency; l != nil {
+			if err := validatePercent(l.Percent); err != nil {
+				return fmt.Errorf("fault rule %d latency: %s", i, err)
+			}
+			switch l.Distribution {
+			case "", LatencyFixed, LatencyUniform, LatencyNormal, LatencyExponential:
+			default:
+				return fmt.Errorf("fault rule %d latency: unknown distribution %q", i, l.Distribution)
+			}
+		}
+
+		total := 0.0
+		for _, e := range rule.Errors {
+			if err := validatePercent(e.Percent); err != nil {
+				return fmt.Errorf("fault rule %d %s: %s", i, e.Kind, err)
+			}
+			switch e.Kind {
+			case FaultTimeout, FaultConnectionReset:
+			case FaultStatus:
+				if e.StatusCode < 100 || e.StatusCode > 599 {
+					return fmt.Errorf("fault rule %d status: invalid status code %d", i, e.StatusCode)
+				}
+			default:
+				return fmt.Errorf("fault rule %d: unknown error kind %q", i, e.Kind)
+			}
+			total += e.Percent
+		}
+		if total > 100 {
+			return fmt.Errorf("fault rule %d: errors add up to %g percent", i, total)
+		}
+
+		if t := rule.Truncate; t != nil {
+			if err := validatePercent(t.Percent); err != nil {
+				return fmt.Errorf("fault rule %d truncate: %s", i, err)
+			}
+			if t.Bytes < 0 {
+				return fmt.Errorf("fault rule %d truncate: negative bytes %d", i, t.Bytes)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePercent checks that a share is within 0 and 100
+func validatePercent(percent float64) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("percent %g is not within 0 and 100", percent)
+	}
+
+	return nil
+}
+
+// roll tells whether a draw falls within the share
+func roll(percent float64) bool {
+	return rand.Float64()*100 < percent
+}
+
+// sample draws a latency from the distribution
+func (l *LatencyFault) sample() time.Duration {
+	ms := float64(l.MeanMS)
+	switch l.Distribution {
+	case LatencyUniform:
+		ms = float64(l.MinMS) + rand.Float64()*float64(l.MaxMS-l.MinMS)
+	case LatencyNormal:
+		ms = float64(l.MeanMS) + rand.NormFloat64()*float64(l.StdDevMS)
+	case LatencyExponential:
+		ms = rand.ExpFloat64() * float64(l.MeanMS)
+	}
+
+	if ms < 0 {
+		ms = 0
+	}
+	if l.MaxMS > 0 && ms > float64(l.MaxMS) {
+		ms = float64(l.MaxMS)
+	}
+
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// faultPlan holds the faults drawn for one attempt
+type faultPlan struct {
+	kinds    []FaultKind
+	latency  time.Duration
+	err      *ErrorFault
+	truncate *TruncateFault
+}
+
+// name joins the kinds of the faults, e.g. latency+timeout
+func (p *faultPlan) name() string {
+	names := make([]string, len(p.kinds))
+	for i, kind := range p.kinds {
+		names[i] = string(kind)
+	}
+
+	return strings.Join(names, "+")
+}
+
+// planFault draws the faults of the attempt and marks its spans
+func (c *Client) planFault() *faultPlan {
+	c.fault = ""
+	if c.faults == nil {
+		return nil
+	}
+
+	plan := c.faults.plan(c.calledService, c.routeMask)
+	if plan == nil {
+		return nil
+	}
+
+	c.fault = plan.name()
+	c.logger.WithFields(map[string]interface{}{
+		"type":  NAME,
+		"fault": c.fault,
+	}).Debug("injecting fault")
+	if c.otelSpan != nil {
+		c.otelSpan.SetAttributes(otelFaultKey.String(c.fault))
+	}
+	if c.openTracingSpan != nil {
+		c.openTracingSpan.SetTag(otFaultTag, c.fault)
+	}
+
+	return plan
+}
+
+// send sends the request, injecting the planned faults.  Latency past
+// the client timeout fails the request with a timeout, as it would for
+// a slow endpoint.
+func (c *Client) send(ctx context.Context, request *http.Request, plan *faultPlan) (*http.Response, error) {
+	if plan == nil {
+		return c.client.Do(request)
+	}
+
+	if plan.latency > 0 {
+		wait, timedOut := plan.latency, false
+		if timeout := c.client.Timeout; timeout > 0 && wait >= timeout {
+			wait, timedOut = timeout, true
+		}
+
+		timer := c.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, faultURLError(request, ctx.Err())
+		}
+		if timedOut {
+			return nil, faultURLError(request, &FaultError{Kind: FaultTimeout, err: errFaultTimeout})
+		}
+	}
+
+	if e := plan.err; e != nil {
+		switch e.Kind {
+		case FaultTimeout:
+			return nil, faultURLError(request, &FaultError{Kind: FaultTimeout, err: errFaultTimeout})
+		case FaultConnectionReset:
+			return nil, faultURLError(request, &FaultError{Kind: FaultConnectionReset, err: syscall.ECONNRESET})
+		case FaultStatus:
+			return &http.Response{
+				Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+				StatusCode:    e.StatusCode,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        e.header(),
+				Body:          ioutil.NopCloser(strings.NewReader(e.Body)),
+				ContentLength: int64(len(e.Body)),
+				Request:       request,
+			}, nil
+		}
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil || plan.truncate == nil {
+		return response, err
+	}
+
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	keep := plan.truncate.Bytes
+	if keep == 0 {
+		keep = len(body) / 2
+	}
+	if keep < len(body) {
+		body = body[:keep]
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	response.ContentLength = int64(len(body))
+	response.Header.Del("Content-Length")
+	response.Header.Set(faultHeader, string(FaultTruncatedBody))
+
+	return response, nil
+}
+
+// faultURLError wraps the error as http.Client.Do does
+func faultURLError(request *http.Request, err error) error {
+	op := request.Method
+	if len(op) > 1 {
+		op = op[:1] + strings.ToLower(op[1:])
+	}
+
+	return &url.Error{Op: op, URL: request.URL.String(), Err: err}
+}
@@ -0,0 +1,331 @@
+package blaster
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fault injection", func() {
+	var (
+		ctx      context.Context
+		server   *httptest.Server
+		received int32
+		injector *FaultInjector
+	)
+
+	// inject returns an enabled injector with a single rule
+	inject := func(rule FaultRule) *FaultInjector {
+		f, err := NewFaultInjector(FaultConfig{Enabled: true, Rules: []FaultRule{rule}})
+		Expect(err).To(BeNil())
+		return f
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		atomic.StoreInt32(&received, 0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&received, 1)
+			w.Header().Set(contentTypeHeader, jsonType)
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"name":"joel"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		pkgFaultInjector = nil
+	})
+
+	// region faults
+
+	It("answers with the chosen status code and body without sending the request", func() {
+		injector = inject(FaultRule{Errors: []ErrorFault{{Percent: 100, Kind: FaultStatus, StatusCode: 503, Body: `{"error":"chaos"}`}}})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector, KeepRawResponse: true})
+
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(string(c.RawResponse())).To(Equal(`{"error":"chaos"}`))
+		Expect(c.Response().Fault).To(Equal("status"))
+		Expect(c.Response().Header.Get(faultHeader)).To(Equal("status"))
+		Expect(atomic.LoadInt32(&received)).To(Equal(int32(0)))
+	})
+
+	It("decodes a JSON status body into the error prototype", func() {
+		injector = inject(FaultRule{Errors: []ErrorFault{{Percent: 100, Kind: FaultStatus, StatusCode: 503, Body: `{"error":"chaos"}`}}})
+		var result, failure struct {
+			Error string `json:"error"`
+		}
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector, WillSaturate: &result, WillSaturateOnError: &failure})
+
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(c.statusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(c.StatusCodeIsError()).To(BeTrue())
+		Expect(failure.Error).To(Equal("chaos"))
+		Expect(c.Response().Header.Get(contentTypeHeader)).To(Equal(jsonType))
+	})
+
+	It("answers with the configured content type", func() {
+		injector = inject(FaultRule{Errors: []ErrorFault{{Percent: 100, Kind: FaultStatus, StatusCode: 502, Body: "bad gateway", ContentType: "text/plain"}}})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector})
+
+		statusCode, _ := c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusBadGateway))
+		Expect(c.Response().Header.Get(contentTypeHeader)).To(Equal("text/plain"))
+	})
+
+	It("fails with a timeout", func() {
+		injector = inject(FaultRule{Errors: []ErrorFault{{Percent: 100, Kind: FaultTimeout}}})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector})
+
+		_, err := c.Get(ctx)
+		Expect(err).ToNot(BeNil())
+		netErr, ok := err.(net.Error)
+		Expect(ok).To(BeTrue())
+		Expect(netErr.Timeout()).To(BeTrue())
+		var faultErr *FaultError
+		Expect(errors.As(err, &faultErr)).To(BeTrue())
+		Expect(faultErr.Kind).To(Equal(FaultTimeout))
+		Expect(metricErrorKind(err)).To(Equal(MetricErrorTimeout))
+	})
+
+	It("fails with a connection reset", func() {
+		injector = inject(FaultRule{Errors: []ErrorFault{{Percent: 100, Kind: FaultConnectionReset}}})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector})
+
+		_, err := c.Get(ctx)
+		Expect(errors.Is(err, syscall.ECONNRESET)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("injected fault"))
+		Expect(c.Response().Fault).To(Equal("connection_reset"))
+	})
+
+	It("adds latency before sending the request", func() {
+		injector = inject(FaultRule{Latency: &LatencyFault{Percent: 100, MeanMS: 50}})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector})
+
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(c.Duration()).To(BeNumerically(">=", 50*time.Millisecond))
+		Expect(c.Response().Fault).To(Equal("latency"))
+		Expect(atomic.LoadInt32(&received)).To(Equal(int32(1)))
+	})
+
+	It("stops waiting when the context is done", func() {
+		injector = inject(FaultRule{Latency: &LatencyFault{Percent: 100, MeanMS: 10000}})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector})
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err := c.Get(ctx)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(c.Duration()).To(BeNumerically("<", time.Second))
+	})
+
+	It("times out when the latency exceeds the client timeout", func() {
+		injector = inject(FaultRule{Latency: &LatencyFault{Percent: 100, MeanMS: 10000}})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector, TimeoutMS: 20})
+
+		_, err := c.Get(ctx)
+		Expect(metricErrorKind(err)).To(Equal(MetricErrorTimeout))
+		Expect(c.Duration()).To(BeNumerically("<", time.Second))
+		Expect(atomic.LoadInt32(&received)).To(Equal(int32(0)))
+	})
+
+	It("truncates the response body", func() {
+		injector = inject(FaultRule{Truncate: &TruncateFault{Percent: 100, Bytes: 5}})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector, KeepRawResponse: true})
+
+		var got map[string]string
+		c.WillSaturate(&got)
+		_, err := c.Get(ctx)
+		Expect(err).ToNot(BeNil())
+		Expect(string(c.RawResponse())).To(BeEmpty())
+		Expect(string(c.Response().Body)).To(Equal(`{"nam`))
+		Expect(c.Response().Fault).To(Equal("truncated_body"))
+	})
+
+	It("combines latency with an error", func() {
+		injector = inject(FaultRule{
+			Latency: &LatencyFault{Percent: 100, MeanMS: 1},
+			Errors:  []ErrorFault{{Percent: 100, Kind: FaultTimeout}},
+		})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector})
+
+		c.Get(ctx)
+		Expect(c.Response().Fault).To(Equal("latency+timeout"))
+	})
+
+	// endregion
+
+	// region rules
+
+	It("only injects faults into the matching service and route", func() {
+		injector = inject(FaultRule{
+			CalledService: "user-service",
+			Route:         "/v1/users/{id}",
+			Errors:        []ErrorFault{{Percent: 100, Kind: FaultStatus, StatusCode: 500}},
+		})
+
+		c, _ := New(ClientOptions{Endpoint: server.URL, CalledService: "user-service", RouteMask: "/v1/users/{id}", FaultInjector: injector})
+		statusCode, _ := c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusInternalServerError))
+
+		c, _ = New(ClientOptions{Endpoint: server.URL, CalledService: "user-service", RouteMask: "/v1/teams", FaultInjector: injector})
+		statusCode, _ = c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(c.Response().Fault).To(BeEmpty())
+
+		c, _ = New(ClientOptions{Endpoint: server.URL, CalledService: "team-service", RouteMask: "/v1/users/{id}", FaultInjector: injector})
+		statusCode, _ = c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusOK))
+	})
+
+	It("never injects faults with a zero percent share", func() {
+		injector = inject(FaultRule{
+			Latency:  &LatencyFault{Percent: 0, MeanMS: 1000},
+			Errors:   []ErrorFault{{Percent: 0, Kind: FaultTimeout}},
+			Truncate: &TruncateFault{Percent: 0},
+		})
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: injector})
+
+		for i := 0; i < 20; i++ {
+			statusCode, err := c.Get(ctx)
+			Expect(err).To(BeNil())
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(c.Response().Fault).To(BeEmpty())
+		}
+	})
+
+	It("rejects invalid configurations", func() {
+		for _, rule := range []FaultRule{
+			{Latency: &LatencyFault{Percent: 101}},
+			{Latency: &LatencyFault{Percent: 10, Distribution: "pareto"}},
+			{Errors: []ErrorFault{{Percent: 10, Kind: "explode"}}},
+			{Errors: []ErrorFault{{Percent: 10, Kind: FaultStatus}}},
+			{Errors: []ErrorFault{{Percent: 60, Kind: FaultTimeout}, {Percent: 60, Kind: FaultConnectionReset}}},
+			{Truncate: &TruncateFault{Percent: 10, Bytes: -1}},
+		} {
+			_, err := NewFaultInjector(FaultConfig{Enabled: true, Rules: []FaultRule{rule}})
+			Expect(err).ToNot(BeNil())
+		}
+	})
+
+	It("keeps latencies within the bounds of the distribution", func() {
+		uniform := &LatencyFault{Distribution: LatencyUniform, MinMS: 10, MaxMS: 20}
+		normal := &LatencyFault{Distribution: LatencyNormal, MeanMS: 10, StdDevMS: 50, MaxMS: 30}
+		exponential := &LatencyFault{Distribution: LatencyExponential, MeanMS: 10, MaxMS: 40}
+		for i := 0; i < 100; i++ {
+			Expect(uniform.sample()).To(BeNumerically("~", 15*time.Millisecond, 5*time.Millisecond))
+			Expect(normal.sample()).To(BeNumerically("<=", 30*time.Millisecond))
+			Expect(normal.sample()).To(BeNumerically(">=", 0))
+			Expect(exponential.sample()).To(BeNumerically("<=", 40*time.Millisecond))
+		}
+		Expect((&LatencyFault{MeanMS: 7}).sample()).To(Equal(7 * time.Millisecond))
+	})
+
+	// endregion
+
+	// region runtime
+
+	It("is toggled at runtime", func() {
+		injector = inject(FaultRule{Errors: []ErrorFault{{Percent: 100, Kind: FaultStatus, StatusCode: 502}}})
+		pkgFaultInjector = injector
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+
+		statusCode, _ := c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusBadGateway))
+
+		injector.SetEnabled(false)
+		Expect(injector.Enabled()).To(BeFalse())
+		statusCode, _ = c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(c.Response().Fault).To(BeEmpty())
+
+		Expect(injector.Update(FaultConfig{Enabled: true, Rules: []FaultRule{{
+			Errors: []ErrorFault{{Percent: 100, Kind: FaultStatus, StatusCode: 504}},
+		}}})).To(Succeed())
+		statusCode, _ = c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusGatewayTimeout))
+	})
+
+	It("reloads the configuration file when it changes", func() {
+		dir, _ := ioutil.TempDir("", "faults")
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "faults.yaml")
+		ioutil.WriteFile(path, []byte("enabled: true\nrules:\n  - errors:\n      - percent: 100\n        kind: status\n        status_code: 503\n"), 0644)
+
+		f, err := NewFaultInjectorFromFile(path, time.Millisecond)
+		Expect(err).To(BeNil())
+		c, _ := New(ClientOptions{Endpoint: server.URL, FaultInjector: f})
+		statusCode, _ := c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusServiceUnavailable))
+
+		ioutil.WriteFile(path, []byte("enabled: false\n"), 0644)
+		time.Sleep(5 * time.Millisecond)
+		statusCode, _ = c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusOK))
+
+		ioutil.WriteFile(path, []byte("enabled: [broken"), 0644)
+		time.Sleep(5 * time.Millisecond)
+		statusCode, _ = c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(f.Enabled()).To(BeFalse())
+	})
+
+	// endregion
+
+	// region reporting
+
+	It("tags injected faults in metrics, spans and hook events", func() {
+		injector = inject(FaultRule{Errors: []ErrorFault{{Percent: 100, Kind: FaultStatus, StatusCode: 503}}})
+		metrics := &recordingMetrics{}
+		statsd := &fakes.FakeStatsdClientPrototype{}
+		tracer := &recordingTracer{}
+		pkgOTelTracerProvider = &recordingProvider{tracer: tracer}
+		defer func() { pkgOTelTracerProvider = nil }()
+		var events []Event
+
+		c, _ := New(ClientOptions{
+			Endpoint:      server.URL,
+			FaultInjector: injector,
+			Metrics:       metrics,
+			Hooks:         &Hooks{OnResponse: func(e Event) { events = append(events, e) }},
+		})
+		c.SetStatsdDelegate(statsd, "api", nil)
+		c.Get(ctx)
+
+		Expect(metrics.durations).To(HaveLen(1))
+		Expect(metrics.durations[0].Fault).To(Equal("status"))
+		_, _, tags, _ := statsd.TimingArgsForCall(0)
+		Expect(tags).To(ContainElement("fault:status"))
+		Expect(tracer.spans).To(HaveLen(1))
+		Expect(tracer.spans[0].attrs["blaster.fault"].AsString()).To(Equal("status"))
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Fault).To(Equal("status"))
+
+		injector.SetEnabled(false)
+		c.Get(ctx)
+		Expect(metrics.durations[1].Fault).To(BeEmpty())
+		_, _, tags, _ = statsd.TimingArgsForCall(statsd.TimingCallCount() - 1)
+		Expect(tags).ToNot(ContainElement("fault:status"))
+	})
+
+	// endregion
+})
@@ -0,0 +1,325 @@
+package blaster
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/InVisionApp/go-logger"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+	defaultHealthyThreshold    = 2
+	defaultUnhealthyThreshold  = 3
+)
+
+// HealthCheck configures active health checking of the endpoints of a
+// called service.  Zero values fall back to the defaults.
+type HealthCheck struct {
+	// Path is requested on each endpoint.  Any 2XX response is a success.
+	Path string
+
+	// Interval is the time between probes.  Defaults to 10 seconds.
+	Interval time.Duration
+
+	// Timeout is the max amount of time for a single probe.  Defaults to 2 seconds.
+	Timeout time.Duration
+
+	// HealthyThreshold is the number of successes in a row that mark
+	// an unhealthy endpoint healthy again.  Defaults to 2.
+	HealthyThreshold int
+
+	// UnhealthyThreshold is the number of failures in a row that mark
+	// an endpoint unhealthy.  Defaults to 3.
+	UnhealthyThreshold int
+
+	// Logger receives health transitions.  Defaults to a no-op logger.
+	Logger log.Logger
+
+	// Transport sends the probes instead of the transport of the default
+	// http client, e.g. the ClientOptions.Transport of the clients of the
+	// service
+	Transport http.RoundTripper
+
+	// Clock ticks the probe interval.  Defaults to Defaults.Clock.
+	Clock Clock
+}
+
+// healthState is what the checker knows about a single endpoint
+type healthState struct {
+	unhealthy bool
+	successes int
+	failures  int
+}
+
+// HealthChecker probes the endpoints of a called service in the background.
+// Endpoints start out healthy, and are only left out of balancing once
+// they fail enough probes in a row.  A single checker should be shared by
+// all clients of the called service.
+type HealthChecker struct {
+	config   HealthCheck
+	resolver EndpointResolver
+	service  string
+	client   *http.Client
+
+	mu    sync.RWMutex
+	state map[string]*healthState
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewHealthChecker returns a checker for the endpoints the resolver returns
+// for the service.  Probing does not begin until Start is called.
+func NewHealthChecker(resolver EndpointResolver, service string, config HealthCheck) *HealthChecker {
+	if config.Interval <= 0 {
+		config.Interval = defaultHealthCheckInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHealthCheckTimeout
+	}
+	if config.HealthyThreshold <= 0 {
+		config.HealthyThreshold = defaultHealthyThreshold
+	}
+	if config.UnhealthyThreshold <= 0 {
+		config.UnhealthyThreshold = defaultUnhealthyThreshold
+	}
+	if config.Logger == nil {
+		config.Logger = log.NewNoop()
+	}
+
+	ensurePackageVariables()
+
+	// under MOCKING_HTTP the http client is the shared http.DefaultClient,
+	// so the probes go through a copy
+	client := *newHTTPClient()
+	client.Timeout = config.Timeout
+	if config.Transport != nil {
+		client.Transport = config.Transport
+	}
+
+	return &HealthChecker{
+		config:   config,
+		resolver: resolver,
+		service:  service,
+		client:   &client,
+		state:    map[string]*healthState{},
+	}
+}
+
+// Start begins probing in the background.  The first round of probes is
+// sent right away.  Probing runs until Stop is called or ctx is done, and
+// the checker can be started again after either.
+func (h *HealthChecker) Start(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.done != nil {
+		return errors.New("health checker already started")
+	}
+
+	h.stop = make(chan struct{})
+	h.done = make(chan struct{})
+
+	go h.run(ctx, h.stop, h.done)
+
+	return nil
+}
+
+// Stop ends probing and waits for any probes in flight to finish, so no
+// probe is sent once it returns.  It is safe to call Stop more than once,
+// and to Start again afterwards.
+func (h *HealthChecker) Stop() {
+	h.mu.Lock()
+	stop, done := h.stop, h.done
+	h.stop, h.done = nil, nil
+	h.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+	if h.config.Transport == nil {
+		h.client.CloseIdleConnections()
+	}
+}
+
+// Healthy determines whether the endpoint is currently considered healthy
+func (h *HealthChecker) Healthy(ep *Endpoint) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	s, ok := h.state[ep.URL.String()]
+	return !ok || !s.unhealthy
+}
+
+// Available filters out the unhealthy endpoints.  If every endpoint
+// is unhealthy, all of them are returned rather than none.
+func (h *HealthChecker) Available(candidates []*Endpoint) []*Endpoint {
+	available := make([]*Endpoint, 0, len(candidates))
+	for _, ep := range candidates {
+		if h.Healthy(ep) {
+			available = append(available, ep)
+		}
+	}
+
+	if len(available) == 0 {
+		return candidates
+	}
+
+	return available
+}
+
+// run probes on every interval until stopped or ctx is done
+func (h *HealthChecker) run(ctx context.Context, stop, done chan struct{}) {
+	defer func() {
+		// let the checker start again when ctx ended the run
+		h.mu.Lock()
+		if h.done == done {
+			h.stop, h.done = nil, nil
+		}
+		h.mu.Unlock()
+		close(done)
+	}()
+
+	ticker := clockOrDefault(h.config.Clock).NewTicker(h.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		h.probeAll(ctx)
+
+		select {
+		case <-ticker.C():
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// probeAll probes every endpoint of the service at once, and waits for
+// them.  The state of endpoints the resolver no longer returns is forgotten.
+func (h *HealthChecker) probeAll(ctx context.Context) {
+	endpoints, err := h.resolver.Resolve(ctx, h.service)
+	if err != nil {
+		h.config.Logger.WithFields(map[string]interface{}{
+			"error_message":  err.Error(),
+			"type":           NAME,
+			"called_service": h.service,
+		}).Warn("health check could not resolve endpoints")
+		return
+	}
+	h.prune(endpoints)
+
+	var wg sync.WaitGroup
+	for _, ep := range endpoints {
+		wg.Add(1)
+		go func(ep *Endpoint) {
+			defer wg.Done()
+			h.record(ep, h.probe(ctx, ep))
+		}(ep)
+	}
+	wg.Wait()
+}
+
+// prune drops the state of the endpoints that are not among the current ones
+func (h *HealthChecker) prune(endpoints []*Endpoint) {
+	current := make(map[string]bool, len(endpoints))
+	for _, ep := range endpoints {
+		current[ep.URL.String()] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key := range h.state {
+		if !current[key] {
+			delete(h.state, key)
+		}
+	}
+}
+
+// probe sends a single health check to the endpoint
+func (h *HealthChecker) probe(ctx context.Context, ep *Endpoint) error {
+	target := joinEndpoint(ep.URL, &url.URL{Path: h.config.Path})
+	request, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set(userAgentHeader, pkgUserAgent)
+	request.Header.Set(callingServiceHeader, pkgServiceName)
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return errors.New(response.Status)
+	}
+
+	return nil
+}
+
+// record applies the outcome of a probe to the endpoint
+func (h *HealthChecker) record(ep *Endpoint, probeErr error) {
+	// a probe cut short by the end of the Start context says nothing
+	// about the endpoint
+	if probeErr != nil && errors.Is(probeErr, context.Canceled) {
+		return
+	}
+
+	h.mu.Lock()
+	s, ok := h.state[ep.URL.String()]
+	if !ok {
+		s = &healthState{}
+		h.state[ep.URL.String()] = s
+	}
+
+	var changed bool
+	if probeErr == nil {
+		s.successes++
+		s.failures = 0
+		if s.unhealthy && s.successes >= h.config.HealthyThreshold {
+			s.unhealthy = false
+			changed = true
+		}
+	} else {
+		s.failures++
+		s.successes = 0
+		if !s.unhealthy && s.failures >= h.config.UnhealthyThreshold {
+			s.unhealthy = true
+			changed = true
+		}
+	}
+	unhealthy := s.unhealthy
+	h.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"type":           NAME,
+		"endpoint":       ep.URL.Host,
+		"called_service": h.service,
+	}
+	if unhealthy {
+		fields["error_message"] = probeErr.Error()
+		h.config.Logger.WithFields(fields).Warn("endpoint marked unhealthy")
+	} else {
+		h.config.Logger.WithFields(fields).Info("endpoint marked healthy")
+	}
+}
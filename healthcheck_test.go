@@ -0,0 +1,196 @@
+package blaster
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// countingTransport counts the requests it sends
+type countingTransport struct {
+	requests int32
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// changingResolver resolves to endpoints that can be changed
+type changingResolver struct {
+	mu        sync.Mutex
+	endpoints []*Endpoint
+}
+
+func (r *changingResolver) Resolve(ctx context.Context, service string) ([]*Endpoint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.endpoints, nil
+}
+
+func (r *changingResolver) set(endpoints []*Endpoint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endpoints = endpoints
+}
+
+var _ = Describe("HealthChecker", func() {
+	var (
+		ctx      context.Context
+		sick     int32
+		probes   int32
+		server   *httptest.Server
+		healthy  *httptest.Server
+		checker  *HealthChecker
+		resolver EndpointResolver
+		logger   *testlog.TestLogger
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		atomic.StoreInt32(&sick, 0)
+		atomic.StoreInt32(&probes, 0)
+		logger = testlog.New()
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/health" {
+				atomic.AddInt32(&probes, 1)
+				if atomic.LoadInt32(&sick) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		healthy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+
+		ep, _ := NewEndpoint(server.URL, 1)
+		other, _ := NewEndpoint(healthy.URL, 1)
+		resolver = NewStaticResolver(ep, other)
+		checker = NewHealthChecker(resolver, "user-service", HealthCheck{
+			Path:               "/health",
+			Interval:           5 * time.Millisecond,
+			HealthyThreshold:   2,
+			UnhealthyThreshold: 2,
+			Logger:             logger,
+		})
+	})
+
+	AfterEach(func() {
+		checker.Stop()
+		server.Close()
+		healthy.Close()
+	})
+
+	// region lifecycle
+	Describe("lifecycle", func() {
+		It("probes until stopped", func() {
+			Expect(checker.Start(ctx)).To(Succeed())
+			Eventually(func() int32 { return atomic.LoadInt32(&probes) }).Should(BeNumerically(">=", 3))
+
+			checker.Stop()
+			stopped := atomic.LoadInt32(&probes)
+			Consistently(func() int32 { return atomic.LoadInt32(&probes) }).Should(Equal(stopped))
+		})
+		It("refuses to start twice", func() {
+			Expect(checker.Start(ctx)).To(Succeed())
+			Expect(checker.Start(ctx)).ToNot(Succeed())
+		})
+		It("can be started again after a stop", func() {
+			Expect(checker.Start(ctx)).To(Succeed())
+			checker.Stop()
+			checker.Stop()
+			Expect(checker.Start(ctx)).To(Succeed())
+		})
+		It("does not leak goroutines", func() {
+			before := runtime.NumGoroutine()
+			for i := 0; i < 5; i++ {
+				Expect(checker.Start(ctx)).To(Succeed())
+				time.Sleep(10 * time.Millisecond)
+				checker.Stop()
+			}
+			Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", before))
+		})
+		It("stops when the context is done, and can be started again", func() {
+			startCtx, cancel := context.WithCancel(ctx)
+			Expect(checker.Start(startCtx)).To(Succeed())
+			cancel()
+			Eventually(func() error { return checker.Start(ctx) }).Should(Succeed())
+		})
+		It("sends the probes through the transport", func() {
+			transport := &countingTransport{}
+			checker = NewHealthChecker(resolver, "user-service", HealthCheck{Path: "/health", Transport: transport})
+			Expect(checker.Start(ctx)).To(Succeed())
+			checker.Stop()
+			Expect(atomic.LoadInt32(&transport.requests)).To(Equal(int32(2)))
+		})
+	})
+	// endregion
+
+	// region health
+	Describe("health", func() {
+		It("marks endpoints unhealthy and healthy again", func() {
+			endpoints, _ := resolver.Resolve(ctx, "user-service")
+			Expect(checker.Start(ctx)).To(Succeed())
+
+			atomic.StoreInt32(&sick, 1)
+			Eventually(func() bool { return checker.Healthy(endpoints[0]) }).Should(BeFalse())
+			Expect(checker.Available(endpoints)).To(Equal(endpoints[1:]))
+			checker.Stop()
+			Expect(string(logger.Bytes())).To(ContainSubstring("endpoint marked unhealthy"))
+
+			atomic.StoreInt32(&sick, 0)
+			Expect(checker.Start(ctx)).To(Succeed())
+			Eventually(func() bool { return checker.Healthy(endpoints[0]) }).Should(BeTrue())
+			checker.Stop()
+			Expect(string(logger.Bytes())).To(ContainSubstring("endpoint marked healthy"))
+		})
+		It("only balances across healthy endpoints", func() {
+			atomic.StoreInt32(&sick, 1)
+			Expect(checker.Start(ctx)).To(Succeed())
+			endpoints, _ := resolver.Resolve(ctx, "user-service")
+			Eventually(func() bool { return checker.Healthy(endpoints[0]) }).Should(BeFalse())
+
+			for i := 0; i < 4; i++ {
+				c, err := New(ClientOptions{
+					Endpoint:         "/",
+					EndpointResolver: resolver,
+					HealthChecker:    checker,
+				})
+				Expect(err).To(BeNil())
+				code, err := c.Get(ctx)
+				Expect(err).To(BeNil())
+				Expect(code).To(Equal(http.StatusAccepted))
+			}
+		})
+		It("forgets the endpoints the resolver no longer returns", func() {
+			endpoints, _ := resolver.Resolve(ctx, "user-service")
+			changing := &changingResolver{endpoints: endpoints}
+			checker = NewHealthChecker(changing, "user-service", HealthCheck{Path: "/health", Interval: 5 * time.Millisecond})
+			Expect(checker.Start(ctx)).To(Succeed())
+
+			stateSize := func() int {
+				checker.mu.RLock()
+				defer checker.mu.RUnlock()
+				return len(checker.state)
+			}
+			Eventually(stateSize).Should(Equal(2))
+
+			changing.set(endpoints[1:])
+			Eventually(stateSize).Should(Equal(1))
+			Expect(checker.Healthy(endpoints[1])).To(BeTrue())
+		})
+	})
+	// endregion
+})
@@ -0,0 +1,293 @@
+package blaster
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/InVisionApp/go-logger"
+)
+
+// defaultHookQueueSize is the size of the queue of asynchronous hooks
+const defaultHookQueueSize = 256
+
+// EventErrorBreaker is the error class of requests rejected by the
+// circuit breaker.  Other errors are classed as the MetricError kinds.
+const EventErrorBreaker = "breaker"
+
+// BreakerState tells what the circuit breaker did with a request
+type BreakerState string
+
+const (
+	// BreakerNone means the client has no circuit breaker
+	BreakerNone BreakerState = "none"
+	// BreakerAllowed means the circuit breaker let the request through
+	BreakerAllowed BreakerState = "allowed"
+	// BreakerRejected means the circuit breaker rejected the request
+	BreakerRejected BreakerState = "rejected"
+)
+
+// Event describes a request at one point of its lifecycle.  Hooks receive
+// a copy, so changing it has no effect on the request.
+type Event struct {
+	// Method is the http method
+	Method string
+
+	// URL is the url of the attempt, or the client endpoint before an
+	// endpoint was selected
+	URL string
+
+	// RouteMask is the templated route of the request
+	RouteMask string
+
+	// CalledService is the service the request is sent to
+	CalledService string
+
+	// RequestID is the Request-ID header of the request
+	RequestID string
+
+	// Attempt is the number of the attempt, starting at 1.  It is 0 for
+	// requests that could not be launched.
+	Attempt int
+
+	// StatusCode is the status code of the response, or 0 if no response
+	// was received
+	StatusCode int
+
+	// Duration is the length of time the attempt took for OnRetry, and
+	// the whole request took for OnResponse and OnError
+	Duration time.Duration
+
+	// ErrorClass is one of the MetricError kinds, or EventErrorBreaker
+	ErrorClass string
+
+	// Err is the error the attempt or request failed with
+	Err error
+
+	// Breaker tells what the circuit breaker did with the request
+	Breaker BreakerState
+
+	// Fault names the faults injected into the attempt, if any, see
+	// FaultInjector
+	Fault string
+}
+
+// Hooks are callbacks for the lifecycle events of requests.  Hooks run
+// synchronously by default, and a panicking hook is recovered and logged.
+// With Async set they run one at a time on a separate goroutine, fed by a
+// queue of QueueSize events; events are dropped when the queue is full.
+// Hooks should be shared by all clients, and Close must be called when
+// they are async.
+type Hooks struct {
+	// OnRequest is called before each attempt is sent
+	OnRequest func(Event)
+
+	// OnResponse is called once a response was received and read,
+	// whatever its status code
+	OnResponse func(Event)
+
+	// OnError is called when the request could not be launched, no
+	// response was received, the response could not be processed, or
+	// the circuit breaker rejected the request
+	OnError func(Event)
+
+	// OnRetry is called when an attempt could not reach its endpoint and
+	// the request fails over to the next one
+	OnRetry func(Event)
+
+	// Async runs the hooks on a separate goroutine
+	Async bool
+
+	// QueueSize is the number of events that can wait for an async hook.
+	// Defaults to 256.
+	QueueSize int
+
+	once    sync.Once
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan hookCall
+	done    chan struct{}
+	dropped int64
+}
+
+// hookCall is a hook waiting to be run
+type hookCall struct {
+	name   string
+	hook   func(Event)
+	event  Event
+	logger log.Logger
+}
+
+// Dropped returns the number of events dropped because the queue was full
+// or the hooks were closed
+func (h *Hooks) Dropped() int64 {
+	return atomic.LoadInt64(&h.dropped)
+}
+
+// Close stops the async hooks once the queued events have been handled.
+// Later events are dropped.
+func (h *Hooks) Close() {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.closed = true
+	started := h.queue != nil
+	if started {
+		close(h.queue)
+	}
+	h.mu.Unlock()
+
+	if started {
+		<-h.done
+	}
+}
+
+// start creates the queue and the goroutine that drains it
+func (h *Hooks) start() {
+	size := h.QueueSize
+	if size <= 0 {
+		size = defaultHookQueueSize
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+
+	h.queue = make(chan hookCall, size)
+	h.done = make(chan struct{})
+	go func() {
+		defer close(h.done)
+		for call := range h.queue {
+			call.run()
+		}
+	}()
+}
+
+// dispatch runs the hook, or queues it when the hooks are async
+func (h *Hooks) dispatch(name string, hook func(Event), event Event, logger log.Logger) {
+	if hook == nil {
+		return
+	}
+
+	call := hookCall{name: name, hook: hook, event: event, logger: logger}
+	if !h.Async {
+		call.run()
+		return
+	}
+
+	h.once.Do(h.start)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		atomic.AddInt64(&h.dropped, 1)
+		return
+	}
+
+	select {
+	case h.queue <- call:
+	default:
+		atomic.AddInt64(&h.dropped, 1)
+	}
+}
+
+// run calls the hook and recovers from a panic
+func (call hookCall) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			call.logger.WithFields(map[string]interface{}{
+				"error_message": fmt.Sprint(r),
+				"type":          NAME,
+				"hook":          call.name,
+			}).Error("hook panicked")
+		}
+	}()
+
+	call.hook(call.event)
+}
+
+// event describes the request for the hooks
+func (c *Client) event(attempt int) Event {
+	e := Event{
+		Method:        c.method,
+		URL:           c.endpoint.String(),
+		RouteMask:     c.routeMask,
+		CalledService: c.calledService,
+		RequestID:     c.headers[requestIDHeader],
+		Attempt:       attempt,
+		Breaker:       BreakerNone,
+		Fault:         c.fault,
+	}
+	if c.selectedEndpoint != nil {
+		e.URL = c.selectedEndpoint.String()
+	}
+	if c.cb != nil {
+		e.Breaker = BreakerAllowed
+	}
+
+	return e
+}
+
+// hookRequest runs the OnRequest hook before an attempt
+func (c *Client) hookRequest(attempt int) {
+	if c.hooks != nil && c.hooks.OnRequest != nil {
+		c.hooks.dispatch("OnRequest", c.hooks.OnRequest, c.event(attempt), c.logger)
+	}
+}
+
+// hookRetry runs the OnRetry hook after an attempt that failed over
+func (c *Client) hookRetry(attempt int, duration time.Duration, err error) {
+	if c.hooks == nil || c.hooks.OnRetry == nil {
+		return
+	}
+
+	e := c.event(attempt)
+	e.Duration = duration
+	e.ErrorClass = metricErrorKind(err)
+	e.Err = err
+	c.hooks.dispatch("OnRetry", c.hooks.OnRetry, e, c.logger)
+}
+
+// hookOutcome runs the OnResponse or OnError hook once the request is done
+func (c *Client) hookOutcome() {
+	if c.hooks == nil {
+		return
+	}
+
+	var attempt int
+	if c.response != nil {
+		attempt = c.response.Attempts
+	}
+	e := c.event(attempt)
+	e.Duration = c.duration
+	if c.response != nil {
+		e.StatusCode = c.response.StatusCode
+	}
+
+	if c.requestErr != nil {
+		e.ErrorClass = c.errorClass
+		e.Err = c.requestErr
+		c.hooks.dispatch("OnError", c.hooks.OnError, e, c.logger)
+		return
+	}
+	c.hooks.dispatch("OnResponse", c.hooks.OnResponse, e, c.logger)
+}
+
+// hookBreakerRejection runs the OnError hook for a request the circuit
+// breaker rejected
+func (c *Client) hookBreakerRejection(err error) {
+	if c.hooks == nil {
+		return
+	}
+
+	e := c.event(0)
+	e.ErrorClass = EventErrorBreaker
+	e.Err = err
+	e.Breaker = BreakerRejected
+	c.hooks.dispatch("OnError", c.hooks.OnError, e, c.logger)
+}
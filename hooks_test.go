@@ -0,0 +1,217 @@
+package blaster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	"github.com/joelhill/go-rest-http-blaster/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hooks", func() {
+	var (
+		ctx    context.Context
+		server *httptest.Server
+		mu     sync.Mutex
+		events map[string][]Event
+		hooks  *Hooks
+	)
+
+	// record returns a hook that keeps the events under the given name
+	record := func(name string) func(Event) {
+		return func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			events[name] = append(events[name], e)
+		}
+	}
+
+	// deadEndpoints returns urls nothing listens on
+	deadEndpoints := func(n int) []string {
+		var dead []string
+		for i := 0; i < n; i++ {
+			l, _ := net.Listen("tcp", "127.0.0.1:0")
+			dead = append(dead, "http://"+l.Addr().String())
+			l.Close()
+		}
+		return dead
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		events = map[string][]Event{}
+		hooks = &Hooks{
+			OnRequest:  record("request"),
+			OnResponse: record("response"),
+			OnError:    record("error"),
+			OnRetry:    record("retry"),
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// region sync
+
+	It("reports a request and its response", func() {
+		pkgRequestIDProviderFunc = func(ctx context.Context) (string, bool) { return "req-1", true }
+		defer func() { pkgRequestIDProviderFunc = nil }()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/users/1", RouteMask: "/users/:id", CalledService: "users", Hooks: hooks})
+		c.Get(ctx)
+
+		Expect(events["request"]).To(HaveLen(1))
+		Expect(events["request"][0].Attempt).To(Equal(1))
+		Expect(events["request"][0].URL).To(Equal(server.URL + "/users/1"))
+
+		Expect(events["response"]).To(HaveLen(1))
+		e := events["response"][0]
+		Expect(e.Method).To(Equal(http.MethodGet))
+		Expect(e.RouteMask).To(Equal("/users/:id"))
+		Expect(e.CalledService).To(Equal("users"))
+		Expect(e.RequestID).To(Equal("req-1"))
+		Expect(e.Attempt).To(Equal(1))
+		Expect(e.StatusCode).To(Equal(http.StatusTeapot))
+		Expect(e.Duration).To(Equal(c.Duration()))
+		Expect(e.Breaker).To(Equal(BreakerNone))
+		Expect(e.Err).To(BeNil())
+
+		Expect(events["error"]).To(BeEmpty())
+		Expect(events["retry"]).To(BeEmpty())
+	})
+
+	It("reports retries and the final error", func() {
+		c, _ := New(ClientOptions{Endpoint: "/", Endpoints: deadEndpoints(2), Hooks: hooks})
+		c.Get(ctx)
+
+		Expect(events["request"]).To(HaveLen(2))
+		Expect(events["retry"]).To(HaveLen(1))
+		Expect(events["retry"][0].Attempt).To(Equal(1))
+		Expect(events["retry"][0].ErrorClass).To(Equal(MetricErrorConnection))
+		Expect(events["retry"][0].Err).ToNot(BeNil())
+
+		Expect(events["error"]).To(HaveLen(1))
+		Expect(events["error"][0].Attempt).To(Equal(2))
+		Expect(events["error"][0].StatusCode).To(BeZero())
+		Expect(events["error"][0].ErrorClass).To(Equal(MetricErrorConnection))
+		Expect(events["response"]).To(BeEmpty())
+	})
+
+	It("reports requests that could not be launched", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Hooks: hooks})
+		c.Post(ctx, func() {})
+
+		Expect(events["request"]).To(BeEmpty())
+		Expect(events["error"]).To(HaveLen(1))
+		Expect(events["error"][0].Attempt).To(BeZero())
+		Expect(events["error"][0].ErrorClass).To(Equal(MetricErrorRequest))
+	})
+
+	It("reports the response of a client reused after an error", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Hooks: hooks})
+		c.Post(ctx, func() {})
+		c.Get(ctx)
+
+		Expect(events["error"]).To(HaveLen(1))
+		Expect(events["response"]).To(HaveLen(1))
+		Expect(events["response"][0].StatusCode).To(Equal(http.StatusTeapot))
+		Expect(events["response"][0].Err).To(BeNil())
+	})
+
+	It("reports what the circuit breaker did", func() {
+		cb := &fakes.FakeCircuitBreakerPrototype{}
+		cb.ExecuteStub = func(f func() (interface{}, error)) (interface{}, error) {
+			return f()
+		}
+		c, _ := New(ClientOptions{Endpoint: server.URL, Hooks: hooks, CircuitBreaker: cb})
+		c.Get(ctx)
+		Expect(events["response"][0].Breaker).To(Equal(BreakerAllowed))
+
+		cb.ExecuteStub = nil
+		cb.ExecuteReturns(nil, errors.New("breaker open"))
+		c, _ = New(ClientOptions{Endpoint: server.URL, Hooks: hooks, CircuitBreaker: cb})
+		c.Get(ctx)
+		Expect(events["error"]).To(HaveLen(1))
+		Expect(events["error"][0].Breaker).To(Equal(BreakerRejected))
+		Expect(events["error"][0].ErrorClass).To(Equal(EventErrorBreaker))
+		Expect(events["error"][0].Err).To(MatchError("breaker open"))
+	})
+
+	It("recovers from a panicking hook", func() {
+		logger := testlog.New()
+		hooks.OnResponse = func(Event) { panic("boom") }
+		c, _ := New(ClientOptions{Endpoint: server.URL, Hooks: hooks, Logger: logger})
+
+		statusCode, _ := c.Get(ctx)
+		Expect(statusCode).To(Equal(http.StatusTeapot))
+		Expect(string(logger.Bytes())).To(ContainSubstring("hook panicked"))
+		Expect(string(logger.Bytes())).To(ContainSubstring("hook=OnResponse"))
+	})
+
+	It("uses the package defaults", func() {
+		pkgHooks = hooks
+		defer func() { pkgHooks = nil }()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.Get(ctx)
+		Expect(events["response"]).To(HaveLen(1))
+	})
+
+	// endregion
+
+	// region async
+
+	It("runs async hooks on a separate goroutine", func() {
+		hooks.Async = true
+		c, _ := New(ClientOptions{Endpoint: server.URL, Hooks: hooks})
+		c.Get(ctx)
+		hooks.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(events["request"]).To(HaveLen(1))
+		Expect(events["response"]).To(HaveLen(1))
+		Expect(hooks.Dropped()).To(BeZero())
+	})
+
+	It("drops events when the queue is full", func() {
+		release := make(chan struct{})
+		hooks.Async = true
+		hooks.QueueSize = 1
+		hooks.OnRequest = func(Event) { <-release }
+		c, _ := New(ClientOptions{Endpoint: server.URL, Hooks: hooks})
+
+		// the first event blocks the worker, the second fills the queue
+		c.Get(ctx)
+		c.Get(ctx)
+		c.Get(ctx)
+		close(release)
+		hooks.Close()
+
+		Expect(hooks.Dropped()).To(BeNumerically(">=", 1))
+	})
+
+	It("drops events once closed", func() {
+		hooks.Async = true
+		hooks.Close()
+		hooks.Close()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL, Hooks: hooks})
+		c.Get(ctx)
+		Expect(hooks.Dropped()).To(Equal(int64(2)))
+		Expect(events).To(BeEmpty())
+	})
+
+	// endregion
+})
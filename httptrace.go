@@ -0,0 +1,227 @@
+package blaster
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Timings breaks the last attempt of a request down into its network
+// phases.  Phases that did not happen are zero, e.g. DNS, Connect and TLS
+// when an idle connection was reused.
+type Timings struct {
+	// DNS is the time spent resolving the host
+	DNS time.Duration
+
+	// Connect is the time spent establishing the TCP connection
+	Connect time.Duration
+
+	// TLS is the time spent on the TLS handshake
+	TLS time.Duration
+
+	// TimeToFirstByte is the time from the start of the attempt
+	// to the first byte of the response
+	TimeToFirstByte time.Duration
+
+	// ServerProcessing is the time from the request being written
+	// to the first byte of the response
+	ServerProcessing time.Duration
+
+	// BodyRead is the time spent reading the response body
+	BodyRead time.Duration
+
+	// ConnReused tells whether an idle connection was reused
+	ConnReused bool
+}
+
+// timingPhase is a named phase with its start, for reporting
+type timingPhase struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+}
+
+// timingRecorder collects the httptrace callbacks of a single attempt.
+// The callbacks may come from other goroutines, e.g. when dialing
+// several addresses at once, hence the lock.
+type timingRecorder struct {
+	mu           sync.Mutex
+	clock        Clock
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wrote        time.Time
+	firstByte    time.Time
+	bodyStart    time.Time
+	bodyDone     time.Time
+	reused       bool
+}
+
+// traceTimings returns the request with an httptrace attached that
+// records its timings into a new recorder on the client
+func (c *Client) traceTimings(request *http.Request) *http.Request {
+	r := &timingRecorder{clock: c.clock, start: c.clock.Now()}
+	c.timings = r
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { r.stamp(&r.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { r.stamp(&r.dnsDone) },
+		ConnectStart: func(network, addr string) {
+			r.stampFirst(&r.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				r.stamp(&r.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() { r.stamp(&r.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { r.stamp(&r.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mu.Lock()
+			r.reused = info.Reused
+			r.mu.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { r.stamp(&r.wrote) },
+		GotFirstResponseByte: func() { r.stamp(&r.firstByte) },
+	}
+
+	return request.WithContext(httptrace.WithClientTrace(request.Context(), trace))
+}
+
+// stamp records the current time
+func (r *timingRecorder) stamp(t *time.Time) {
+	r.mu.Lock()
+	*t = r.clock.Now()
+	r.mu.Unlock()
+}
+
+// stampFirst records the current time, unless a time was already recorded
+func (r *timingRecorder) stampFirst(t *time.Time) {
+	r.mu.Lock()
+	if t.IsZero() {
+		*t = r.clock.Now()
+	}
+	r.mu.Unlock()
+}
+
+// body records when reading the response body started and finished
+func (r *timingRecorder) body(start, done time.Time) {
+	r.mu.Lock()
+	r.bodyStart, r.bodyDone = start, done
+	r.mu.Unlock()
+}
+
+// between returns the time from start to end, or zero if either is missing
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+// phases returns the phases that happened, in order
+func (r *timingRecorder) phases() []timingPhase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	all := []timingPhase{
+		{"dns", r.dnsStart, between(r.dnsStart, r.dnsDone)},
+		{"connect", r.connectStart, between(r.connectStart, r.connectDone)},
+		{"tls", r.tlsStart, between(r.tlsStart, r.tlsDone)},
+		{"ttfb", r.start, between(r.start, r.firstByte)},
+		{"server_processing", r.wrote, between(r.wrote, r.firstByte)},
+		{"body_read", r.bodyStart, between(r.bodyStart, r.bodyDone)},
+	}
+
+	phases := all[:0]
+	for _, phase := range all {
+		if phase.duration > 0 {
+			phases = append(phases, phase)
+		}
+	}
+
+	return phases
+}
+
+// result returns the recorded timings
+func (r *timingRecorder) result() Timings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return Timings{
+		DNS:              between(r.dnsStart, r.dnsDone),
+		Connect:          between(r.connectStart, r.connectDone),
+		TLS:              between(r.tlsStart, r.tlsDone),
+		TimeToFirstByte:  between(r.start, r.firstByte),
+		ServerProcessing: between(r.wrote, r.firstByte),
+		BodyRead:         between(r.bodyStart, r.bodyDone),
+		ConnReused:       r.reused,
+	}
+}
+
+// Timings returns the network timings of the last attempt
+func (c *Client) Timings() Timings {
+	if c.timings == nil {
+		return Timings{}
+	}
+
+	return c.timings.result()
+}
+
+// reportTimings emits the timings of the current attempt as statsd timings,
+// span events and a debug log line
+func (c *Client) reportTimings() {
+	if c.timings == nil {
+		return
+	}
+
+	phases := c.timings.phases()
+	reused := c.timings.result().ConnReused
+
+	if c.statsdClient != nil {
+		tags := c.tagsWith(
+			fmt.Sprintf("http-verb:%s", c.method),
+			fmt.Sprintf("called-service:%s", c.calledService),
+			fmt.Sprintf("route:%s", c.routeMask),
+			fmt.Sprintf("conn-reused:%t", reused),
+		)
+		for _, phase := range phases {
+			c.statsdClient.Timing(fmt.Sprintf("%s.%s", c.statsdStat, phase.name), phase.duration, tags, pkgStatsdRate)
+		}
+	}
+
+	if c.otelSpan != nil {
+		for _, phase := range phases {
+			c.otelSpan.AddEvent(phase.name,
+				oteltrace.WithTimestamp(phase.start),
+				oteltrace.WithAttributes(attribute.Int64("duration_us", phase.duration.Microseconds())),
+			)
+		}
+	}
+
+	if c.openTracingSpan != nil {
+		for _, phase := range phases {
+			c.openTracingSpan.LogKV("event", phase.name, "duration_us", phase.duration.Microseconds())
+		}
+	}
+
+	fields := map[string]interface{}{
+		"type":        NAME,
+		"conn_reused": reused,
+	}
+	for _, phase := range phases {
+		fields[phase.name] = phase.duration.String()
+	}
+	c.logger.WithFields(fields).Debug("request timings")
+}
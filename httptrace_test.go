@@ -0,0 +1,134 @@
+package blaster
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	"github.com/joelhill/go-rest-http-blaster/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Timings", func() {
+	var (
+		ctx    context.Context
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+			w.Header().Set("X-Answer", "42")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("hello"))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("records each phase of a new connection", func() {
+		c, _ := New(ClientOptions{Endpoint: strings.Replace(server.URL, "127.0.0.1", "localhost", 1)})
+		_, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+
+		timings := c.Timings()
+		Expect(timings.DNS).To(BeNumerically(">", 0))
+		Expect(timings.Connect).To(BeNumerically(">", 0))
+		Expect(timings.TLS).To(BeZero())
+		Expect(timings.ServerProcessing).To(BeNumerically(">=", 20*time.Millisecond))
+		Expect(timings.TimeToFirstByte).To(BeNumerically(">=", timings.ServerProcessing))
+		Expect(timings.BodyRead).To(BeNumerically(">", 0))
+		Expect(timings.ConnReused).To(BeFalse())
+	})
+
+	It("records the TLS handshake", func() {
+		tlsServer := httptest.NewTLSServer(server.Config.Handler)
+		defer tlsServer.Close()
+
+		c, _ := New(ClientOptions{Endpoint: tlsServer.URL})
+		c.client = tlsServer.Client()
+		_, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(c.Timings().TLS).To(BeNumerically(">", 0))
+	})
+
+	It("tells when a connection was reused", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.Get(ctx)
+		c.Get(ctx)
+
+		timings := c.Timings()
+		Expect(timings.ConnReused).To(BeTrue())
+		Expect(timings.Connect).To(BeZero())
+		Expect(timings.TimeToFirstByte).To(BeNumerically(">", 0))
+	})
+
+	It("exposes the timings on the response", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		Expect(c.Response()).To(BeNil())
+		c.Get(ctx)
+
+		response := c.Response()
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.Header.Get("X-Answer")).To(Equal("42"))
+		Expect(response.Body).To(Equal([]byte("hello")))
+		Expect(response.Endpoint.String()).To(Equal(server.URL))
+		Expect(response.Attempts).To(Equal(1))
+		Expect(response.Duration).To(Equal(c.Duration()))
+		Expect(response.Timings.TimeToFirstByte).To(BeNumerically(">", 0))
+	})
+
+	It("keeps the timings of a failed request", func() {
+		l, _ := net.Listen("tcp", "127.0.0.1:0")
+		dead := "http://" + l.Addr().String()
+		l.Close()
+
+		c, _ := New(ClientOptions{Endpoint: dead})
+		_, err := c.Get(ctx)
+		Expect(err).ToNot(BeNil())
+
+		response := c.Response()
+		Expect(response).ToNot(BeNil())
+		Expect(response.StatusCode).To(BeZero())
+		Expect(response.Timings.TimeToFirstByte).To(BeZero())
+	})
+
+	It("emits statsd timings for each phase", func() {
+		statsd := &fakes.FakeStatsdClientPrototype{}
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.SetStatsdDelegate(statsd, "api", []string{"env:test"})
+		c.Get(ctx)
+
+		names := map[string][]string{}
+		for i := 0; i < statsd.TimingCallCount(); i++ {
+			name, _, tags, _ := statsd.TimingArgsForCall(i)
+			names[name] = tags
+		}
+		Expect(names).To(HaveKey("api"))
+		Expect(names).To(HaveKey("api.connect"))
+		Expect(names).To(HaveKey("api.ttfb"))
+		Expect(names).To(HaveKey("api.server_processing"))
+		Expect(names).To(HaveKey("api.body_read"))
+		Expect(names).ToNot(HaveKey("api.tls"))
+		Expect(names["api.ttfb"]).To(ContainElement("conn-reused:false"))
+		Expect(names["api.ttfb"]).To(ContainElement("env:test"))
+	})
+
+	It("logs the timings at debug level", func() {
+		logger := testlog.New()
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger})
+		c.Get(ctx)
+
+		Expect(string(logger.Bytes())).To(ContainSubstring("request timings"))
+		Expect(string(logger.Bytes())).To(ContainSubstring("ttfb"))
+	})
+})
@@ -2,12 +2,18 @@ package blaster
 
 import (
 	"context"
+	"net/url"
 	"time"
+
+	"github.com/InVisionApp/go-logger"
 )
 
 //go:generate counterfeiter -o ./fakes/fake_circuitbreaker_prototype.go . CircuitBreakerPrototype
 //go:generate counterfeiter -o ./fakes/fake_statsd_client_prototype.go . StatsdClientPrototype
-//go:generate counterfeiter -o ./fakes/fake_client.go . IClient
+
+// The IClient fake refers to types of this package, so it lives in a
+// package of its own that the tests of this package do not import.
+//go:generate counterfeiter -o ./fakes/fakeclient/fake_client.go . IClient
 
 // CircuitBreakerPrototype defines the circuit breaker Execute function signature
 type CircuitBreakerPrototype interface {
@@ -20,24 +26,89 @@ type StatsdClientPrototype interface {
 	Timing(name string, value time.Duration, tags []string, rate float64) error
 }
 
+// Metrics records request metrics.  Implementations must be safe for
+// concurrent use, since a single instance is normally shared by every
+// client.  See NewStatsdMetrics and NewPrometheusMetrics.
+//
+// The client records everything but CacheHit.  It does not cache
+// responses, so callers that serve a request from their own cache
+// record the hit themselves.
+type Metrics interface {
+	RequestDuration(labels MetricLabels, duration time.Duration)
+	RequestSize(labels MetricLabels, bytes int)
+	ResponseSize(labels MetricLabels, bytes int)
+	InFlight(labels MetricLabels, delta int)
+	Error(labels MetricLabels, kind string)
+	Retry(labels MetricLabels)
+	BreakerRejection(labels MetricLabels)
+
+	// CacheHit is never called by the client, see above
+	CacheHit(labels MetricLabels)
+}
+
+// ContractMetrics is implemented by Metrics that count contract
+// violations, see ContractValidation.  Direction is request or response.
+type ContractMetrics interface {
+	ContractViolation(labels MetricLabels, direction string)
+}
+
+// EndpointResolver resolves a called service to the set of endpoints
+// that are eligible to receive its requests
+type EndpointResolver interface {
+	Resolve(ctx context.Context, service string) ([]*Endpoint, error)
+}
+
+// EndpointWatcher is implemented by resolvers that can publish changes
+// to the endpoints of a service as they happen.  The channel is closed
+// once the context is done.
+type EndpointWatcher interface {
+	Watch(ctx context.Context, service string) (<-chan []*Endpoint, error)
+}
+
+// BalancerSource is implemented by resolvers that keep the balancers of
+// their endpoints, so the balancing state lives and dies with the resolver.
+// The same balancer is returned for the same strategy.
+type BalancerSource interface {
+	Balancer(strategy BalancingStrategy) Balancer
+}
+
+// Balancer chooses the endpoint for the next request from a set of candidates
+type Balancer interface {
+	Pick(ctx context.Context, candidates []*Endpoint) (*Endpoint, error)
+}
+
 // IClient - interface for the cb api client
 type IClient interface {
 	Delete(ctx context.Context, payload interface{}) (int, error)
-	Duration() time.Duration
 	Do(ctx context.Context, method string, payload interface{}) (int, error)
+	Duration() time.Duration
 	Get(ctx context.Context) (int, error)
 	KeepRawResponse()
+	Patch(ctx context.Context, payload interface{}) (int, error)
 	Post(ctx context.Context, payload interface{}) (int, error)
 	Put(ctx context.Context, payload interface{}) (int, error)
-	Patch(ctx context.Context, payload interface{}) (int, error)
 	RawResponse() []byte
+	Request() *Request
+	Response() *Response
+	SelectedEndpoint() *url.URL
 	SetCircuitBreaker(cb CircuitBreakerPrototype)
-	SetStatsdDelegate(sdClient StatsdClientPrototype, stat string, tags []string)
+	SetClock(clock Clock)
 	SetContentType(ct string)
+	SetContractValidation(validation *ContractValidation)
+	SetEndpointResolver(resolver EndpointResolver, balancer Balancer)
+	SetFaultInjector(injector *FaultInjector)
 	SetHeader(key string, value string)
+	SetHealthChecker(checker *HealthChecker)
+	SetHooks(hooks *Hooks)
+	SetLogger(logger log.Logger)
+	SetMetrics(metrics Metrics)
 	SetNRTxnName(name string)
-	SetTimeoutMS(timeout time.Duration)
+	SetOutlierDetector(detector *OutlierDetector)
+	SetRequestLogging(logging *RequestLogging)
+	SetStatsdDelegate(sdClient StatsdClientPrototype, stat string, tags []string)
+	SetTimeoutMS(timeout int)
 	StatusCodeIsError() bool
+	Timings() Timings
 	WillSaturate(proto interface{})
 	WillSaturateOnError(proto interface{})
 	WillSaturateWithStatusCode(statusCode int, proto interface{})
@@ -0,0 +1,227 @@
+package blaster
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/InVisionApp/go-logger"
+)
+
+// LogLevel is the level a request is logged at
+type LogLevel int
+
+const (
+	// LogDebug logs at debug level
+	LogDebug LogLevel = iota + 1
+	// LogInfo logs at info level
+	LogInfo
+	// LogWarn logs at warn level
+	LogWarn
+	// LogError logs at error level
+	LogError
+	// LogOff does not log
+	LogOff
+)
+
+// defaultMaxLoggedBody is how much of a body is logged by default
+const defaultMaxLoggedBody = 4096
+
+// RequestLogging configures a structured log line for every request that
+// was launched.  The line holds the method, templated url, status,
+// duration, sizes and request id, plus the chosen headers and bodies.
+// Levels that are not set fall back to their defaults, use LogOff to
+// leave an outcome out.
+type RequestLogging struct {
+	// SuccessLevel is used for 1xx, 2xx and 3xx responses.  Defaults to LogDebug.
+	SuccessLevel LogLevel
+
+	// ClientErrorLevel is used for 4xx responses.  Defaults to LogWarn.
+	ClientErrorLevel LogLevel
+
+	// ServerErrorLevel is used for 5xx responses.  Defaults to LogError.
+	ServerErrorLevel LogLevel
+
+	// TransportErrorLevel is used when no response was received.  Defaults to LogError.
+	TransportErrorLevel LogLevel
+
+	// SlowThreshold raises the level of requests that take longer, to at
+	// least SlowLevel.  Zero turns it off.
+	SlowThreshold time.Duration
+
+	// SlowLevel defaults to LogWarn
+	SlowLevel LogLevel
+
+	// Headers are the request and response headers to log, or "*" for all
+	Headers []string
+
+	// RequestBody and ResponseBody log the bodies, up to MaxBodySize bytes
+	RequestBody  bool
+	ResponseBody bool
+
+	// MaxBodySize defaults to 4096 bytes
+	MaxBodySize int
+
+	// Redaction blanks out secrets in the logged headers and bodies
+	Redaction Redaction
+
+	// CurlOnFailure adds the request as a curl command to the line of
+	// requests that failed or returned a 5xx, see Request.AsCurl
+	CurlOnFailure bool
+}
+
+// levelFor picks the level for the outcome of a request
+func (l *RequestLogging) levelFor(statusCode int, err error, duration time.Duration) LogLevel {
+	level := orLevel(l.SuccessLevel, LogDebug)
+	switch {
+	case err != nil && statusCode == 0:
+		level = orLevel(l.TransportErrorLevel, LogError)
+	case statusCode >= http.StatusInternalServerError:
+		level = orLevel(l.ServerErrorLevel, LogError)
+	case statusCode >= http.StatusBadRequest:
+		level = orLevel(l.ClientErrorLevel, LogWarn)
+	}
+
+	if l.SlowThreshold > 0 && duration >= l.SlowThreshold {
+		if slow := orLevel(l.SlowLevel, LogWarn); level == LogOff || slow > level {
+			level = slow
+		}
+	}
+
+	return level
+}
+
+// orLevel returns level, or fallback if level is not set
+func orLevel(level, fallback LogLevel) LogLevel {
+	if level == 0 {
+		return fallback
+	}
+
+	return level
+}
+
+// logsHeader tells whether the header is chosen for logging
+func (l *RequestLogging) logsHeader(name string) bool {
+	for _, header := range l.Headers {
+		if header == "*" || strings.EqualFold(header, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// headerFields returns the chosen headers, redacted
+func (l *RequestLogging) headerFields(h http.Header) map[string]string {
+	fields := map[string]string{}
+	for name, values := range h {
+		if l.logsHeader(name) {
+			fields[name] = l.Redaction.header(name, strings.Join(values, ", "))
+		}
+	}
+
+	return fields
+}
+
+// body returns a body for logging, redacted and truncated
+func (l *RequestLogging) body(body []byte) string {
+	max := l.MaxBodySize
+	if max == 0 {
+		max = defaultMaxLoggedBody
+	}
+
+	return truncateBody(l.Redaction.body(body), max)
+}
+
+// templatedURL is the url with the route mask in place of the path, so
+// that ids and query strings stay out of the logs
+func (c *Client) templatedURL() string {
+	target := c.selectedEndpoint
+	if target == nil {
+		target = c.endpoint
+	}
+
+	path := c.routeMask
+	if path == "" {
+		path = target.Path
+	}
+
+	return fmt.Sprintf("%s://%s%s", target.Scheme, target.Host, path)
+}
+
+// logRequest writes the structured log line for a request that was launched
+func (c *Client) logRequest() {
+	l := c.requestLogging
+	if l == nil || c.response == nil {
+		return
+	}
+
+	level := l.levelFor(c.response.StatusCode, c.requestErr, c.duration)
+	if level == LogOff {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"type":           NAME,
+		"method":         c.method,
+		"url":            c.templatedURL(),
+		"status":         c.response.StatusCode,
+		"duration_ms":    float64(c.duration) / float64(time.Millisecond),
+		"request_size":   len(c.requestBody),
+		"response_size":  len(c.response.Body),
+		"attempts":       c.response.Attempts,
+		"called_service": c.calledService,
+	}
+	if requestID := c.headers[requestIDHeader]; requestID != "" {
+		fields["request_id"] = requestID
+	}
+	if c.requestErr != nil {
+		fields["error_message"] = c.requestErr.Error()
+	}
+	if c.fault != "" {
+		fields["fault"] = c.fault
+	}
+	if l.SlowThreshold > 0 && c.duration >= l.SlowThreshold {
+		fields["slow"] = true
+	}
+	if len(l.Headers) > 0 {
+		// the headers of the last attempt as it was sent, including those
+		// added from the context, the tracers and the failover attempt
+		sent := http.Header{}
+		if c.request != nil {
+			sent = c.request.Header
+		} else {
+			for k, v := range c.headers {
+				sent.Set(k, v)
+			}
+		}
+		fields["request_headers"] = l.headerFields(sent)
+		fields["response_headers"] = l.headerFields(c.response.Header)
+	}
+	if l.RequestBody && len(c.requestBody) > 0 {
+		fields["request_body"] = l.body(c.requestBody)
+	}
+	if l.ResponseBody && len(c.response.Body) > 0 {
+		fields["response_body"] = l.body(c.response.Body)
+	}
+	if l.CurlOnFailure && c.request != nil && (c.requestErr != nil || c.response.StatusCode >= http.StatusInternalServerError) {
+		fields["curl"] = c.request.AsCurl()
+	}
+
+	logAt(c.logger.WithFields(fields), level, fmt.Sprintf("%s %s %d", c.method, c.templatedURL(), c.response.StatusCode))
+}
+
+// logAt logs the message at the given level
+func logAt(logger log.Logger, level LogLevel, msg string) {
+	switch level {
+	case LogDebug:
+		logger.Debug(msg)
+	case LogInfo:
+		logger.Info(msg)
+	case LogWarn:
+		logger.Warn(msg)
+	case LogError:
+		logger.Error(msg)
+	}
+}
@@ -0,0 +1,262 @@
+package blaster
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/opentracing/opentracing-go"
+)
+
+var _ = Describe("RequestLogging", func() {
+	var (
+		ctx    context.Context
+		logger *testlog.TestLogger
+		server *httptest.Server
+		status int
+		delay  time.Duration
+	)
+
+	// requestLine returns the structured line logged for the request
+	requestLine := func() string {
+		for _, line := range strings.Split(string(logger.Bytes()), "\n") {
+			if strings.Contains(line, "duration_ms=") {
+				return line
+			}
+		}
+		return ""
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		logger = testlog.New()
+		status = http.StatusOK
+		delay = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.Header().Set("Set-Cookie", "session=secret")
+			w.Header().Set("X-Answer", "42")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"token":"abc","items":[{"card":{"number":"4111"}},{"card":{"number":"4242"}}],"name":"joel"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// region levels
+
+	It("does not log without a configuration", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger})
+		c.Get(ctx)
+		Expect(requestLine()).To(BeEmpty())
+	})
+
+	It("logs successes at debug level with the request summary", func() {
+		pkgRequestIDProviderFunc = func(ctx context.Context) (string, bool) { return "req-1", true }
+		defer func() { pkgRequestIDProviderFunc = nil }()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/users/42", RouteMask: "/users/:id", Logger: logger, RequestLogging: &RequestLogging{}})
+		c.Get(ctx)
+
+		line := requestLine()
+		Expect(line).To(HavePrefix("[DEBUG] GET " + server.URL + "/users/:id 200"))
+		Expect(line).To(ContainSubstring("method=GET"))
+		Expect(line).To(ContainSubstring("status=200"))
+		Expect(line).To(ContainSubstring("request_id=req-1"))
+		Expect(line).To(ContainSubstring("attempts=1"))
+		Expect(line).To(ContainSubstring("type=" + NAME))
+		Expect(line).ToNot(ContainSubstring("/users/42"))
+	})
+
+	It("logs client errors at warn level", func() {
+		status = http.StatusNotFound
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{}})
+		c.Get(ctx)
+		Expect(requestLine()).To(HavePrefix("[WARN]"))
+	})
+
+	It("logs server errors at error level", func() {
+		status = http.StatusBadGateway
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{}})
+		c.Get(ctx)
+		Expect(requestLine()).To(HavePrefix("[ERROR]"))
+	})
+
+	It("logs transport errors at error level with the error message", func() {
+		l, _ := net.Listen("tcp", "127.0.0.1:0")
+		dead := "http://" + l.Addr().String()
+		l.Close()
+
+		c, _ := New(ClientOptions{Endpoint: dead, Logger: logger, RequestLogging: &RequestLogging{}})
+		c.Get(ctx)
+
+		line := requestLine()
+		Expect(line).To(HavePrefix("[ERROR]"))
+		Expect(line).To(ContainSubstring("status=0"))
+		Expect(line).To(ContainSubstring("error_message="))
+	})
+
+	It("does not carry the error of a previous request over", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{}})
+		_, err := c.Post(ctx, func() {})
+		Expect(err).ToNot(BeNil())
+
+		_, err = c.Get(ctx)
+		Expect(err).To(BeNil())
+		line := requestLine()
+		Expect(line).To(HavePrefix("[DEBUG]"))
+		Expect(line).ToNot(ContainSubstring("error_message="))
+	})
+
+	It("honours the configured levels", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{SuccessLevel: LogInfo}})
+		c.Get(ctx)
+		Expect(requestLine()).To(HavePrefix("[INFO]"))
+	})
+
+	It("leaves out outcomes that are turned off", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{SuccessLevel: LogOff}})
+		c.Get(ctx)
+		Expect(requestLine()).To(BeEmpty())
+	})
+
+	It("raises the level of slow requests", func() {
+		delay = 20 * time.Millisecond
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{
+			SuccessLevel:  LogOff,
+			SlowThreshold: 10 * time.Millisecond,
+		}})
+		c.Get(ctx)
+
+		line := requestLine()
+		Expect(line).To(HavePrefix("[WARN]"))
+		Expect(line).To(ContainSubstring("slow=true"))
+	})
+
+	It("does not lower the level of slow requests", func() {
+		delay = 20 * time.Millisecond
+		status = http.StatusInternalServerError
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{SlowThreshold: 10 * time.Millisecond}})
+		c.Get(ctx)
+		Expect(requestLine()).To(HavePrefix("[ERROR]"))
+	})
+
+	It("uses the package defaults", func() {
+		pkgRequestLogging = &RequestLogging{SuccessLevel: LogInfo}
+		defer func() { pkgRequestLogging = nil }()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger})
+		c.Get(ctx)
+		Expect(requestLine()).To(HavePrefix("[INFO]"))
+	})
+
+	// endregion
+
+	// region headers and bodies
+
+	It("logs the chosen headers with secrets redacted", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{
+			Headers:   []string{"Authorization", "Cookie", "X-Tenant", "Set-Cookie", "X-Answer"},
+			Redaction: Redaction{Headers: []string{"X-Tenant"}},
+		}})
+		c.SetHeader("Authorization", "Bearer secret")
+		c.SetHeader("Cookie", "session=secret")
+		c.SetHeader("X-Tenant", "acme")
+		c.SetHeader("X-Other", "skipped")
+		c.Get(ctx)
+
+		line := requestLine()
+		Expect(line).To(ContainSubstring("Authorization:" + redactedValue))
+		Expect(line).To(ContainSubstring("Cookie:" + redactedValue))
+		Expect(line).To(ContainSubstring("X-Tenant:" + redactedValue))
+		Expect(line).To(ContainSubstring("Set-Cookie:" + redactedValue))
+		Expect(line).To(ContainSubstring("X-Answer:42"))
+		Expect(line).ToNot(ContainSubstring("secret"))
+		Expect(line).ToNot(ContainSubstring("acme"))
+		Expect(line).ToNot(ContainSubstring("X-Other"))
+	})
+
+	It("logs the headers of the request as it was sent", func() {
+		pkgTracerProviderFunc = func(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span) {
+			r.Header.Set("X-B3-Traceid", "trace-1")
+			r.Header.Set("X-Tenant", "overridden")
+			return r, nil
+		}
+		defer func() { pkgTracerProviderFunc = nil }()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{
+			Headers: []string{"X-B3-Traceid", "X-Tenant"},
+		}})
+		c.SetHeader("X-Tenant", "acme")
+		c.Get(ctx)
+
+		line := requestLine()
+		Expect(line).To(ContainSubstring("X-B3-Traceid:trace-1"))
+		Expect(line).To(ContainSubstring("X-Tenant:overridden"))
+		Expect(line).ToNot(ContainSubstring("acme"))
+	})
+
+	It("logs the bodies with fields redacted by path", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{
+			RequestBody:  true,
+			ResponseBody: true,
+			Redaction:    Redaction{Fields: []string{"password", "token", "items.*.number"}},
+		}})
+		c.Post(ctx, map[string]string{"user": "joel", "password": "hunter2"})
+
+		line := requestLine()
+		Expect(line).To(ContainSubstring(`"password":"` + redactedValue + `"`))
+		Expect(line).To(ContainSubstring(`"user":"joel"`))
+		Expect(line).To(ContainSubstring(`"token":"` + redactedValue + `"`))
+		Expect(line).To(ContainSubstring(`"name":"joel"`))
+		Expect(line).ToNot(ContainSubstring("hunter2"))
+		Expect(line).ToNot(ContainSubstring("4111"))
+		Expect(line).ToNot(ContainSubstring("4242"))
+	})
+
+	It("truncates long bodies", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{
+			ResponseBody: true,
+			MaxBodySize:  10,
+		}})
+		c.Get(ctx)
+		Expect(requestLine()).To(ContainSubstring(`response_body={"token":"...(`))
+		Expect(requestLine()).To(ContainSubstring("bytes truncated)"))
+	})
+
+	// endregion
+})
+
+var _ = Describe("Redaction", func() {
+	It("returns bodies that are not JSON as is", func() {
+		r := Redaction{Fields: []string{"password"}}
+		Expect(r.body([]byte("password=secret"))).To(Equal([]byte("password=secret")))
+	})
+
+	It("keeps numbers as they were", func() {
+		r := Redaction{Fields: []string{"secret"}}
+		Expect(string(r.body([]byte(`{"id":12345678901234567890,"secret":1}`)))).To(Equal(`{"id":12345678901234567890,"secret":"[REDACTED]"}`))
+	})
+
+	It("copies the headers it redacts", func() {
+		h := http.Header{"Authorization": {"Bearer secret"}, "Accept": {"application/json"}}
+		redacted := Redaction{}.headers(h)
+		Expect(redacted.Get("Authorization")).To(Equal(redactedValue))
+		Expect(redacted.Get("Accept")).To(Equal("application/json"))
+		Expect(h.Get("Authorization")).To(Equal("Bearer secret"))
+	})
+
+	It("leaves short bodies whole", func() {
+		Expect(truncateBody([]byte("short"), 10)).To(Equal("short"))
+		Expect(truncateBody([]byte("0123456789ab"), 10)).To(Equal("0123456789...(2 bytes truncated)"))
+	})
+})
@@ -0,0 +1,131 @@
+package blaster
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// error kinds reported to Metrics.Error
+const (
+	// MetricErrorRequest means the request could not be launched,
+	// e.g. the payload could not be encoded or no endpoint was available
+	MetricErrorRequest = "request"
+
+	// MetricErrorTimeout means the request timed out
+	MetricErrorTimeout = "timeout"
+
+	// MetricErrorConnection means the endpoint could not be reached
+	MetricErrorConnection = "connection"
+
+	// MetricErrorTransport is any other error while sending the
+	// request or receiving the response
+	MetricErrorTransport = "transport"
+
+	// MetricErrorResponse means the response could not be processed
+	MetricErrorResponse = "response"
+
+	// MetricErrorContract means a body broke the contract, see
+	// ContractValidation
+	MetricErrorContract = "contract"
+)
+
+// MetricLabels describes the request a metric is recorded for.  Only low
+// cardinality values are used, the route mask stands in for the path.
+type MetricLabels struct {
+	CalledService string
+	RouteMask     string
+	Method        string
+
+	// StatusCode is the response status code, or 0 when there is none,
+	// e.g. for the in-flight gauge or when the request failed
+	StatusCode int
+
+	// Fault names the faults injected into the request, e.g. latency+timeout,
+	// or is empty for real requests
+	Fault string
+
+	// Tags are the context tags of the request, see WithTags, joined by
+	// commas after the guard on their distinct values.  They are kept as
+	// a string so that the labels can still be compared and used as map
+	// keys.
+	Tags string
+}
+
+// ContextTags returns the context tags of the request, as "key:value"
+func (l MetricLabels) ContextTags() []string {
+	if l.Tags == "" {
+		return nil
+	}
+
+	return strings.Split(l.Tags, ",")
+}
+
+// StatusClass returns the class of the status code, e.g. 2xx.
+// It is "unknown" if there was no response.
+func (l MetricLabels) StatusClass() string {
+	return responseTypeForStatusCode(l.StatusCode)
+}
+
+// metricLabels returns the labels for the current request
+func (c *Client) metricLabels() MetricLabels {
+	return MetricLabels{
+		CalledService: c.calledService,
+		RouteMask:     c.routeMask,
+		Method:        c.method,
+		StatusCode:    c.statusCode,
+		Fault:         c.fault,
+		Tags:          strings.Join(c.contextTags, ","),
+	}
+}
+
+// requestLabels leaves out the status code, for metrics recorded before
+// the response is known.  This keeps in-flight increments and decrements
+// on the same series.
+func (c *Client) requestLabels() MetricLabels {
+	labels := c.metricLabels()
+	labels.StatusCode = 0
+	return labels
+}
+
+// reportMetricError counts an error of the given kind
+func (c *Client) reportMetricError(kind string) {
+	if c.metrics != nil {
+		c.metrics.Error(c.metricLabels(), kind)
+	}
+}
+
+// reportMetricSizes records the request and response body sizes
+func (c *Client) reportMetricSizes(requestSize, responseSize int) {
+	if c.metrics != nil {
+		labels := c.metricLabels()
+		c.metrics.RequestSize(labels, requestSize)
+		c.metrics.ResponseSize(labels, responseSize)
+	}
+}
+
+// reportMetricDuration records the duration of a request that was launched
+func (c *Client) reportMetricDuration() {
+	if c.metrics != nil {
+		c.metrics.RequestDuration(c.metricLabels(), c.duration)
+	}
+}
+
+// metricErrorKind classifies an error returned by the http client,
+// or while processing its response
+func metricErrorKind(err error) string {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return MetricErrorTimeout
+	}
+	if _, ok := err.(*ContractError); ok {
+		return MetricErrorContract
+	}
+	if isConnectionError(err) {
+		return MetricErrorConnection
+	}
+	if _, ok := err.(*url.Error); ok {
+		return MetricErrorTransport
+	}
+
+	return MetricErrorResponse
+}
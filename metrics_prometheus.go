@@ -0,0 +1,236 @@
+package blaster
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prometheus label names
+const (
+	promCalledServiceLabel = "called_service"
+	promRouteLabel         = "route"
+	promMethodLabel        = "method"
+	promStatusClassLabel   = "status_class"
+	promErrorKindLabel     = "kind"
+	promFaultLabel         = "fault"
+	promDirectionLabel     = "direction"
+)
+
+var (
+	promRequestLabels  = []string{promCalledServiceLabel, promRouteLabel, promMethodLabel, promFaultLabel}
+	promStatusLabels   = []string{promCalledServiceLabel, promRouteLabel, promMethodLabel, promFaultLabel, promStatusClassLabel}
+	promErrorLabels    = []string{promCalledServiceLabel, promRouteLabel, promMethodLabel, promFaultLabel, promErrorKindLabel}
+	promContractLabels = []string{promCalledServiceLabel, promRouteLabel, promMethodLabel, promFaultLabel, promDirectionLabel}
+
+	// promSizeBuckets go from 100B to 10MB
+	promSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
+)
+
+// prometheusMetrics reports Metrics as prometheus collectors
+type prometheusMetrics struct {
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inFlight     *prometheus.GaugeVec
+	errors       *prometheus.CounterVec
+	retries      *prometheus.CounterVec
+	rejections   *prometheus.CounterVec
+	cacheHits    *prometheus.CounterVec
+	violations   *prometheus.CounterVec
+}
+
+// NewPrometheusMetrics registers the client collectors with registerer and
+// returns Metrics that record to them.  If registerer is nil, the default
+// registerer is used.  The collectors are named
+// <namespace>_http_client_<metric> and labeled by called service, route
+// mask, method, injected fault and, where it applies, status class.
+// Calling this again with the same registerer and namespace reuses the
+// registered collectors.
+//
+// Prometheus collectors have a fixed set of labels, so the context tags of
+// the requests, see WithTags, are not recorded.
+func NewPrometheusMetrics(registerer prometheus.Registerer, namespace string) (Metrics, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	m := &prometheusMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of outgoing HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, promStatusLabels),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "request_size_bytes",
+			Help:      "Size of outgoing HTTP request bodies.",
+			Buckets:   promSizeBuckets,
+		}, promStatusLabels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "response_size_bytes",
+			Help:      "Size of HTTP response bodies.",
+			Buckets:   promSizeBuckets,
+		}, promStatusLabels),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "requests_in_flight",
+			Help:      "Number of outgoing HTTP requests waiting for a response.",
+		}, promRequestLabels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "errors_total",
+			Help:      "Number of outgoing HTTP requests that failed, by kind of error.",
+		}, promErrorLabels),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "retries_total",
+			Help:      "Number of outgoing HTTP requests that were retried.",
+		}, promRequestLabels),
+		rejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "breaker_rejections_total",
+			Help:      "Number of outgoing HTTP requests rejected by the circuit breaker.",
+		}, promRequestLabels),
+		cacheHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "cache_hits_total",
+			Help:      "Number of outgoing HTTP requests served from a cache.",
+		}, promRequestLabels),
+		violations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http_client",
+			Name:      "contract_violations_total",
+			Help:      "Number of request and response bodies that broke the contract.",
+		}, promContractLabels),
+	}
+
+	var err error
+	if m.duration, err = registerHistogram(registerer, m.duration); err != nil {
+		return nil, err
+	}
+	if m.requestSize, err = registerHistogram(registerer, m.requestSize); err != nil {
+		return nil, err
+	}
+	if m.responseSize, err = registerHistogram(registerer, m.responseSize); err != nil {
+		return nil, err
+	}
+	if m.inFlight, err = registerGauge(registerer, m.inFlight); err != nil {
+		return nil, err
+	}
+	for _, counter := range []**prometheus.CounterVec{&m.errors, &m.retries, &m.rejections, &m.cacheHits, &m.violations} {
+		if *counter, err = registerCounter(registerer, *counter); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// RequestDuration implements Metrics
+func (m *prometheusMetrics) RequestDuration(labels MetricLabels, duration time.Duration) {
+	m.duration.WithLabelValues(promStatusValues(labels)...).Observe(duration.Seconds())
+}
+
+// RequestSize implements Metrics
+func (m *prometheusMetrics) RequestSize(labels MetricLabels, bytes int) {
+	m.requestSize.WithLabelValues(promStatusValues(labels)...).Observe(float64(bytes))
+}
+
+// ResponseSize implements Metrics
+func (m *prometheusMetrics) ResponseSize(labels MetricLabels, bytes int) {
+	m.responseSize.WithLabelValues(promStatusValues(labels)...).Observe(float64(bytes))
+}
+
+// InFlight implements Metrics
+func (m *prometheusMetrics) InFlight(labels MetricLabels, delta int) {
+	m.inFlight.WithLabelValues(promRequestValues(labels)...).Add(float64(delta))
+}
+
+// Error implements Metrics
+func (m *prometheusMetrics) Error(labels MetricLabels, kind string) {
+	m.errors.WithLabelValues(append(promRequestValues(labels), kind)...).Inc()
+}
+
+// Retry implements Metrics
+func (m *prometheusMetrics) Retry(labels MetricLabels) {
+	m.retries.WithLabelValues(promRequestValues(labels)...).Inc()
+}
+
+// BreakerRejection implements Metrics
+func (m *prometheusMetrics) BreakerRejection(labels MetricLabels) {
+	m.rejections.WithLabelValues(promRequestValues(labels)...).Inc()
+}
+
+// CacheHit implements Metrics
+func (m *prometheusMetrics) CacheHit(labels MetricLabels) {
+	m.cacheHits.WithLabelValues(promRequestValues(labels)...).Inc()
+}
+
+// ContractViolation implements ContractMetrics
+func (m *prometheusMetrics) ContractViolation(labels MetricLabels, direction string) {
+	m.violations.WithLabelValues(append(promRequestValues(labels), direction)...).Inc()
+}
+
+// promRequestValues returns the values for promRequestLabels
+func promRequestValues(labels MetricLabels) []string {
+	return []string{labels.CalledService, labels.RouteMask, labels.Method, labels.Fault}
+}
+
+// promStatusValues returns the values for promStatusLabels
+func promStatusValues(labels MetricLabels) []string {
+	return append(promRequestValues(labels), labels.StatusClass())
+}
+
+// registerHistogram registers the collector, or returns the one already registered
+func registerHistogram(registerer prometheus.Registerer, collector *prometheus.HistogramVec) (*prometheus.HistogramVec, error) {
+	existing, err := registerCollector(registerer, collector)
+	if err != nil {
+		return nil, err
+	}
+
+	return existing.(*prometheus.HistogramVec), nil
+}
+
+// registerGauge registers the collector, or returns the one already registered
+func registerGauge(registerer prometheus.Registerer, collector *prometheus.GaugeVec) (*prometheus.GaugeVec, error) {
+	existing, err := registerCollector(registerer, collector)
+	if err != nil {
+		return nil, err
+	}
+
+	return existing.(*prometheus.GaugeVec), nil
+}
+
+// registerCounter registers the collector, or returns the one already registered
+func registerCounter(registerer prometheus.Registerer, collector *prometheus.CounterVec) (*prometheus.CounterVec, error) {
+	existing, err := registerCollector(registerer, collector)
+	if err != nil {
+		return nil, err
+	}
+
+	return existing.(*prometheus.CounterVec), nil
+}
+
+// registerCollector registers the collector.  If an identical collector
+// is already registered, that one is returned instead.
+func registerCollector(registerer prometheus.Registerer, collector prometheus.Collector) (prometheus.Collector, error) {
+	if err := registerer.Register(collector); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector, nil
+		}
+		return nil, err
+	}
+
+	return collector, nil
+}
@@ -0,0 +1,143 @@
+package blaster
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// statsdGauge is implemented by statsd clients that support gauges,
+// e.g. the DataDog client
+type statsdGauge interface {
+	Gauge(name string, value float64, tags []string, rate float64) error
+}
+
+// statsdHistogram is implemented by statsd clients that support histograms,
+// e.g. the DataDog client
+type statsdHistogram interface {
+	Histogram(name string, value float64, tags []string, rate float64) error
+}
+
+// statsdMetrics reports Metrics through a statsd client
+type statsdMetrics struct {
+	client StatsdClientPrototype
+	stat   string
+	tags   []string
+
+	mu       sync.Mutex
+	inFlight map[MetricLabels]int64
+}
+
+// NewStatsdMetrics returns Metrics that report through the statsd client.
+// The duration is reported as a timing on stat, with the same tags as
+// SetStatsdDelegate, and everything else on stat.<metric>, e.g.
+// stat.retry.  Sizes and the in-flight gauge are only reported if the
+// client also has Histogram and Gauge functions, as the DataDog client does.
+//
+// The adapter takes the place of SetStatsdDelegate.  A client with both
+// sends the duration twice, so give them different stats, or only use one.
+func NewStatsdMetrics(client StatsdClientPrototype, stat string, tags []string) Metrics {
+	return &statsdMetrics{
+		client:   client,
+		stat:     stat,
+		tags:     tags,
+		inFlight: map[MetricLabels]int64{},
+	}
+}
+
+// RequestDuration implements Metrics
+func (m *statsdMetrics) RequestDuration(labels MetricLabels, duration time.Duration) {
+	m.client.Timing(m.stat, duration, m.statusTags(labels), pkgStatsdRate)
+}
+
+// RequestSize implements Metrics
+func (m *statsdMetrics) RequestSize(labels MetricLabels, bytes int) {
+	if h, ok := m.client.(statsdHistogram); ok {
+		h.Histogram(m.name("request_size"), float64(bytes), m.statusTags(labels), pkgStatsdRate)
+	}
+}
+
+// ResponseSize implements Metrics
+func (m *statsdMetrics) ResponseSize(labels MetricLabels, bytes int) {
+	if h, ok := m.client.(statsdHistogram); ok {
+		h.Histogram(m.name("response_size"), float64(bytes), m.statusTags(labels), pkgStatsdRate)
+	}
+}
+
+// InFlight implements Metrics.  Gauges are absolute in statsd, so the
+// count is kept here and the current value is sent on every change.
+func (m *statsdMetrics) InFlight(labels MetricLabels, delta int) {
+	g, ok := m.client.(statsdGauge)
+	if !ok {
+		return
+	}
+
+	m.mu.Lock()
+	m.inFlight[labels] += int64(delta)
+	current := m.inFlight[labels]
+	if current == 0 {
+		delete(m.inFlight, labels)
+	}
+	m.mu.Unlock()
+
+	g.Gauge(m.name("in_flight"), float64(current), m.requestTags(labels), pkgStatsdRate)
+}
+
+// Error implements Metrics
+func (m *statsdMetrics) Error(labels MetricLabels, kind string) {
+	tags := append(m.requestTags(labels), fmt.Sprintf("error:%s", kind))
+	m.client.Incr(m.name("error"), tags, pkgStatsdRate)
+}
+
+// Retry implements Metrics
+func (m *statsdMetrics) Retry(labels MetricLabels) {
+	m.client.Incr(m.name("retry"), m.requestTags(labels), pkgStatsdRate)
+}
+
+// BreakerRejection implements Metrics
+func (m *statsdMetrics) BreakerRejection(labels MetricLabels) {
+	m.client.Incr(m.name("breaker_rejected"), m.requestTags(labels), pkgStatsdRate)
+}
+
+// CacheHit implements Metrics
+func (m *statsdMetrics) CacheHit(labels MetricLabels) {
+	m.client.Incr(m.name("cache_hit"), m.requestTags(labels), pkgStatsdRate)
+}
+
+// ContractViolation implements ContractMetrics
+func (m *statsdMetrics) ContractViolation(labels MetricLabels, direction string) {
+	tags := append(m.requestTags(labels), fmt.Sprintf("direction:%s", direction))
+	m.client.Incr(m.name("contract_violation"), tags, pkgStatsdRate)
+}
+
+// name returns the stat name for a metric
+func (m *statsdMetrics) name(metric string) string {
+	return fmt.Sprintf("%s.%s", m.stat, metric)
+}
+
+// requestTags returns the base tags plus the request labels and the
+// context tags
+func (m *statsdMetrics) requestTags(labels MetricLabels) []string {
+	contextTags := labels.ContextTags()
+	tags := make([]string, 0, len(m.tags)+len(contextTags)+4)
+	tags = append(tags, m.tags...)
+	tags = append(tags, contextTags...)
+	tags = append(tags,
+		fmt.Sprintf("http-verb:%s", labels.Method),
+		fmt.Sprintf("called-service:%s", labels.CalledService),
+		fmt.Sprintf("route:%s", labels.RouteMask),
+	)
+	if labels.Fault != "" {
+		tags = append(tags, fmt.Sprintf("fault:%s", labels.Fault))
+	}
+
+	return tags
+}
+
+// statusTags returns the request tags plus the response status
+func (m *statsdMetrics) statusTags(labels MetricLabels) []string {
+	return append(m.requestTags(labels),
+		fmt.Sprintf("response-code:%d", labels.StatusCode),
+		fmt.Sprintf("response-type:%s", labels.StatusClass()),
+	)
+}
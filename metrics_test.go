@@ -0,0 +1,348 @@
+package blaster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joelhill/go-rest-http-blaster/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// recordingMetrics keeps every call made to it
+type recordingMetrics struct {
+	mu            sync.Mutex
+	durations     []MetricLabels
+	requestSizes  []int
+	responseSizes []int
+	inFlight      int
+	maxInFlight   int
+	errors        []string
+	retries       int
+	rejections    int
+}
+
+func (m *recordingMetrics) RequestDuration(labels MetricLabels, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations = append(m.durations, labels)
+}
+func (m *recordingMetrics) RequestSize(labels MetricLabels, bytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requestSizes = append(m.requestSizes, bytes)
+}
+func (m *recordingMetrics) ResponseSize(labels MetricLabels, bytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responseSizes = append(m.responseSizes, bytes)
+}
+func (m *recordingMetrics) InFlight(labels MetricLabels, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight += delta
+	if m.inFlight > m.maxInFlight {
+		m.maxInFlight = m.inFlight
+	}
+}
+func (m *recordingMetrics) Error(labels MetricLabels, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors = append(m.errors, kind)
+}
+func (m *recordingMetrics) Retry(labels MetricLabels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries++
+}
+func (m *recordingMetrics) BreakerRejection(labels MetricLabels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rejections++
+}
+func (m *recordingMetrics) CacheHit(labels MetricLabels) {}
+
+// dogstatsdClient adds gauges and histograms to the statsd fake
+type dogstatsdClient struct {
+	*fakes.FakeStatsdClientPrototype
+	gauges     map[string]float64
+	histograms map[string][]float64
+}
+
+func (d *dogstatsdClient) Gauge(name string, value float64, tags []string, rate float64) error {
+	d.gauges[name] = value
+	return nil
+}
+func (d *dogstatsdClient) Histogram(name string, value float64, tags []string, rate float64) error {
+	d.histograms[name] = append(d.histograms[name], value)
+	return nil
+}
+
+var _ = Describe("Metrics", func() {
+	var (
+		ctx     context.Context
+		metrics *recordingMetrics
+		server  *httptest.Server
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		metrics = &recordingMetrics{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(contentTypeHeader, jsonType)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":1}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		pkgMetrics = nil
+	})
+
+	// region client
+	Describe("client", func() {
+		It("records the duration, sizes and in-flight requests", func() {
+			c, _ := New(ClientOptions{
+				Endpoint:      server.URL,
+				RouteMask:     "/v1/users",
+				CalledService: "user-service",
+				Metrics:       metrics,
+			})
+			_, err := c.Post(ctx, map[string]string{"name": "x"})
+			Expect(err).To(BeNil())
+
+			Expect(metrics.durations).To(Equal([]MetricLabels{{
+				CalledService: "user-service",
+				RouteMask:     "/v1/users",
+				Method:        http.MethodPost,
+				StatusCode:    http.StatusCreated,
+			}}))
+			Expect(metrics.durations[0].StatusClass()).To(Equal("2xx"))
+			Expect(metrics.requestSizes).To(Equal([]int{len(`{"name":"x"}`)}))
+			Expect(metrics.responseSizes).To(Equal([]int{len(`{"id":1}`)}))
+			Expect(metrics.maxInFlight).To(Equal(1))
+			Expect(metrics.inFlight).To(Equal(0))
+			Expect(metrics.errors).To(BeEmpty())
+		})
+		It("uses the package default", func() {
+			pkgMetrics = metrics
+			c, _ := New(ClientOptions{Endpoint: server.URL})
+			c.Get(ctx)
+			Expect(metrics.durations).To(HaveLen(1))
+		})
+		It("counts requests that could not be launched", func() {
+			c, _ := New(ClientOptions{Endpoint: server.URL, Metrics: metrics})
+			_, err := c.Post(ctx, make(chan int))
+			Expect(err).ToNot(BeNil())
+
+			Expect(metrics.errors).To(Equal([]string{MetricErrorRequest}))
+			Expect(metrics.durations).To(BeEmpty())
+		})
+		It("counts connection errors", func() {
+			l, _ := net.Listen("tcp", "127.0.0.1:0")
+			dead := "http://" + l.Addr().String()
+			l.Close()
+
+			c, _ := New(ClientOptions{Endpoint: dead, Metrics: metrics})
+			c.Get(ctx)
+			Expect(metrics.errors).To(Equal([]string{MetricErrorConnection}))
+			Expect(metrics.inFlight).To(Equal(0))
+		})
+		It("counts timeouts", func() {
+			slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(50 * time.Millisecond)
+			}))
+			defer slow.Close()
+
+			c, _ := New(ClientOptions{Endpoint: slow.URL, Metrics: metrics, TimeoutMS: 5})
+			c.Get(ctx)
+			Expect(metrics.errors).To(Equal([]string{MetricErrorTimeout}))
+		})
+		It("counts failovers as retries", func() {
+			l, _ := net.Listen("tcp", "127.0.0.1:0")
+			dead, _ := NewEndpoint("http://"+l.Addr().String(), 1)
+			l.Close()
+			live, _ := NewEndpoint(server.URL, 1)
+
+			c, _ := New(ClientOptions{EndpointResolver: NewStaticResolver(dead, live), Metrics: metrics})
+			_, err := c.Get(ctx)
+			Expect(err).To(BeNil())
+			Expect(metrics.retries).To(Equal(1))
+			Expect(metrics.errors).To(BeEmpty())
+		})
+		It("counts breaker rejections", func() {
+			cb := &fakes.FakeCircuitBreakerPrototype{}
+			cb.ExecuteReturns(nil, errors.New("circuit breaker is open"))
+
+			c, _ := New(ClientOptions{Endpoint: server.URL, CircuitBreaker: cb, Metrics: metrics})
+			code, _ := c.Get(ctx)
+			Expect(code).To(Equal(http.StatusFailedDependency))
+			Expect(metrics.rejections).To(Equal(1))
+		})
+	})
+	// endregion
+
+	// region statsd
+	Describe("statsd adapter", func() {
+		var (
+			statsd *fakes.FakeStatsdClientPrototype
+			labels MetricLabels
+		)
+
+		BeforeEach(func() {
+			statsd = &fakes.FakeStatsdClientPrototype{}
+			labels = MetricLabels{CalledService: "user-service", RouteMask: "/v1/users", Method: "GET", StatusCode: 503}
+		})
+
+		It("reports the duration as a timing", func() {
+			NewStatsdMetrics(statsd, "api", []string{"env:test"}).RequestDuration(labels, time.Second)
+
+			Expect(statsd.TimingCallCount()).To(Equal(1))
+			name, duration, tags, _ := statsd.TimingArgsForCall(0)
+			Expect(name).To(Equal("api"))
+			Expect(duration).To(Equal(time.Second))
+			Expect(tags).To(ConsistOf("env:test", "http-verb:GET", "called-service:user-service",
+				"route:/v1/users", "response-code:503", "response-type:5xx"))
+		})
+		It("reports counters", func() {
+			m := NewStatsdMetrics(statsd, "api", nil)
+			m.Error(labels, MetricErrorTimeout)
+			m.Retry(labels)
+			m.BreakerRejection(labels)
+			m.CacheHit(labels)
+
+			Expect(statsd.IncrCallCount()).To(Equal(4))
+			name, tags, _ := statsd.IncrArgsForCall(0)
+			Expect(name).To(Equal("api.error"))
+			Expect(tags).To(ContainElement("error:timeout"))
+			names := []string{}
+			for i := 1; i < 4; i++ {
+				name, _, _ := statsd.IncrArgsForCall(i)
+				names = append(names, name)
+			}
+			Expect(names).To(Equal([]string{"api.retry", "api.breaker_rejected", "api.cache_hit"}))
+		})
+		It("skips gauges and histograms the client does not support", func() {
+			m := NewStatsdMetrics(statsd, "api", nil)
+			m.InFlight(labels, 1)
+			m.RequestSize(labels, 10)
+			Expect(statsd.IncrCallCount()).To(Equal(0))
+			Expect(statsd.TimingCallCount()).To(Equal(0))
+		})
+		It("uses gauges and histograms when the client has them", func() {
+			dog := &dogstatsdClient{statsd, map[string]float64{}, map[string][]float64{}}
+			m := NewStatsdMetrics(dog, "api", nil)
+			m.InFlight(labels, 1)
+			m.InFlight(labels, 1)
+			Expect(dog.gauges["api.in_flight"]).To(Equal(2.0))
+			m.InFlight(labels, -1)
+			Expect(dog.gauges["api.in_flight"]).To(Equal(1.0))
+
+			m.RequestSize(labels, 10)
+			m.ResponseSize(labels, 20)
+			Expect(dog.histograms["api.request_size"]).To(Equal([]float64{10}))
+			Expect(dog.histograms["api.response_size"]).To(Equal([]float64{20}))
+		})
+	})
+	// endregion
+
+	// region prometheus
+	Describe("prometheus adapter", func() {
+		var (
+			registry *prometheus.Registry
+			labels   MetricLabels
+		)
+
+		BeforeEach(func() {
+			registry = prometheus.NewRegistry()
+			labels = MetricLabels{CalledService: "user-service", RouteMask: "/v1/users", Method: "GET", StatusCode: 404}
+		})
+
+		It("records labeled collectors", func() {
+			m, err := NewPrometheusMetrics(registry, "test")
+			Expect(err).To(BeNil())
+
+			m.RequestDuration(labels, 250*time.Millisecond)
+			m.RequestSize(labels, 10)
+			m.ResponseSize(labels, 2000)
+			m.InFlight(labels, 1)
+			m.Error(labels, MetricErrorConnection)
+			m.Retry(labels)
+			m.BreakerRejection(labels)
+			m.CacheHit(labels)
+
+			expected := `
+# HELP test_http_client_errors_total Number of outgoing HTTP requests that failed, by kind of error.
+# TYPE test_http_client_errors_total counter
+test_http_client_errors_total{called_service="user-service",fault="",kind="connection",method="GET",route="/v1/users"} 1
+# HELP test_http_client_requests_in_flight Number of outgoing HTTP requests waiting for a response.
+# TYPE test_http_client_requests_in_flight gauge
+test_http_client_requests_in_flight{called_service="user-service",fault="",method="GET",route="/v1/users"} 1
+`
+			Expect(testutil.GatherAndCompare(registry, strings.NewReader(expected),
+				"test_http_client_errors_total", "test_http_client_requests_in_flight")).To(Succeed())
+
+			families, _ := registry.Gather()
+			names := []string{}
+			for _, family := range families {
+				names = append(names, family.GetName())
+				if family.GetName() == "test_http_client_request_duration_seconds" {
+					histogram := family.GetMetric()[0]
+					Expect(histogram.GetHistogram().GetSampleSum()).To(Equal(0.25))
+					statusClass := ""
+					for _, pair := range histogram.GetLabel() {
+						if pair.GetName() == "status_class" {
+							statusClass = pair.GetValue()
+						}
+					}
+					Expect(statusClass).To(Equal("4xx"))
+				}
+			}
+			Expect(names).To(ConsistOf(
+				"test_http_client_request_duration_seconds",
+				"test_http_client_request_size_bytes",
+				"test_http_client_response_size_bytes",
+				"test_http_client_requests_in_flight",
+				"test_http_client_errors_total",
+				"test_http_client_retries_total",
+				"test_http_client_breaker_rejections_total",
+				"test_http_client_cache_hits_total",
+			))
+		})
+		It("reuses collectors that are already registered", func() {
+			first, err := NewPrometheusMetrics(registry, "test")
+			Expect(err).To(BeNil())
+			second, err := NewPrometheusMetrics(registry, "test")
+			Expect(err).To(BeNil())
+
+			first.Retry(labels)
+			second.Retry(labels)
+			Expect(testutil.ToFloat64(second.(*prometheusMetrics).retries)).To(Equal(2.0))
+		})
+		It("records client requests", func() {
+			m, _ := NewPrometheusMetrics(registry, "")
+			c, _ := New(ClientOptions{Endpoint: server.URL, CalledService: "user-service", Metrics: m})
+			c.Get(ctx)
+
+			families, _ := registry.Gather()
+			for _, family := range families {
+				if family.GetName() == "http_client_request_duration_seconds" {
+					Expect(family.GetMetric()[0].GetHistogram().GetSampleCount()).To(Equal(uint64(1)))
+					return
+				}
+			}
+			Fail("request duration not recorded")
+		})
+	})
+	// endregion
+})
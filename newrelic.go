@@ -0,0 +1,57 @@
+package blaster
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/newrelic/go-agent"
+)
+
+// SetNRTxnName names the New Relic transaction the request is made in.
+// The name is applied when the request is launched, see
+// Defaults.NewRelicTransactionProviderFunc.
+func (c *Client) SetNRTxnName(name string) {
+	c.nrTxnName = name
+}
+
+// startNewRelicSegment wraps the attempt in an external segment of the
+// New Relic transaction carried by the context, if there is one.  This
+// adds the cross application tracing headers to the request.
+func (c *Client) startNewRelicSegment(ctx context.Context, request *http.Request) {
+	if pkgNewRelicTransactionProviderFunc == nil {
+		return
+	}
+
+	txn, ok := pkgNewRelicTransactionProviderFunc(ctx)
+	if !ok || txn == nil {
+		return
+	}
+
+	if c.nrTxnName != "" {
+		if err := txn.SetName(c.nrTxnName); err != nil {
+			c.logger.WithFields(map[string]interface{}{
+				"error_message": err.Error(),
+				"type":          NAME,
+			}).Warn("unable to name new relic transaction")
+		}
+	}
+
+	segment := newrelic.StartExternalSegment(txn, request)
+	c.nrSegment = &segment
+}
+
+// endNewRelicSegment ends the external segment of the attempt
+func (c *Client) endNewRelicSegment(response *http.Response) {
+	if c.nrSegment == nil {
+		return
+	}
+
+	c.nrSegment.Response = response
+	if err := c.nrSegment.End(); err != nil {
+		c.logger.WithFields(map[string]interface{}{
+			"error_message": err.Error(),
+			"type":          NAME,
+		}).Warn("unable to end new relic segment")
+	}
+	c.nrSegment = nil
+}
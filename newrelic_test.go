@@ -0,0 +1,122 @@
+package blaster
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	"github.com/newrelic/go-agent"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeNRTransaction records what the client does with the transaction
+type fakeNRTransaction struct {
+	newrelic.Transaction
+
+	names    []string
+	segments int
+}
+
+func (t *fakeNRTransaction) SetName(name string) error {
+	t.names = append(t.names, name)
+	return nil
+}
+
+func (t *fakeNRTransaction) StartSegmentNow() newrelic.SegmentStartTime {
+	t.segments++
+	return newrelic.SegmentStartTime{}
+}
+
+var _ = Describe("New Relic", func() {
+	var (
+		ctx    context.Context
+		server *httptest.Server
+		txn    newrelic.Transaction
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		pkgNewRelicTransactionProviderFunc = func(ctx context.Context) (newrelic.Transaction, bool) {
+			return txn, txn != nil
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		pkgNewRelicTransactionProviderFunc = nil
+		txn = nil
+	})
+
+	It("wraps each attempt in an external segment", func() {
+		fake := &fakeNRTransaction{}
+		txn = fake
+
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.Get(ctx)
+		Expect(fake.segments).To(Equal(1))
+		Expect(fake.names).To(BeEmpty())
+	})
+
+	It("names the transaction", func() {
+		fake := &fakeNRTransaction{}
+		txn = fake
+
+		c, _ := New(ClientOptions{Endpoint: server.URL, NRTxnName: "from-options"})
+		c.Get(ctx)
+		Expect(fake.names).To(Equal([]string{"from-options"}))
+
+		c.SetNRTxnName("from-setter")
+		c.Get(ctx)
+		Expect(fake.names).To(Equal([]string{"from-options", "from-setter"}))
+	})
+
+	It("wraps every failover attempt", func() {
+		var dead []string
+		for i := 0; i < 2; i++ {
+			l, _ := net.Listen("tcp", "127.0.0.1:0")
+			dead = append(dead, "http://"+l.Addr().String())
+			l.Close()
+		}
+
+		fake := &fakeNRTransaction{}
+		txn = fake
+
+		c, _ := New(ClientOptions{Endpoint: "/", Endpoints: dead})
+		_, err := c.Get(ctx)
+		Expect(err).ToNot(BeNil())
+		Expect(fake.segments).To(Equal(2))
+	})
+
+	It("skips requests without a transaction", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+	})
+
+	It("works with a disabled agent", func() {
+		config := newrelic.NewConfig("blaster-test", "")
+		config.Enabled = false
+		app, err := newrelic.NewApplication(config)
+		Expect(err).To(BeNil())
+		defer app.Shutdown(0)
+
+		realTxn := app.StartTransaction("outbound", nil, nil)
+		defer realTxn.End()
+		txn = realTxn
+
+		logger := testlog.New()
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, NRTxnName: "outbound-call"})
+		statusCode, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+		Expect(statusCode).To(Equal(http.StatusOK))
+		Expect(string(logger.Bytes())).ToNot(ContainSubstring("new relic"))
+	})
+})
@@ -0,0 +1,119 @@
+package blaster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+)
+
+// standard opentracing tag names
+const (
+	otSpanKindTag     = "span.kind"
+	otComponentTag    = "component"
+	otHTTPMethodTag   = "http.method"
+	otHTTPURLTag      = "http.url"
+	otHTTPStatusTag   = "http.status_code"
+	otPeerAddressTag  = "peer.address"
+	otPeerServiceTag  = "peer.service"
+	otErrorTag        = "error"
+	otRouteTag        = "http.route"
+	otRetryAttemptTag = "retry.attempt"
+	otFaultTag        = "blaster.fault"
+)
+
+// GlobalTracerProvider is a ready-made TracerProviderFunc.  It starts a client
+// span with opentracing.GlobalTracer(), as a child of the span on the context
+// if there is one, and injects it into the outgoing request headers.
+//
+//	blaster.SetDefaults(&blaster.Defaults{
+//		TracerProviderFunc: blaster.GlobalTracerProvider,
+//	})
+func GlobalTracerProvider(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span) {
+	return startOpenTracingSpan(ctx, opentracing.GlobalTracer(), operationName, r)
+}
+
+// NewTracerProvider returns a TracerProviderFunc that works as
+// GlobalTracerProvider does, with the given tracer, e.g. a mocktracer in
+// tests
+func NewTracerProvider(tracer opentracing.Tracer) func(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span) {
+	return func(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span) {
+		return startOpenTracingSpan(ctx, tracer, operationName, r)
+	}
+}
+
+// startOpenTracingSpan starts the client span of a request and injects it
+// into the request headers
+func startOpenTracingSpan(ctx context.Context, tracer opentracing.Tracer, operationName string, r *http.Request) (*http.Request, opentracing.Span) {
+	opts := []opentracing.StartSpanOption{
+		opentracing.Tag{Key: otSpanKindTag, Value: "client"},
+		opentracing.Tag{Key: otComponentTag, Value: NAME},
+		opentracing.Tag{Key: otHTTPMethodTag, Value: r.Method},
+		opentracing.Tag{Key: otHTTPURLTag, Value: r.URL.String()},
+	}
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+
+	span := tracer.StartSpan(operationName, opts...)
+	if err := tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header)); err != nil {
+		span.LogFields(otlog.String("event", "inject failed"), otlog.Error(err))
+	}
+
+	return r.WithContext(opentracing.ContextWithSpan(r.Context(), span)), span
+}
+
+// enrichOpenTracingSpan tags the span of an attempt with what is known
+// about the request before it is launched
+func (c *Client) enrichOpenTracingSpan(request *http.Request, attempt int) {
+	span := c.openTracingSpan
+	span.SetTag(otPeerAddressTag, request.URL.Host)
+	if c.calledService != "" {
+		span.SetTag(otPeerServiceTag, c.calledService)
+	}
+	if c.routeMask != "" {
+		span.SetTag(otRouteTag, c.routeMask)
+	}
+	if attempt > 1 {
+		span.SetTag(otRetryAttemptTag, attempt-1)
+	}
+}
+
+// setOpenTracingResponse records the response status on the current span.
+// As with OpenTelemetry, 4xx and 5xx both flag the span as an error.
+func (c *Client) setOpenTracingResponse(statusCode int) {
+	if c.openTracingSpan == nil {
+		return
+	}
+
+	c.openTracingSpan.SetTag(otHTTPStatusTag, statusCode)
+	if statusCode >= http.StatusBadRequest {
+		c.openTracingSpan.SetTag(otErrorTag, true)
+	}
+}
+
+// recordOpenTracingError flags the current span as an error and logs the error on it
+func (c *Client) recordOpenTracingError(err error) {
+	if c.openTracingSpan == nil || err == nil {
+		return
+	}
+
+	c.openTracingSpan.SetTag(otErrorTag, true)
+	c.openTracingSpan.LogFields(
+		otlog.String("event", "error"),
+		otlog.String("error.kind", fmt.Sprintf("%T", err)),
+		otlog.String("message", err.Error()),
+	)
+}
+
+// finishOpenTracingSpan finishes the current span, if any
+func (c *Client) finishOpenTracingSpan() {
+	if c.openTracingSpan == nil {
+		return
+	}
+
+	c.openTracingSpan.Finish()
+	c.openTracingSpan = nil
+}
@@ -0,0 +1,218 @@
+package blaster
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/opentracing/opentracing-go"
+	otlog "github.com/opentracing/opentracing-go/log"
+)
+
+// recordingOTSpan keeps the tags, logs and finish calls made on it
+type recordingOTSpan struct {
+	opentracing.Span
+	tracer   *recordingOTTracer
+	name     string
+	parent   opentracing.SpanContext
+	tags     map[string]interface{}
+	logs     []otlog.Field
+	finished int
+}
+
+func (s *recordingOTSpan) Tracer() opentracing.Tracer { return s.tracer }
+func (s *recordingOTSpan) Finish()                    { s.finished++ }
+func (s *recordingOTSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.tags[key] = value
+	return s
+}
+func (s *recordingOTSpan) LogFields(fields ...otlog.Field) {
+	s.logs = append(s.logs, fields...)
+}
+
+// recordingOTTracer hands out recording spans and injects a fixed header
+type recordingOTTracer struct {
+	opentracing.NoopTracer
+	mu    sync.Mutex
+	spans []*recordingOTSpan
+}
+
+func (t *recordingOTTracer) StartSpan(name string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	options := opentracing.StartSpanOptions{}
+	for _, opt := range opts {
+		opt.Apply(&options)
+	}
+
+	span := &recordingOTSpan{
+		Span:   t.NoopTracer.StartSpan(name),
+		tracer: t,
+		name:   name,
+		tags:   map[string]interface{}{},
+	}
+	for key, value := range options.Tags {
+		span.tags[key] = value
+	}
+	for _, ref := range options.References {
+		span.parent = ref.ReferencedContext
+	}
+	t.spans = append(t.spans, span)
+
+	return span
+}
+
+func (t *recordingOTTracer) Inject(sm opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	carrier.(opentracing.HTTPHeadersCarrier).Set("Ot-Tracer-Traceid", "42")
+	return nil
+}
+
+var _ = Describe("OpenTracing", func() {
+	var (
+		ctx      context.Context
+		tracer   *recordingOTTracer
+		previous opentracing.Tracer
+		server   *httptest.Server
+		received http.Header
+		status   int
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		status = http.StatusOK
+		tracer = &recordingOTTracer{}
+		previous = opentracing.GlobalTracer()
+		opentracing.SetGlobalTracer(tracer)
+		pkgTracerProviderFunc = GlobalTracerProvider
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+			w.WriteHeader(status)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		opentracing.SetGlobalTracer(previous)
+		pkgTracerProviderFunc = nil
+	})
+
+	// region provider
+	Describe("GlobalTracerProvider", func() {
+		It("starts a client span and injects it into the request", func() {
+			c, _ := New(ClientOptions{Endpoint: server.URL})
+			_, err := c.Get(ctx)
+			Expect(err).To(BeNil())
+
+			Expect(tracer.spans).To(HaveLen(1))
+			span := tracer.spans[0]
+			Expect(span.tags[otSpanKindTag]).To(Equal("client"))
+			Expect(span.tags[otHTTPMethodTag]).To(Equal("GET"))
+			Expect(span.parent).To(BeNil())
+			Expect(received.Get("Ot-Tracer-Traceid")).To(Equal("42"))
+		})
+		It("continues the trace on the context", func() {
+			parent := tracer.StartSpan("handler")
+			c, _ := New(ClientOptions{Endpoint: server.URL})
+			c.Get(opentracing.ContextWithSpan(ctx, parent))
+
+			Expect(tracer.spans).To(HaveLen(2))
+			Expect(tracer.spans[1].parent).ToNot(BeNil())
+			Expect(tracer.spans[1].parent).To(Equal(parent.Context()))
+		})
+	})
+	// endregion
+
+	// region enrichment
+	Describe("span enrichment", func() {
+		It("tags the status, called service and route", func() {
+			c, _ := New(ClientOptions{
+				Endpoint:      server.URL + "/v1/users/123",
+				RouteMask:     "/v1/users/{id}",
+				CalledService: "user-service",
+			})
+			c.Get(ctx)
+
+			span := tracer.spans[0]
+			Expect(span.finished).To(Equal(1))
+			Expect(span.tags[otHTTPStatusTag]).To(Equal(http.StatusOK))
+			Expect(span.tags[otPeerServiceTag]).To(Equal("user-service"))
+			Expect(span.tags[otRouteTag]).To(Equal("/v1/users/{id}"))
+			Expect(span.tags).ToNot(HaveKey(otErrorTag))
+		})
+		It("flags error responses", func() {
+			status = http.StatusBadGateway
+			c, _ := New(ClientOptions{Endpoint: server.URL})
+			c.Get(ctx)
+
+			span := tracer.spans[0]
+			Expect(span.tags[otHTTPStatusTag]).To(Equal(http.StatusBadGateway))
+			Expect(span.tags[otErrorTag]).To(Equal(true))
+		})
+		It("flags and logs transport errors", func() {
+			l, _ := net.Listen("tcp", "127.0.0.1:0")
+			dead := "http://" + l.Addr().String()
+			l.Close()
+
+			c, _ := New(ClientOptions{Endpoint: dead})
+			_, err := c.Get(ctx)
+			Expect(err).ToNot(BeNil())
+
+			span := tracer.spans[0]
+			Expect(span.finished).To(Equal(1))
+			Expect(span.tags[otErrorTag]).To(Equal(true))
+			Expect(span.logs).To(ContainElement(otlog.String("event", "error")))
+			Expect(span.logs).To(ContainElement(otlog.String("message", err.Error())))
+		})
+		It("tags the retry attempt and finishes every span once", func() {
+			l, _ := net.Listen("tcp", "127.0.0.1:0")
+			dead, _ := NewEndpoint("http://"+l.Addr().String(), 1)
+			l.Close()
+			live, _ := NewEndpoint(server.URL, 1)
+
+			c, _ := New(ClientOptions{EndpointResolver: NewStaticResolver(dead, live)})
+			_, err := c.Get(ctx)
+			Expect(err).To(BeNil())
+
+			Expect(tracer.spans).To(HaveLen(2))
+			Expect(tracer.spans[0].finished).To(Equal(1))
+			Expect(tracer.spans[0].tags[otErrorTag]).To(Equal(true))
+			Expect(tracer.spans[1].finished).To(Equal(1))
+			Expect(tracer.spans[1].tags[otRetryAttemptTag]).To(Equal(1))
+		})
+		It("finishes the span when the response cannot be processed", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(contentTypeHeader, jsonType)
+				w.Write([]byte("{not json"))
+			})
+			c, _ := New(ClientOptions{Endpoint: server.URL})
+			var payload map[string]interface{}
+			c.WillSaturate(&payload)
+			_, err := c.Get(ctx)
+			Expect(err).ToNot(BeNil())
+
+			span := tracer.spans[0]
+			Expect(span.finished).To(Equal(1))
+			Expect(span.tags[otErrorTag]).To(Equal(true))
+		})
+		It("keeps the request returned by the provider", func() {
+			pkgTracerProviderFunc = func(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span) {
+				wrapped := r.Clone(ctx)
+				wrapped.Header.Set("X-Wrapped", "yes")
+				return wrapped, nil
+			}
+
+			c, _ := New(ClientOptions{Endpoint: server.URL})
+			_, err := c.Get(ctx)
+			Expect(err).To(BeNil())
+			Expect(received.Get("X-Wrapped")).To(Equal("yes"))
+		})
+	})
+	// endregion
+})
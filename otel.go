@@ -0,0 +1,128 @@
+package blaster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// otelInstrumentationName identifies this library as the source of its spans
+const otelInstrumentationName = "github.com/joelhill/go-rest-http-blaster"
+
+// semantic convention attribute keys for HTTP client spans
+const (
+	otelMethodKey        = attribute.Key("http.request.method")
+	otelStatusCodeKey    = attribute.Key("http.response.status_code")
+	otelResendCountKey   = attribute.Key("http.request.resend_count")
+	otelServerAddrKey    = attribute.Key("server.address")
+	otelServerPortKey    = attribute.Key("server.port")
+	otelURLFullKey       = attribute.Key("url.full")
+	otelURLTemplateKey   = attribute.Key("url.template")
+	otelPeerServiceKey   = attribute.Key("peer.service")
+	otelErrorTypeKey     = attribute.Key("error.type")
+	otelFaultKey         = attribute.Key("blaster.fault")
+	otelErrorTypeTimeout = "timeout"
+)
+
+// defaultOTelPropagator injects traceparent, tracestate and baggage
+var defaultOTelPropagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(
+	propagation.TraceContext{},
+	propagation.Baggage{},
+)
+
+// otelPropagator returns the configured propagator, or the W3C default
+func otelPropagator() propagation.TextMapPropagator {
+	if pkgOTelPropagator != nil {
+		return pkgOTelPropagator
+	}
+
+	return defaultOTelPropagator
+}
+
+// startOTelSpan starts the client span for a single attempt, and injects
+// its context into the outgoing headers.  Every attempt gets its own span,
+// retries are told apart by http.request.resend_count.
+func (c *Client) startOTelSpan(ctx context.Context, request *http.Request, attempt int) {
+	if pkgOTelTracerProvider == nil {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		otelMethodKey.String(c.method),
+		otelServerAddrKey.String(request.URL.Hostname()),
+		otelURLFullKey.String(request.URL.String()),
+	}
+	if port, err := strconv.Atoi(request.URL.Port()); err == nil {
+		attrs = append(attrs, otelServerPortKey.Int(port))
+	}
+	if c.routeMask != "" {
+		attrs = append(attrs, otelURLTemplateKey.String(c.routeMask))
+	}
+	if c.calledService != "" {
+		attrs = append(attrs, otelPeerServiceKey.String(c.calledService))
+	}
+	if attempt > 1 {
+		attrs = append(attrs, otelResendCountKey.Int(attempt-1))
+	}
+
+	// the span name is low cardinality on purpose, never the full url
+	name := c.method
+	if c.routeMask != "" {
+		name = fmt.Sprintf("%s %s", c.method, c.routeMask)
+	}
+
+	spanCtx, span := pkgOTelTracerProvider.Tracer(otelInstrumentationName).Start(ctx, name,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(attrs...),
+	)
+	otelPropagator().Inject(spanCtx, propagation.HeaderCarrier(request.Header))
+
+	c.otelSpan = span
+}
+
+// setOTelResponse records the response status on the current span.
+// For client spans, both 4xx and 5xx count as errors.
+func (c *Client) setOTelResponse(statusCode int) {
+	if c.otelSpan == nil {
+		return
+	}
+
+	c.otelSpan.SetAttributes(otelStatusCodeKey.Int(statusCode))
+	if statusCode >= http.StatusBadRequest {
+		c.otelSpan.SetAttributes(otelErrorTypeKey.String(strconv.Itoa(statusCode)))
+		c.otelSpan.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+}
+
+// recordOTelError records an error on the current span
+func (c *Client) recordOTelError(err error) {
+	if c.otelSpan == nil || err == nil {
+		return
+	}
+
+	errorType := fmt.Sprintf("%T", err)
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		errorType = otelErrorTypeTimeout
+	}
+
+	c.otelSpan.RecordError(err)
+	c.otelSpan.SetAttributes(otelErrorTypeKey.String(errorType))
+	c.otelSpan.SetStatus(codes.Error, err.Error())
+}
+
+// endOTelSpan ends the current span, if any
+func (c *Client) endOTelSpan() {
+	if c.otelSpan == nil {
+		return
+	}
+
+	c.otelSpan.End()
+	c.otelSpan = nil
+}
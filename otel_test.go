@@ -0,0 +1,203 @@
+package blaster
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingSpan keeps what was recorded on it, on top of a no-op span
+type recordingSpan struct {
+	noop.Span
+	name   string
+	kind   oteltrace.SpanKind
+	sc     oteltrace.SpanContext
+	attrs  map[attribute.Key]attribute.Value
+	errs   []error
+	status codes.Code
+	ended  bool
+}
+
+func (s *recordingSpan) SpanContext() oteltrace.SpanContext { return s.sc }
+func (s *recordingSpan) IsRecording() bool                  { return true }
+func (s *recordingSpan) End(...oteltrace.SpanEndOption)     { s.ended = true }
+func (s *recordingSpan) RecordError(err error, _ ...oteltrace.EventOption) {
+	s.errs = append(s.errs, err)
+}
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+// recordingTracer hands out recording spans
+type recordingTracer struct {
+	noop.Tracer
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	config := oteltrace.NewSpanStartConfig(opts...)
+	span := &recordingSpan{
+		name:  name,
+		kind:  config.SpanKind(),
+		attrs: map[attribute.Key]attribute.Value{},
+	}
+	span.sc = oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     oteltrace.SpanID{1, 1, 1, 1, 1, 1, 1, byte(len(t.spans) + 1)},
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+	span.SetAttributes(config.Attributes()...)
+	t.spans = append(t.spans, span)
+
+	return oteltrace.ContextWithSpan(ctx, span), span
+}
+
+// recordingProvider always returns the same recording tracer
+type recordingProvider struct {
+	noop.TracerProvider
+	tracer *recordingTracer
+}
+
+func (p *recordingProvider) Tracer(string, ...oteltrace.TracerOption) oteltrace.Tracer {
+	return p.tracer
+}
+
+var _ = Describe("OpenTelemetry", func() {
+	var (
+		ctx      context.Context
+		tracer   *recordingTracer
+		server   *httptest.Server
+		received http.Header
+		status   int
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		status = http.StatusOK
+		tracer = &recordingTracer{}
+		pkgOTelTracerProvider = &recordingProvider{tracer: tracer}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header
+			w.WriteHeader(status)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		pkgOTelTracerProvider = nil
+		pkgOTelPropagator = nil
+	})
+
+	It("creates a client span with semantic convention attributes", func() {
+		c, err := New(ClientOptions{
+			Endpoint:      server.URL + "/v1/users/123",
+			RouteMask:     "/v1/users/{id}",
+			CalledService: "user-service",
+		})
+		Expect(err).To(BeNil())
+		_, err = c.Get(ctx)
+		Expect(err).To(BeNil())
+
+		Expect(tracer.spans).To(HaveLen(1))
+		span := tracer.spans[0]
+		Expect(span.name).To(Equal("GET /v1/users/{id}"))
+		Expect(span.kind).To(Equal(oteltrace.SpanKindClient))
+		Expect(span.ended).To(BeTrue())
+		Expect(span.attrs["http.request.method"].AsString()).To(Equal("GET"))
+		Expect(span.attrs["server.address"].AsString()).To(Equal("127.0.0.1"))
+		Expect(span.attrs["url.template"].AsString()).To(Equal("/v1/users/{id}"))
+		Expect(span.attrs["peer.service"].AsString()).To(Equal("user-service"))
+		Expect(span.attrs["http.response.status_code"].AsInt64()).To(Equal(int64(200)))
+		Expect(span.status).To(Equal(codes.Unset))
+	})
+
+	It("injects traceparent and baggage", func() {
+		member, _ := baggage.NewMember("tenant", "acme")
+		bag, _ := baggage.New(member)
+		ctx = baggage.ContextWithBaggage(ctx, bag)
+
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.Get(ctx)
+
+		Expect(received.Get("traceparent")).To(Equal("00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01"))
+		Expect(received.Get("baggage")).To(Equal("tenant=acme"))
+	})
+
+	It("marks error responses", func() {
+		status = http.StatusServiceUnavailable
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.Get(ctx)
+
+		span := tracer.spans[0]
+		Expect(span.status).To(Equal(codes.Error))
+		Expect(span.attrs["error.type"].AsString()).To(Equal("503"))
+	})
+
+	It("records transport errors", func() {
+		l, _ := net.Listen("tcp", "127.0.0.1:0")
+		dead := "http://" + l.Addr().String()
+		l.Close()
+
+		c, _ := New(ClientOptions{Endpoint: dead})
+		_, err := c.Get(ctx)
+		Expect(err).ToNot(BeNil())
+
+		span := tracer.spans[0]
+		Expect(span.ended).To(BeTrue())
+		Expect(span.errs).To(HaveLen(1))
+		Expect(span.status).To(Equal(codes.Error))
+	})
+
+	It("creates a span per attempt", func() {
+		l, _ := net.Listen("tcp", "127.0.0.1:0")
+		dead, _ := NewEndpoint("http://"+l.Addr().String(), 1)
+		l.Close()
+		live, _ := NewEndpoint(server.URL, 1)
+
+		c, _ := New(ClientOptions{EndpointResolver: NewStaticResolver(dead, live)})
+		_, err := c.Get(ctx)
+		Expect(err).To(BeNil())
+
+		Expect(tracer.spans).To(HaveLen(2))
+		Expect(tracer.spans[0].ended).To(BeTrue())
+		Expect(tracer.spans[0].status).To(Equal(codes.Error))
+		Expect(tracer.spans[1].ended).To(BeTrue())
+		Expect(tracer.spans[1].attrs["http.request.resend_count"].AsInt64()).To(Equal(int64(1)))
+		Expect(received.Get("traceparent")).To(ContainSubstring("0101010101010102"))
+	})
+
+	It("works alongside opentracing", func() {
+		called := false
+		pkgTracerProviderFunc = func(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span) {
+			called = true
+			return r, nil
+		}
+		defer func() { pkgTracerProviderFunc = nil }()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.Get(ctx)
+
+		Expect(called).To(BeTrue())
+		Expect(tracer.spans).To(HaveLen(1))
+	})
+})
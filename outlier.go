@@ -0,0 +1,254 @@
+package blaster
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConsecutiveErrors    = 5
+	defaultErrorRateMinRequests = 20
+	defaultOutlierInterval      = 10 * time.Second
+	defaultBaseEjectionTime     = 30 * time.Second
+	defaultMaxEjectionTime      = 5 * time.Minute
+	defaultMaxEjectionPercent   = 50
+)
+
+// OutlierDetection configures passive outlier detection.  A request counts
+// as a failure if it returns a 5xx, fails at the transport level, or takes
+// longer than the latency threshold.  Zero values fall back to the defaults.
+type OutlierDetection struct {
+	// ConsecutiveErrors ejects an endpoint after this many failures in a row.  Defaults to 5.
+	ConsecutiveErrors int
+
+	// ErrorRate ejects an endpoint once this fraction (0-1] of its requests
+	// in the current interval have failed.  Disabled if zero.
+	ErrorRate float64
+
+	// ErrorRateMinRequests is the minimum number of requests in the interval
+	// before the error rate is considered.  Defaults to 20.
+	ErrorRateMinRequests int
+
+	// Interval is the length of the window the error rate is measured over.  Defaults to 10 seconds.
+	Interval time.Duration
+
+	// LatencyThreshold counts any request slower than this as a failure.  Disabled if zero.
+	LatencyThreshold time.Duration
+
+	// BaseEjectionTime is how long the first ejection lasts.  Each following
+	// ejection lasts twice as long as the one before it.  Defaults to 30 seconds.
+	BaseEjectionTime time.Duration
+
+	// MaxEjectionTime caps the length of an ejection.  An endpoint that stays
+	// in rotation this long starts over at the base time.  Defaults to 5 minutes.
+	MaxEjectionTime time.Duration
+
+	// MaxEjectionPercent is the largest share of endpoints that can be ejected
+	// at once.  Defaults to 50.
+	MaxEjectionPercent int
+
+	// Clock times the windows and ejections.  Defaults to Defaults.Clock.
+	Clock Clock
+}
+
+// OutlierEvent describes an endpoint leaving or returning to rotation
+type OutlierEvent struct {
+	Endpoint *Endpoint
+	Ejected  bool
+	Reason   string
+	Duration time.Duration
+}
+
+// outlierState is what the detector knows about a single endpoint
+type outlierState struct {
+	consecutive  int
+	requests     int
+	failures     int
+	windowStart  time.Time
+	ejections    int
+	ejected      bool
+	ejectedUntil time.Time
+	returnedAt   time.Time
+}
+
+// OutlierDetector takes misbehaving endpoints out of rotation.  Like a
+// balancer, it keeps state between requests, so a single detector should
+// be shared by all clients of a called service.
+type OutlierDetector struct {
+	config OutlierDetection
+
+	mu    sync.Mutex
+	state map[*Endpoint]*outlierState
+}
+
+// NewOutlierDetector returns a detector for the given configuration
+func NewOutlierDetector(config OutlierDetection) *OutlierDetector {
+	if config.ConsecutiveErrors <= 0 {
+		config.ConsecutiveErrors = defaultConsecutiveErrors
+	}
+	if config.ErrorRateMinRequests <= 0 {
+		config.ErrorRateMinRequests = defaultErrorRateMinRequests
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultOutlierInterval
+	}
+	if config.BaseEjectionTime <= 0 {
+		config.BaseEjectionTime = defaultBaseEjectionTime
+	}
+	if config.MaxEjectionTime <= 0 {
+		config.MaxEjectionTime = defaultMaxEjectionTime
+	}
+	if config.MaxEjectionPercent <= 0 {
+		config.MaxEjectionPercent = defaultMaxEjectionPercent
+	}
+
+	return &OutlierDetector{
+		config: config,
+		state:  map[*Endpoint]*outlierState{},
+	}
+}
+
+// Available filters out the ejected endpoints.  Endpoints whose ejection
+// has run out are returned to rotation, and reported as events.  If every
+// endpoint is ejected, all of them are returned rather than none.  The
+// state of endpoints that are no longer among the candidates, e.g. after
+// the resolver dropped them, is forgotten.
+func (d *OutlierDetector) Available(candidates []*Endpoint) ([]*Endpoint, []OutlierEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := clockOrDefault(d.config.Clock).Now()
+	var events []OutlierEvent
+	available := make([]*Endpoint, 0, len(candidates))
+	current := make(map[*Endpoint]bool, len(candidates))
+	for _, ep := range candidates {
+		current[ep] = true
+		s := d.stateFor(ep, now)
+		if s.ejected && !now.Before(s.ejectedUntil) {
+			s.ejected = false
+			s.returnedAt = now
+			s.consecutive = 0
+			s.requests, s.failures, s.windowStart = 0, 0, now
+			events = append(events, OutlierEvent{Endpoint: ep})
+		}
+		if !s.ejected {
+			available = append(available, ep)
+		}
+	}
+
+	for ep := range d.state {
+		if !current[ep] {
+			delete(d.state, ep)
+		}
+	}
+
+	if len(available) == 0 {
+		return candidates, events
+	}
+
+	return available, events
+}
+
+// Observe records the outcome of a request to an endpoint, and ejects
+// the endpoint if it has become an outlier.  The candidates are the
+// endpoints of the same service, used to cap the share ejected at once.
+func (d *OutlierDetector) Observe(candidates []*Endpoint, ep *Endpoint, statusCode int, err error, latency time.Duration) *OutlierEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := clockOrDefault(d.config.Clock).Now()
+	s := d.stateFor(ep, now)
+	if s.ejected {
+		return nil
+	}
+
+	if now.Sub(s.windowStart) >= d.config.Interval {
+		s.requests, s.failures, s.windowStart = 0, 0, now
+	}
+
+	s.requests++
+	if err == nil && statusCode < http.StatusInternalServerError && (d.config.LatencyThreshold <= 0 || latency <= d.config.LatencyThreshold) {
+		s.consecutive = 0
+		return nil
+	}
+	s.failures++
+	s.consecutive++
+
+	var reason string
+	switch {
+	case s.consecutive >= d.config.ConsecutiveErrors:
+		reason = "consecutive_errors"
+	case d.config.ErrorRate > 0 && s.requests >= d.config.ErrorRateMinRequests &&
+		float64(s.failures)/float64(s.requests) >= d.config.ErrorRate:
+		reason = "error_rate"
+	default:
+		return nil
+	}
+
+	if !d.canEject(candidates, now) {
+		return nil
+	}
+
+	// an endpoint that stayed in rotation long enough starts over
+	if s.ejections > 0 && now.Sub(s.returnedAt) >= d.config.MaxEjectionTime {
+		s.ejections = 0
+	}
+	s.ejections++
+
+	duration := d.config.BaseEjectionTime
+	for i := 1; i < s.ejections && duration < d.config.MaxEjectionTime; i++ {
+		duration *= 2
+	}
+	if duration > d.config.MaxEjectionTime {
+		duration = d.config.MaxEjectionTime
+	}
+
+	s.ejected = true
+	s.ejectedUntil = now.Add(duration)
+
+	return &OutlierEvent{
+		Endpoint: ep,
+		Ejected:  true,
+		Reason:   reason,
+		Duration: duration,
+	}
+}
+
+// Ejected determines whether the endpoint is currently out of rotation
+func (d *OutlierDetector) Ejected(ep *Endpoint) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	s, ok := d.state[ep]
+	return ok && s.ejected && clockOrDefault(d.config.Clock).Now().Before(s.ejectedUntil)
+}
+
+// canEject checks that one more ejection stays within the max percentage.
+// Must be called with the lock held.
+func (d *OutlierDetector) canEject(candidates []*Endpoint, now time.Time) bool {
+	if len(candidates) == 0 {
+		return false
+	}
+
+	ejected := 0
+	for _, candidate := range candidates {
+		if s, ok := d.state[candidate]; ok && s.ejected && now.Before(s.ejectedUntil) {
+			ejected++
+		}
+	}
+
+	return (ejected+1)*100 <= len(candidates)*d.config.MaxEjectionPercent
+}
+
+// stateFor returns the state of an endpoint, creating it if needed.
+// Must be called with the lock held.
+func (d *OutlierDetector) stateFor(ep *Endpoint, now time.Time) *outlierState {
+	s, ok := d.state[ep]
+	if !ok {
+		s = &outlierState{windowStart: now}
+		d.state[ep] = s
+	}
+
+	return s
+}
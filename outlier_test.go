@@ -0,0 +1,215 @@
+package blaster
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	"github.com/joelhill/go-rest-http-blaster/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OutlierDetector", func() {
+	var (
+		detector  *OutlierDetector
+		endpoints []*Endpoint
+		config    OutlierDetection
+	)
+
+	BeforeEach(func() {
+		config = OutlierDetection{
+			ConsecutiveErrors: 3,
+			BaseEjectionTime:  20 * time.Millisecond,
+			MaxEjectionTime:   time.Second,
+		}
+		endpoints = []*Endpoint{}
+		for _, raw := range []string{"http://one:8080", "http://two:8080", "http://three:8080", "http://four:8080"} {
+			ep, _ := NewEndpoint(raw, 1)
+			endpoints = append(endpoints, ep)
+		}
+	})
+
+	JustBeforeEach(func() {
+		detector = NewOutlierDetector(config)
+	})
+
+	fail := func(ep *Endpoint, times int) *OutlierEvent {
+		var event *OutlierEvent
+		for i := 0; i < times; i++ {
+			if e := detector.Observe(endpoints, ep, http.StatusBadGateway, nil, time.Millisecond); e != nil {
+				event = e
+			}
+		}
+		return event
+	}
+
+	// region ejection
+	Describe("ejection", func() {
+		Context("consecutive errors", func() {
+			It("ejects the endpoint", func() {
+				Expect(fail(endpoints[0], 2)).To(BeNil())
+				event := fail(endpoints[0], 1)
+				Expect(event).ToNot(BeNil())
+				Expect(event.Ejected).To(BeTrue())
+				Expect(event.Reason).To(Equal("consecutive_errors"))
+				Expect(event.Duration).To(Equal(20 * time.Millisecond))
+
+				available, _ := detector.Available(endpoints)
+				Expect(available).To(HaveLen(3))
+				Expect(available).ToNot(ContainElement(endpoints[0]))
+			})
+			It("starts over after a success", func() {
+				fail(endpoints[0], 2)
+				detector.Observe(endpoints, endpoints[0], http.StatusOK, nil, time.Millisecond)
+				Expect(fail(endpoints[0], 2)).To(BeNil())
+			})
+			It("counts transport errors", func() {
+				for i := 0; i < 3; i++ {
+					detector.Observe(endpoints, endpoints[0], 0, errors.New("FAIL"), time.Millisecond)
+				}
+				Expect(detector.Ejected(endpoints[0])).To(BeTrue())
+			})
+		})
+		Context("error rate", func() {
+			BeforeEach(func() {
+				config.ConsecutiveErrors = 100
+				config.ErrorRate = 0.5
+				config.ErrorRateMinRequests = 4
+			})
+			It("ejects the endpoint once enough requests failed", func() {
+				detector.Observe(endpoints, endpoints[0], http.StatusOK, nil, time.Millisecond)
+				fail(endpoints[0], 1)
+				detector.Observe(endpoints, endpoints[0], http.StatusOK, nil, time.Millisecond)
+				Expect(detector.Ejected(endpoints[0])).To(BeFalse())
+
+				event := fail(endpoints[0], 1)
+				Expect(event).ToNot(BeNil())
+				Expect(event.Reason).To(Equal("error_rate"))
+			})
+		})
+		Context("latency", func() {
+			BeforeEach(func() {
+				config.LatencyThreshold = 10 * time.Millisecond
+			})
+			It("counts slow requests as failures", func() {
+				for i := 0; i < 3; i++ {
+					detector.Observe(endpoints, endpoints[0], http.StatusOK, nil, 50*time.Millisecond)
+				}
+				Expect(detector.Ejected(endpoints[0])).To(BeTrue())
+			})
+		})
+		Context("max ejection percent", func() {
+			It("never ejects more than half of the endpoints", func() {
+				Expect(fail(endpoints[0], 3)).ToNot(BeNil())
+				Expect(fail(endpoints[1], 3)).ToNot(BeNil())
+				Expect(fail(endpoints[2], 3)).To(BeNil())
+				Expect(detector.Ejected(endpoints[2])).To(BeFalse())
+			})
+		})
+	})
+	// endregion
+
+	// region return
+	Describe("return to rotation", func() {
+		It("returns the endpoint once the ejection runs out", func() {
+			fail(endpoints[0], 3)
+			time.Sleep(30 * time.Millisecond)
+
+			available, events := detector.Available(endpoints)
+			Expect(available).To(ContainElement(endpoints[0]))
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Ejected).To(BeFalse())
+			Expect(events[0].Endpoint).To(Equal(endpoints[0]))
+		})
+		It("ejects for twice as long the next time", func() {
+			fail(endpoints[0], 3)
+			time.Sleep(30 * time.Millisecond)
+			detector.Available(endpoints)
+
+			event := fail(endpoints[0], 3)
+			Expect(event.Duration).To(Equal(40 * time.Millisecond))
+		})
+		It("keeps every endpoint when all are ejected", func() {
+			config.MaxEjectionPercent = 100
+			detector = NewOutlierDetector(config)
+			for _, ep := range endpoints {
+				fail(ep, 3)
+			}
+			available, _ := detector.Available(endpoints)
+			Expect(available).To(HaveLen(4))
+		})
+		It("forgets the endpoints that are no longer candidates", func() {
+			fail(endpoints[0], 3)
+			Expect(detector.state).To(HaveLen(1))
+
+			detector.Available(endpoints[1:])
+			Expect(detector.state).To(HaveLen(3))
+			Expect(detector.state).ToNot(HaveKey(endpoints[0]))
+
+			available, _ := detector.Available(endpoints)
+			Expect(available).To(ContainElement(endpoints[0]))
+		})
+	})
+	// endregion
+
+	// region client
+	Describe("client", func() {
+		var (
+			bad    *httptest.Server
+			good   *httptest.Server
+			statsd *fakes.FakeStatsdClientPrototype
+			logger *testlog.TestLogger
+		)
+
+		BeforeEach(func() {
+			pkgRequireHeaders = false
+			config.BaseEjectionTime = time.Minute
+			bad = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			good = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			statsd = &fakes.FakeStatsdClientPrototype{}
+			logger = testlog.New()
+		})
+
+		AfterEach(func() {
+			bad.Close()
+			good.Close()
+		})
+
+		It("takes the bad endpoint out of rotation", func() {
+			badEp, _ := NewEndpoint(bad.URL, 1)
+			goodEp, _ := NewEndpoint(good.URL, 1)
+			resolver := NewStaticResolver(badEp, goodEp)
+
+			codes := []int{}
+			for i := 0; i < 10; i++ {
+				c, err := New(ClientOptions{
+					EndpointResolver: resolver,
+					OutlierDetector:  detector,
+					Logger:           logger,
+				})
+				Expect(err).To(BeNil())
+				c.SetStatsdDelegate(statsd, "api-call", nil)
+				code, _ := c.Get(context.Background())
+				codes = append(codes, code)
+			}
+
+			Expect(detector.Ejected(badEp)).To(BeTrue())
+			Expect(codes[6:]).To(Equal([]int{200, 200, 200, 200}))
+			Expect(string(logger.Bytes())).To(ContainSubstring("endpoint ejected"))
+
+			Expect(statsd.IncrCallCount()).To(Equal(1))
+			name, tags, _ := statsd.IncrArgsForCall(0)
+			Expect(name).To(Equal("api-call.outlier.ejected"))
+			Expect(tags).To(ContainElement("reason:consecutive_errors"))
+		})
+	})
+	// endregion
+})
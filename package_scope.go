@@ -2,6 +2,7 @@ package blaster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -10,7 +11,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/newrelic/go-agent"
 	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 // Defaults is a container for setting package level values
@@ -43,17 +47,85 @@ type Defaults struct {
 
 	// StatsdRate is the statsd reporting rate
 	StatsdRate float64
+
+	// OTelTracerProvider enables OpenTelemetry tracing.  Every request attempt
+	// gets a client span, and its context is injected into the outgoing
+	// headers.  This works alongside TracerProviderFunc.
+	OTelTracerProvider oteltrace.TracerProvider
+
+	// OTelPropagator injects the span context into outgoing requests.
+	// Defaults to W3C traceparent/tracestate and baggage.
+	OTelPropagator propagation.TextMapPropagator
+
+	// EndpointResolver resolves svc:// endpoints, e.g. svc://user-service/v1/users,
+	// for clients that do not bring their own resolver
+	EndpointResolver EndpointResolver
+
+	// Metrics records request metrics for clients that do not bring
+	// their own, see NewStatsdMetrics and NewPrometheusMetrics
+	Metrics Metrics
+
+	// RequestLogging logs every request as a structured line, for
+	// clients that do not bring their own configuration
+	RequestLogging *RequestLogging
+
+	// NewRelicTransactionProviderFunc is a function that provides the
+	// New Relic transaction of the caller.  Every request attempt is
+	// wrapped in an external segment of that transaction.
+	NewRelicTransactionProviderFunc func(ctx context.Context) (newrelic.Transaction, bool)
+
+	// Hooks are called on the lifecycle events of requests, for clients
+	// that do not bring their own
+	Hooks *Hooks
+
+	// MaxTagValues is the number of distinct values each tag carried by a
+	// context may take, see WithTags.  Defaults to 100.
+	MaxTagValues int
+
+	// MaxTagKeys is the number of distinct keys of the tags carried by
+	// contexts that are reported, see WithTags.  Tags with further keys
+	// are dropped.  Defaults to 20, and does not apply with AllowedTagKeys.
+	MaxTagKeys int
+
+	// AllowedTagKeys, if set, are the only keys of the tags carried by
+	// contexts that are reported, see WithTags
+	AllowedTagKeys []string
+
+	// FaultInjector injects faults into requests for chaos testing, for
+	// clients that do not bring their own
+	FaultInjector *FaultInjector
+
+	// ContractValidation validates bodies against a contract, for clients
+	// that do not bring their own
+	ContractValidation *ContractValidation
+
+	// Clock tells the time for clients, outlier detectors, resolvers and
+	// fault injectors that do not bring their own.  Defaults to SystemClock.
+	Clock Clock
 }
 
 var (
-	pkgServiceName               string
-	pkgUserAgent                 string
-	pkgTracerProviderFunc        func(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span)
-	pkgRequestIDProviderFunc     func(cxt context.Context) (string, bool)
-	pkgRequestSourceProviderFunc func(cxt context.Context) (string, bool)
-	pkgOnce                      sync.Once
-	pkgRequireHeaders            bool
-	pkgStatsdRate                float64
+	pkgServiceName                     string
+	pkgUserAgent                       string
+	pkgTracerProviderFunc              func(ctx context.Context, operationName string, r *http.Request) (*http.Request, opentracing.Span)
+	pkgRequestIDProviderFunc           func(cxt context.Context) (string, bool)
+	pkgRequestSourceProviderFunc       func(cxt context.Context) (string, bool)
+	pkgOnce                            sync.Once
+	pkgRequireHeaders                  bool
+	pkgStatsdRate                      float64
+	pkgEndpointResolver                EndpointResolver
+	pkgOTelTracerProvider              oteltrace.TracerProvider
+	pkgOTelPropagator                  propagation.TextMapPropagator
+	pkgMetrics                         Metrics
+	pkgRequestLogging                  *RequestLogging
+	pkgNewRelicTransactionProviderFunc func(ctx context.Context) (newrelic.Transaction, bool)
+	pkgHooks                           *Hooks
+	pkgMaxTagValues                    int
+	pkgMaxTagKeys                      int
+	pkgAllowedTagKeys                  []string
+	pkgFaultInjector                   *FaultInjector
+	pkgContractValidation              *ContractValidation
+	pkgClock                           Clock
 
 	envHTTPMocking = "MOCKING_HTTP"
 )
@@ -100,6 +172,19 @@ func SetDefaults(defaults *Defaults) {
 	pkgRequireHeaders = defaults.RequireHeaders
 	pkgStatsdRate = defaults.StatsdRate
 	pkgTracerProviderFunc = defaults.TracerProviderFunc
+	pkgEndpointResolver = defaults.EndpointResolver
+	pkgOTelTracerProvider = defaults.OTelTracerProvider
+	pkgOTelPropagator = defaults.OTelPropagator
+	pkgMetrics = defaults.Metrics
+	pkgRequestLogging = defaults.RequestLogging
+	pkgNewRelicTransactionProviderFunc = defaults.NewRelicTransactionProviderFunc
+	pkgHooks = defaults.Hooks
+	pkgMaxTagValues = defaults.MaxTagValues
+	pkgMaxTagKeys = defaults.MaxTagKeys
+	pkgAllowedTagKeys = defaults.AllowedTagKeys
+	pkgFaultInjector = defaults.FaultInjector
+	pkgContractValidation = defaults.ContractValidation
+	pkgClock = defaults.Clock
 }
 
 // this creates a http client with sensible defaults
@@ -145,9 +230,15 @@ func NewClient(uri string) (*Client, error) {
 	}
 
 	c := &Client{
-		endpoint: ep,
-		method:   http.MethodGet,
-		client:   newHTTPClient(),
+		endpoint:           ep,
+		method:             http.MethodGet,
+		client:             newHTTPClient(),
+		metrics:            pkgMetrics,
+		requestLogging:     pkgRequestLogging,
+		hooks:              pkgHooks,
+		faults:             pkgFaultInjector,
+		contractValidation: pkgContractValidation,
+		clock:              clockOrDefault(nil),
 		headers: map[string]string{
 			userAgentHeader:      pkgUserAgent,
 			contentTypeHeader:    jsonType,
@@ -159,18 +250,52 @@ func NewClient(uri string) (*Client, error) {
 	return c, nil
 }
 
+// New will initialize and return a new client from the given options.
+// The client's content type defaults to application/json
 func New(opts ClientOptions) (*Client, error) {
 	ensurePackageVariables()
 
-	ep, err := url.ParseRequestURI(opts.Endpoint)
-	if err != nil {
-		return nil, err
+	// when balancing, the endpoint only holds the path and may be empty
+	ep := &url.URL{}
+	if opts.Endpoint != "" || (opts.EndpointResolver == nil && len(opts.Endpoints) == 0) {
+		var err error
+		if ep, err = url.ParseRequestURI(opts.Endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	resolver := opts.EndpointResolver
+	if resolver == nil && len(opts.Endpoints) > 0 {
+		var resolverErr error
+		if resolver, resolverErr = sharedStaticResolver(opts.Endpoints); resolverErr != nil {
+			return nil, resolverErr
+		}
+	}
+
+	// svc:// endpoints name the service, which is resolved on every request
+	calledService := opts.CalledService
+	if ep.Scheme == serviceScheme {
+		if resolver == nil {
+			resolver = pkgEndpointResolver
+		}
+		if resolver == nil {
+			return nil, errors.New("no endpoint resolver set for service endpoint")
+		}
+		if calledService == "" {
+			calledService = ep.Host
+		}
 	}
 
 	c := &Client{
-		endpoint: ep,
-		method:   http.MethodGet,
-		client:   newHTTPClient(),
+		endpoint:           ep,
+		method:             http.MethodGet,
+		client:             newHTTPClient(),
+		metrics:            pkgMetrics,
+		requestLogging:     pkgRequestLogging,
+		hooks:              pkgHooks,
+		faults:             pkgFaultInjector,
+		contractValidation: pkgContractValidation,
+		clock:              clockOrDefault(nil),
 		headers: map[string]string{
 			userAgentHeader:      pkgUserAgent,
 			contentTypeHeader:    jsonType,
@@ -185,16 +310,50 @@ func New(opts ClientOptions) (*Client, error) {
 		}
 	}
 	c.routeMask = opts.RouteMask
-	c.calledService = opts.CalledService
+	c.calledService = calledService
 	c.prototype = opts.WillSaturate
 	c.errorPrototype = opts.WillSaturateOnError
 	c.customPrototypes = opts.WillSaturateWithStatusCode
+	if opts.Transport != nil {
+		client := *c.client
+		client.Transport = opts.Transport
+		c.client = &client
+	}
 	if opts.TimeoutMS > 0 {
-		c.client.Timeout = time.Duration(opts.TimeoutMS) * time.Millisecond
+		c.SetTimeoutMS(opts.TimeoutMS)
 	}
 	c.cb = opts.CircuitBreaker
 	c.keepRawResponse = opts.KeepRawResponse
 	c.logger = opts.Logger
+	if resolver != nil {
+		balancer := opts.Balancer
+		if balancer == nil {
+			balancer = sharedBalancer(resolver, opts.BalancingStrategy)
+		}
+		c.SetEndpointResolver(resolver, balancer)
+	}
+	c.outliers = opts.OutlierDetector
+	c.healthChecker = opts.HealthChecker
+	c.hashKeyHeader = opts.HashKeyHeader
+	if opts.Metrics != nil {
+		c.metrics = opts.Metrics
+	}
+	if opts.RequestLogging != nil {
+		c.requestLogging = opts.RequestLogging
+	}
+	c.nrTxnName = opts.NRTxnName
+	if opts.Hooks != nil {
+		c.hooks = opts.Hooks
+	}
+	if opts.FaultInjector != nil {
+		c.faults = opts.FaultInjector
+	}
+	if opts.ContractValidation != nil {
+		c.contractValidation = opts.ContractValidation
+	}
+	if opts.Clock != nil {
+		c.clock = opts.Clock
+	}
 
 	return c, nil
 }
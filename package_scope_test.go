@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/newrelic/go-agent"
 	"github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
 
@@ -38,6 +39,14 @@ var _ = Describe("PackageScope", func() {
 		pkgRequireHeaders = false
 		pkgStatsdRate = 0
 		pkgTracerProviderFunc = nil
+		pkgEndpointResolver = nil
+		pkgMetrics = nil
+		pkgRequestLogging = nil
+		pkgNewRelicTransactionProviderFunc = nil
+		pkgHooks = nil
+		pkgMaxTagValues = 0
+		pkgMaxTagKeys = 0
+		pkgAllowedTagKeys = nil
 
 		ctx = context.Background()
 		logBytes = []byte{}
@@ -163,14 +172,27 @@ var _ = Describe("PackageScope", func() {
 			})
 		})
 		Context("new relic txn provider", func() {
+			var txn newrelic.Transaction
 			BeforeEach(func() {
 				logrus.SetOutput(logBuffer)
+				txn = &fakeNRTransaction{}
+				defaults.NewRelicTransactionProviderFunc = func(ctx context.Context) (newrelic.Transaction, bool) {
+					return txn, true
+				}
 				SetDefaults(defaults)
 				ensurePackageVariables()
 			})
 			AfterEach(func() {
 				logrus.SetOutput(os.Stderr)
 			})
+			It("sets new relic transaction provider", func() {
+				Expect(pkgNewRelicTransactionProviderFunc).ToNot(BeNil())
+
+				By("testing new relic transaction provider")
+				provided, ok := pkgNewRelicTransactionProviderFunc(ctx)
+				Expect(provided).To(Equal(txn))
+				Expect(ok).To(BeTrue())
+			})
 		})
 	})
 	// endregion
@@ -182,6 +204,13 @@ var _ = Describe("PackageScope", func() {
 				httpClient := newHTTPClient()
 				Expect(httpClient).To(Equal(http.DefaultClient))
 			})
+			It("leaves the default http client alone when a client sets a timeout", func() {
+				c, err := New(ClientOptions{Endpoint: "http://www.invisionapp.com", TimeoutMS: 5})
+				Expect(err).To(BeNil())
+				c.SetTimeoutMS(10)
+				Expect(c.client.Timeout).To(Equal(10 * time.Millisecond))
+				Expect(http.DefaultClient.Timeout).To(BeZero())
+			})
 		})
 		Context("non-testing client", func() {
 			BeforeEach(func() {
@@ -0,0 +1,499 @@
+package blaster
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PactSpecification is the version of the pact files written
+const PactSpecification = "2.0.0"
+
+// Pact is a consumer driven contract: the requests a consumer sends to a
+// provider and the parts of the responses it relies on.  It is written
+// as a pact file, see https://github.com/pact-foundation/pact-specification.
+// Interactions come from blastertest expectations, see
+// blastertest.Server.RecordPact, or from cassettes, see AddCassette.
+type Pact struct {
+	Consumer     PactParticipant   `json:"consumer"`
+	Provider     PactParticipant   `json:"provider"`
+	Interactions []PactInteraction `json:"interactions"`
+	Metadata     PactMetadata      `json:"metadata"`
+
+	mu sync.Mutex
+}
+
+// PactParticipant names the consumer or the provider
+type PactParticipant struct {
+	Name string `json:"name"`
+}
+
+// PactMetadata describes the pact file
+type PactMetadata struct {
+	PactSpecification struct {
+		Version string `json:"version"`
+	} `json:"pactSpecification"`
+}
+
+// PactInteraction is a request and the response the consumer expects
+type PactInteraction struct {
+	Description string `json:"description"`
+
+	// ProviderState is the state the provider must be in, e.g.
+	// "user 42 exists"
+	ProviderState string `json:"providerState,omitempty"`
+
+	Request  PactRequest  `json:"request"`
+	Response PactResponse `json:"response"`
+}
+
+// PactRequest is the request of an interaction
+type PactRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// PactResponse is the part of the response the consumer relies on.  The
+// provider may add headers, and fields to the objects of the body.
+type PactResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// NewPact creates an empty pact between the consumer and the provider
+func NewPact(consumer, provider string) *Pact {
+	p := &Pact{
+		Consumer: PactParticipant{Name: consumer},
+		Provider: PactParticipant{Name: provider},
+	}
+	p.Metadata.PactSpecification.Version = PactSpecification
+
+	return p
+}
+
+// ReadPact reads a pact file
+func ReadPact(path string) (*Pact, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pact{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("pact %s: %s", path, err)
+	}
+
+	return p, nil
+}
+
+// AddInteraction adds an interaction.  An interaction that is already in
+// the pact is not added twice, and a different one with the same
+// description and provider state gets a numbered description.
+func (p *Pact) AddInteraction(interaction PactInteraction) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	description := interaction.Description
+	for n := 2; ; n++ {
+		existing := p.find(interaction.Description, interaction.ProviderState)
+		if existing == nil {
+			break
+		}
+		if reflect.DeepEqual(*existing, interaction) {
+			return
+		}
+		interaction.Description = fmt.Sprintf("%s (%d)", description, n)
+	}
+
+	p.Interactions = append(p.Interactions, interaction)
+}
+
+// find returns the interaction with the description and provider state
+func (p *Pact) find(description, state string) *PactInteraction {
+	for i := range p.Interactions {
+		if p.Interactions[i].Description == description && p.Interactions[i].ProviderState == state {
+			return &p.Interactions[i]
+		}
+	}
+
+	return nil
+}
+
+// AddCassette adds the entries of a cassette as interactions, described
+// by their method and path.  Only the Content-Type headers are kept.
+func (p *Pact) AddCassette(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry cassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("cassette %s line %d: %s", path, line, err)
+		}
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return fmt.Errorf("cassette %s line %d: %s", path, line, err)
+		}
+
+		p.AddInteraction(PactInteraction{
+			Description: fmt.Sprintf("%s %s", entry.Request.Method, u.Path),
+			Request: PactRequest{
+				Method:  entry.Request.Method,
+				Path:    u.Path,
+				Query:   u.RawQuery,
+				Headers: PactHeaders(entry.Request.Header, contentTypeHeader),
+				Body:    PactBody([]byte(entry.Request.Body)),
+			},
+			Response: PactResponse{
+				Status:  entry.Response.StatusCode,
+				Headers: PactHeaders(entry.Response.Header, contentTypeHeader),
+				Body:    PactBody([]byte(entry.Response.Body)),
+			},
+		})
+	}
+
+	return scanner.Err()
+}
+
+// WriteFile writes the pact to dir as <consumer>-<provider>.json,
+// replacing the file, and returns its path
+func (p *Pact) WriteFile(dir string) (string, error) {
+	p.mu.Lock()
+	data, err := json.MarshalIndent(p, "", "  ")
+	p.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.json", pactFileName(p.Consumer.Name), pactFileName(p.Provider.Name)))
+
+	return path, ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// pactFileName makes a participant name safe for a file name
+func pactFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, strings.ToLower(name))
+}
+
+// PactBody returns a body as it is written in a pact: JSON as it is,
+// anything else as a JSON string, and nothing if it is empty
+func PactBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+// PactHeaders keeps the named headers, if they are set
+func PactHeaders(header http.Header, names ...string) map[string]string {
+	kept := map[string]string{}
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			kept[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+
+	return kept
+}
+
+// region verification
+
+// PactVerifier replays the interactions of pacts against a running
+// provider, and checks that it answers as the consumers expect
+type PactVerifier struct {
+	// ProviderURL is the base url of the provider, e.g. http://localhost:8080
+	ProviderURL string
+
+	// StateHandler puts the provider in the state an interaction needs,
+	// before it is replayed
+	StateHandler func(ctx context.Context, state string) error
+
+	// Headers are added to every request, e.g. credentials
+	Headers map[string]string
+
+	// Transport sends the requests instead of the default transport
+	Transport http.RoundTripper
+}
+
+// PactResult is the outcome of replaying an interaction
+type PactResult struct {
+	Description   string
+	ProviderState string
+
+	// Mismatches lists how the response differs from the expected one,
+	// e.g. "$.body.name: expected \"joel\", got \"jo\""
+	Mismatches []string
+}
+
+// OK tells whether the provider answered as expected
+func (r PactResult) OK() bool {
+	return len(r.Mismatches) == 0
+}
+
+// PactVerificationError is returned when a provider breaks a pact
+type PactVerificationError struct {
+	Consumer string
+	Provider string
+	Failed   []PactResult
+}
+
+// Error implements error
+func (e *PactVerificationError) Error() string {
+	lines := make([]string, 0, len(e.Failed))
+	for _, result := range e.Failed {
+		lines = append(lines, fmt.Sprintf("%s: %s", result.Description, strings.Join(result.Mismatches, "; ")))
+	}
+
+	return fmt.Sprintf("%s breaks %d interaction(s) of its pact with %s: %s",
+		e.Provider, len(e.Failed), e.Consumer, strings.Join(lines, ", "))
+}
+
+// VerifyFile verifies the pact file, see Verify
+func (v *PactVerifier) VerifyFile(ctx context.Context, path string) ([]PactResult, error) {
+	p, err := ReadPact(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Verify(ctx, p)
+}
+
+// Verify replays every interaction of the pact, in order, and returns
+// their results.  The error is a *PactVerificationError if any
+// interaction failed.
+func (v *PactVerifier) Verify(ctx context.Context, p *Pact) ([]PactResult, error) {
+	results := make([]PactResult, 0, len(p.Interactions))
+	var failed []PactResult
+	for _, interaction := range p.Interactions {
+		result := PactResult{
+			Description:   interaction.Description,
+			ProviderState: interaction.ProviderState,
+			Mismatches:    v.replay(ctx, interaction),
+		}
+		results = append(results, result)
+		if !result.OK() {
+			failed = append(failed, result)
+		}
+	}
+
+	if len(failed) > 0 {
+		return results, &PactVerificationError{Consumer: p.Consumer.Name, Provider: p.Provider.Name, Failed: failed}
+	}
+
+	return results, nil
+}
+
+// replay sends the request of the interaction and compares the response
+func (v *PactVerifier) replay(ctx context.Context, interaction PactInteraction) []string {
+	if interaction.ProviderState != "" && v.StateHandler != nil {
+		if err := v.StateHandler(ctx, interaction.ProviderState); err != nil {
+			return []string{fmt.Sprintf("provider state %q: %s", interaction.ProviderState, err)}
+		}
+	}
+
+	request := interaction.Request
+	endpoint := strings.TrimRight(v.ProviderURL, "/") + request.Path
+	if request.Query != "" {
+		endpoint += "?" + request.Query
+	}
+	c, err := New(ClientOptions{Endpoint: endpoint, Transport: v.Transport})
+	if err != nil {
+		return []string{err.Error()}
+	}
+	for name, value := range v.Headers {
+		c.SetHeader(http.CanonicalHeaderKey(name), value)
+	}
+	contentType := jsonType
+	for name, value := range request.Headers {
+		if name = http.CanonicalHeaderKey(name); name == contentTypeHeader {
+			contentType = value
+		}
+		c.SetHeader(name, value)
+	}
+	c.SetContentType(contentType)
+
+	_, err = c.Do(ctx, request.Method, pactPayload(request.Body, contentType))
+	response := c.Response()
+	if response == nil || response.StatusCode == 0 {
+		if err == nil {
+			err = errors.New("no response received")
+		}
+		return []string{err.Error()}
+	}
+
+	return comparePactResponse(interaction.Response, response)
+}
+
+// pactPayload returns the payload that sends the recorded body as it is.
+// The client marshals payloads sent as exactly application/json, so the
+// body goes as JSON then, and as bytes with any other content type.
+// Bodies that are not JSON were recorded as JSON strings, see PactBody.
+func pactPayload(body json.RawMessage, contentType string) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	if contentType == jsonType {
+		return body
+	}
+
+	var text string
+	if !strings.Contains(strings.ToLower(contentType), "json") && json.Unmarshal(body, &text) == nil {
+		return []byte(text)
+	}
+
+	return []byte(body)
+}
+
+// comparePactResponse lists how the response differs from the expected one
+func comparePactResponse(expected PactResponse, response *Response) []string {
+	var mismatches []string
+	if expected.Status != response.StatusCode {
+		mismatches = append(mismatches, fmt.Sprintf("status: expected %d, got %d", expected.Status, response.StatusCode))
+	}
+
+	names := make([]string, 0, len(expected.Headers))
+	for name := range expected.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want, got := expected.Headers[name], response.Header.Get(name)
+		if !sameHeaderValue(name, want, got) {
+			mismatches = append(mismatches, fmt.Sprintf("header %s: expected %q, got %q", name, want, got))
+		}
+	}
+
+	if len(expected.Body) == 0 {
+		return mismatches
+	}
+	var want interface{}
+	if err := json.Unmarshal(expected.Body, &want); err != nil {
+		return append(mismatches, fmt.Sprintf("$.body: the pact body is not JSON: %s", err))
+	}
+	var got interface{}
+	if err := json.Unmarshal(response.Body, &got); err != nil {
+		// a body that is not JSON is written in the pact as a string
+		got = string(response.Body)
+	}
+
+	return append(mismatches, matchPactBody("$.body", want, got)...)
+}
+
+// sameHeaderValue compares header values, and only the media types of
+// content types
+func sameHeaderValue(name, want, got string) bool {
+	if http.CanonicalHeaderKey(name) != contentTypeHeader {
+		return want == got
+	}
+
+	wantType, _, err := mime.ParseMediaType(want)
+	if err != nil {
+		return want == got
+	}
+	gotType, _, err := mime.ParseMediaType(got)
+
+	return err == nil && wantType == gotType
+}
+
+// matchPactBody compares a body with the expected one.  Objects may have
+// more fields than expected, everything else must be equal.
+func matchPactBody(path string, want, got interface{}) []string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %s", path, describeJSON(got))}
+		}
+		keys := make([]string, 0, len(w))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var mismatches []string
+		for _, key := range keys {
+			value, ok := g[key]
+			if !ok {
+				mismatches = append(mismatches, fmt.Sprintf("%s.%s: missing", path, key))
+				continue
+			}
+			mismatches = append(mismatches, matchPactBody(path+"."+key, w[key], value)...)
+		}
+		return mismatches
+
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %s", path, describeJSON(got))}
+		}
+		if len(g) != len(w) {
+			return []string{fmt.Sprintf("%s: expected %d item(s), got %d", path, len(w), len(g))}
+		}
+		var mismatches []string
+		for i := range w {
+			mismatches = append(mismatches, matchPactBody(fmt.Sprintf("%s[%d]", path, i), w[i], g[i])...)
+		}
+		return mismatches
+
+	default:
+		if !reflect.DeepEqual(want, got) {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, describeJSON(want), describeJSON(got))}
+		}
+		return nil
+	}
+}
+
+// describeJSON renders a decoded JSON value for a mismatch
+func describeJSON(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	}
+
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
+
+// endregion
@@ -0,0 +1,251 @@
+package blaster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pact", func() {
+	var (
+		ctx      context.Context
+		dir      string
+		provider *httptest.Server
+		states   []string
+		answer   func(w http.ResponseWriter, r *http.Request)
+	)
+
+	// getUser is the interaction of the tests
+	getUser := PactInteraction{
+		Description:   "a request for user 42",
+		ProviderState: "user 42 exists",
+		Request:       PactRequest{Method: http.MethodGet, Path: "/v1/users/42", Query: "fields=all"},
+		Response: PactResponse{
+			Status:  http.StatusOK,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    json.RawMessage(`{"id":"42","name":"joel","roles":["admin"],"address":{"city":"nyc"}}`),
+		},
+	}
+
+	// verify replays the pact against the provider
+	verify := func(p *Pact) ([]PactResult, error) {
+		verifier := &PactVerifier{
+			ProviderURL: provider.URL,
+			StateHandler: func(ctx context.Context, state string) error {
+				states = append(states, state)
+				return nil
+			},
+		}
+		return verifier.Verify(ctx, p)
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		dir, _ = ioutil.TempDir("", "pact")
+		states = nil
+		answer = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(contentTypeHeader, "application/json; charset=utf-8")
+			w.Write([]byte(`{"id":"42","name":"joel","email":"joel@example.com","roles":["admin"],"address":{"city":"nyc","zip":"10001"}}`))
+		}
+		provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			answer(w, r)
+		}))
+	})
+
+	AfterEach(func() {
+		provider.Close()
+		os.RemoveAll(dir)
+	})
+
+	// region pact files
+
+	It("writes and reads pact files", func() {
+		p := NewPact("order-service", "user-service")
+		p.AddInteraction(getUser)
+
+		path, err := p.WriteFile(filepath.Join(dir, "pacts"))
+		Expect(err).To(BeNil())
+		Expect(filepath.Base(path)).To(Equal("order-service-user-service.json"))
+
+		var raw map[string]interface{}
+		data, _ := ioutil.ReadFile(path)
+		Expect(json.Unmarshal(data, &raw)).To(Succeed())
+		Expect(raw["metadata"]).To(Equal(map[string]interface{}{"pactSpecification": map[string]interface{}{"version": "2.0.0"}}))
+		Expect(raw["consumer"]).To(Equal(map[string]interface{}{"name": "order-service"}))
+
+		read, err := ReadPact(path)
+		Expect(err).To(BeNil())
+		Expect(read.Provider.Name).To(Equal("user-service"))
+		Expect(read.Interactions).To(HaveLen(1))
+		Expect(read.Interactions[0].Request).To(Equal(getUser.Request))
+		Expect(read.Interactions[0].Response.Status).To(Equal(http.StatusOK))
+	})
+
+	It("adds an interaction once, and numbers different ones with the same description", func() {
+		p := NewPact("order-service", "user-service")
+		p.AddInteraction(getUser)
+		p.AddInteraction(getUser)
+
+		other := getUser
+		other.Request.Query = ""
+		p.AddInteraction(other)
+
+		Expect(p.Interactions).To(HaveLen(2))
+		Expect(p.Interactions[1].Description).To(Equal("a request for user 42 (2)"))
+	})
+
+	It("turns cassette entries into interactions", func() {
+		cassettePath := filepath.Join(dir, "users.jsonl")
+		cassette, err := NewCassette(cassettePath, CassetteConfig{Mode: CassetteRecord, Transport: http.DefaultTransport})
+		Expect(err).To(BeNil())
+		c, _ := New(ClientOptions{Endpoint: provider.URL + "/v1/users/42?fields=all", Transport: cassette})
+		c.Get(ctx)
+		cassette.Close()
+
+		p := NewPact("order-service", "user-service")
+		Expect(p.AddCassette(cassettePath)).To(Succeed())
+		Expect(p.Interactions).To(HaveLen(1))
+		interaction := p.Interactions[0]
+		Expect(interaction.Description).To(Equal("GET /v1/users/42"))
+		Expect(interaction.Request.Query).To(Equal("fields=all"))
+		Expect(interaction.Request.Headers).To(Equal(map[string]string{"Content-Type": jsonType}))
+		Expect(interaction.Response.Headers).To(Equal(map[string]string{"Content-Type": "application/json; charset=utf-8"}))
+		Expect(string(interaction.Response.Body)).To(ContainSubstring(`"email":"joel@example.com"`))
+
+		Expect(p.AddCassette(filepath.Join(dir, "missing.jsonl"))).ToNot(Succeed())
+	})
+
+	It("writes bodies that are not JSON as strings", func() {
+		Expect(string(PactBody([]byte("plain")))).To(Equal(`"plain"`))
+		Expect(string(PactBody([]byte(`{"a":1}`)))).To(Equal(`{"a":1}`))
+		Expect(PactBody(nil)).To(BeNil())
+		Expect(PactHeaders(http.Header{"X-Other": {"1"}}, "Content-Type")).To(BeNil())
+	})
+
+	// endregion
+
+	// region verification
+
+	It("passes providers that answer as the consumer expects", func() {
+		var query string
+		inner := answer
+		answer = func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			inner(w, r)
+		}
+		p := NewPact("order-service", "user-service")
+		p.AddInteraction(getUser)
+
+		results, err := verify(p)
+		Expect(err).To(BeNil())
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].OK()).To(BeTrue())
+		Expect(states).To(Equal([]string{"user 42 exists"}))
+		Expect(query).To(Equal("fields=all"))
+	})
+
+	It("reports how providers break the pact", func() {
+		answer = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(contentTypeHeader, "text/plain")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id":42,"roles":["admin","owner"],"address":"nyc"}`))
+		}
+		p := NewPact("order-service", "user-service")
+		p.AddInteraction(getUser)
+
+		results, err := verify(p)
+		var verificationErr *PactVerificationError
+		Expect(errors.As(err, &verificationErr)).To(BeTrue())
+		Expect(verificationErr.Failed).To(HaveLen(1))
+		Expect(err.Error()).To(HavePrefix("user-service breaks 1 interaction(s) of its pact with order-service: a request for user 42: "))
+		Expect(results[0].Mismatches).To(Equal([]string{
+			"status: expected 200, got 202",
+			`header Content-Type: expected "application/json", got "text/plain"`,
+			"$.body.address: expected an object, got \"nyc\"",
+			`$.body.id: expected "42", got 42`,
+			"$.body.name: missing",
+			"$.body.roles: expected 1 item(s), got 2",
+		}))
+	})
+
+	It("compares the items of arrays and bodies that are not JSON", func() {
+		answer = func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Write(append([]byte("got "), body...))
+		}
+		p := NewPact("order-service", "user-service")
+		p.AddInteraction(PactInteraction{
+			Description: "a note",
+			Request: PactRequest{
+				Method:  http.MethodPost,
+				Path:    "/v1/notes",
+				Headers: map[string]string{"Content-Type": "text/plain"},
+				Body:    PactBody([]byte("hello")),
+			},
+			Response: PactResponse{Status: http.StatusOK, Body: PactBody([]byte("got hello"))},
+		})
+		p.AddInteraction(PactInteraction{
+			Description: "a list",
+			Request:     PactRequest{Method: http.MethodGet, Path: "/v1/list"},
+			Response:    PactResponse{Status: http.StatusOK, Body: json.RawMessage(`[1,2]`)},
+		})
+
+		results, err := verify(p)
+		Expect(err).ToNot(BeNil())
+		Expect(results[0].OK()).To(BeTrue())
+		Expect(results[1].Mismatches).To(Equal([]string{"$.body: expected an array, got \"got \""}))
+	})
+
+	It("replays JSON bodies of any JSON content type as they were recorded", func() {
+		var bodies, contentTypes []string
+		answer = func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(body))
+			contentTypes = append(contentTypes, r.Header.Get(contentTypeHeader))
+		}
+		p := NewPact("order-service", "user-service")
+		for _, contentType := range []string{"application/json", "application/json; charset=utf-8", "application/vnd.api+json"} {
+			p.AddInteraction(PactInteraction{
+				Description: "a user as " + contentType,
+				Request: PactRequest{
+					Method:  http.MethodPost,
+					Path:    "/v1/users",
+					Headers: map[string]string{"content-type": contentType},
+					Body:    json.RawMessage(`{"name":"joel"}`),
+				},
+				Response: PactResponse{Status: http.StatusOK},
+			})
+		}
+
+		_, err := verify(p)
+		Expect(err).To(BeNil())
+		Expect(bodies).To(Equal([]string{`{"name":"joel"}`, `{"name":"joel"}`, `{"name":"joel"}`}))
+		Expect(contentTypes).To(Equal([]string{"application/json", "application/json; charset=utf-8", "application/vnd.api+json"}))
+	})
+
+	It("fails interactions whose provider state cannot be set up, or that get no response", func() {
+		p := NewPact("order-service", "user-service")
+		p.AddInteraction(getUser)
+		verifier := &PactVerifier{
+			ProviderURL:  provider.URL,
+			StateHandler: func(context.Context, string) error { return errors.New("no database") },
+		}
+		results, _ := verifier.Verify(ctx, p)
+		Expect(results[0].Mismatches).To(Equal([]string{`provider state "user 42 exists": no database`}))
+
+		provider.Close()
+		results, _ = (&PactVerifier{ProviderURL: provider.URL}).Verify(ctx, p)
+		Expect(results[0].OK()).To(BeFalse())
+	})
+
+	// endregion
+})
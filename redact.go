@@ -0,0 +1,146 @@
+package blaster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// redactedValue replaces redacted header values and body fields
+const redactedValue = "[REDACTED]"
+
+// DefaultRedactedHeaders are always redacted
+var DefaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// Redaction selects what is blanked out of logged or stored requests
+type Redaction struct {
+	// Headers are redacted on top of DefaultRedactedHeaders
+	Headers []string
+
+	// Fields are JSON body fields, given as dot separated paths such as
+	// "password" or "user.card.number".  A * matches any key, and arrays
+	// are walked transparently, so "items.token" covers every item.
+	Fields []string
+
+	// QueryParams are url query parameters, such as "api_key"
+	QueryParams []string
+}
+
+// redactsHeader tells whether the header is redacted
+func (r Redaction) redactsHeader(name string) bool {
+	for _, lists := range [][]string{DefaultRedactedHeaders, r.Headers} {
+		for _, header := range lists {
+			if strings.EqualFold(header, name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// header returns the value of a header, or redactedValue if it is redacted
+func (r Redaction) header(name, value string) string {
+	if r.redactsHeader(name) {
+		return redactedValue
+	}
+
+	return value
+}
+
+// headers returns a copy of the headers with the redacted ones blanked out
+func (r Redaction) headers(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		if r.redactsHeader(name) {
+			redacted[name] = []string{redactedValue}
+			continue
+		}
+		redacted[name] = append([]string(nil), values...)
+	}
+
+	return redacted
+}
+
+// url returns the url with the redacted query parameters blanked out
+func (r Redaction) url(u *url.URL) string {
+	if len(r.QueryParams) == 0 || u.RawQuery == "" {
+		return u.String()
+	}
+
+	query := u.Query()
+	for name, values := range query {
+		for _, param := range r.QueryParams {
+			if strings.EqualFold(param, name) {
+				for i := range values {
+					values[i] = redactedValue
+				}
+			}
+		}
+	}
+
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// body blanks out the redacted fields of a JSON body.  Bodies that are
+// not JSON, or when there are no fields to redact, are returned as is.
+func (r Redaction) body(body []byte) []byte {
+	if len(r.Fields) == 0 || len(body) == 0 {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return body
+	}
+
+	for _, field := range r.Fields {
+		redactPath(doc, strings.Split(field, "."))
+	}
+
+	redacted, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+
+	return redacted
+}
+
+// redactPath blanks out the value at path in a decoded JSON document
+func redactPath(doc interface{}, path []string) {
+	switch node := doc.(type) {
+	case []interface{}:
+		for _, item := range node {
+			redactPath(item, path)
+		}
+	case map[string]interface{}:
+		for key, value := range node {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			if len(path) == 1 {
+				node[key] = redactedValue
+			} else {
+				redactPath(value, path[1:])
+			}
+		}
+	}
+}
+
+// truncateBody shortens a body to at most max bytes, and notes how much
+// was cut.  A max of 0 or less leaves the body whole.
+func truncateBody(body []byte, max int) string {
+	if max <= 0 || len(body) <= max {
+		return string(body)
+	}
+
+	return fmt.Sprintf("%s...(%d bytes truncated)", body[:max], len(body)-max)
+}
@@ -0,0 +1,96 @@
+package blaster
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Request describes the last attempt as it was sent, with the headers
+// added by the client, the context and the tracers
+type Request struct {
+	// Method is the http method
+	Method string
+
+	// URL is the url the attempt was sent to
+	URL *url.URL
+
+	// Header holds the headers that were sent
+	Header http.Header
+
+	// Body is the encoded payload
+	Body []byte
+
+	// redaction and maxBody apply to AsCurl
+	redaction Redaction
+	maxBody   int
+}
+
+// Request returns the last attempt that was sent.  It is nil until a
+// request has been launched.
+func (c *Client) Request() *Request {
+	return c.request
+}
+
+// recordRequest keeps what is about to be sent
+func (c *Client) recordRequest(request *http.Request, body []byte) {
+	redaction, maxBody := c.redaction()
+	c.request = &Request{
+		Method:    request.Method,
+		URL:       request.URL,
+		Header:    request.Header,
+		Body:      body,
+		redaction: redaction,
+		maxBody:   maxBody,
+	}
+}
+
+// redaction returns what the request logging redacts and how much of a
+// body it keeps
+func (c *Client) redaction() (Redaction, int) {
+	if c.requestLogging == nil {
+		return Redaction{}, defaultMaxLoggedBody
+	}
+
+	maxBody := c.requestLogging.MaxBodySize
+	if maxBody == 0 {
+		maxBody = defaultMaxLoggedBody
+	}
+
+	return c.requestLogging.Redaction, maxBody
+}
+
+// AsCurl renders the request as a curl command, with secrets redacted and
+// the body truncated as configured by RequestLogging
+func (r *Request) AsCurl() string {
+	parts := []string{"curl", "-X", r.Method, shellQuote(r.redaction.url(r.URL))}
+
+	header := r.redaction.headers(r.Header)
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// curl works out the length of the body itself
+		if name == "Content-Length" {
+			continue
+		}
+		for _, value := range header[name] {
+			parts = append(parts, "-H", shellQuote(fmt.Sprintf("%s: %s", name, value)))
+		}
+	}
+
+	if len(r.Body) > 0 {
+		parts = append(parts, "--data-raw", shellQuote(truncateBody(r.redaction.body(r.Body), r.maxBody)))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
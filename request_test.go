@@ -0,0 +1,152 @@
+package blaster
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request", func() {
+	var (
+		ctx      context.Context
+		server   *httptest.Server
+		status   int
+		received *http.Request
+		body     []byte
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		status = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = ioutil.ReadAll(r.Body)
+			w.Header().Set("Set-Cookie", "session=secret")
+			w.Header().Set("X-Answer", "42")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"token":"abc","name":"joel"}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// region AsCurl
+
+	It("is nil until a request was launched", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		Expect(c.Request()).To(BeNil())
+	})
+
+	It("holds the headers that were actually sent", func() {
+		pkgRequestIDProviderFunc = func(ctx context.Context) (string, bool) { return "req-1", true }
+		defer func() { pkgRequestIDProviderFunc = nil }()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.SetHeader("X-Custom", "yes")
+		c.Post(ctx, map[string]string{"name": "joel"})
+
+		request := c.Request()
+		Expect(request.Method).To(Equal(http.MethodPost))
+		Expect(request.URL.String()).To(Equal(server.URL))
+		Expect(request.Body).To(Equal(body))
+		for name := range received.Header {
+			if name == "Accept-Encoding" || name == "User-Agent" || name == "Content-Length" {
+				continue
+			}
+			Expect(request.Header.Get(name)).To(Equal(received.Header.Get(name)), name)
+		}
+		Expect(request.Header.Get(requestIDHeader)).To(Equal("req-1"))
+	})
+
+	It("renders a curl command with secrets redacted", func() {
+		pkgRequestIDProviderFunc = func(ctx context.Context) (string, bool) { return "req-1", true }
+		defer func() { pkgRequestIDProviderFunc = nil }()
+
+		c, _ := New(ClientOptions{Endpoint: server.URL + "/users?q=it's", RequestLogging: &RequestLogging{
+			Redaction: Redaction{Fields: []string{"password"}},
+		}})
+		c.SetHeader("Authorization", "Bearer secret")
+		c.SetHeader("X-Custom", "yes")
+		c.Post(ctx, map[string]string{"name": "joel", "password": "hunter2"})
+
+		curl := c.Request().AsCurl()
+		Expect(curl).To(HavePrefix("curl -X POST '" + server.URL + "/users?q=it'\\''s'"))
+		Expect(curl).To(ContainSubstring("-H 'Authorization: " + redactedValue + "'"))
+		Expect(curl).To(ContainSubstring("-H 'X-Custom: yes'"))
+		Expect(curl).To(ContainSubstring("-H 'Request-Id: req-1'"))
+		Expect(curl).To(ContainSubstring(`--data-raw '{"name":"joel","password":"` + redactedValue + `"}'`))
+		Expect(curl).ToNot(ContainSubstring("Content-Length"))
+		Expect(curl).ToNot(ContainSubstring("secret"))
+		Expect(curl).ToNot(ContainSubstring("hunter2"))
+	})
+
+	It("truncates the body of the curl command", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, RequestLogging: &RequestLogging{MaxBodySize: 5}})
+		c.Post(ctx, map[string]string{"name": "joel"})
+		Expect(c.Request().AsCurl()).To(ContainSubstring(`--data-raw '{"nam...(`))
+	})
+
+	// endregion
+
+	// region Dump
+
+	It("dumps the response with secrets redacted", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, RequestLogging: &RequestLogging{
+			Redaction: Redaction{Fields: []string{"token"}},
+		}})
+		c.Get(ctx)
+
+		dump := c.Response().Dump()
+		Expect(dump).To(HavePrefix("200 OK\n"))
+		Expect(dump).To(ContainSubstring("Set-Cookie: " + redactedValue + "\n"))
+		Expect(dump).To(ContainSubstring("X-Answer: 42\n"))
+		Expect(dump).To(HaveSuffix("\n\n" + `{"name":"joel","token":"` + redactedValue + `"}`))
+	})
+
+	It("dumps a failed request", func() {
+		c, _ := New(ClientOptions{Endpoint: "http://127.0.0.1:1"})
+		c.Get(ctx)
+		Expect(c.Response().Dump()).To(Equal("no response received"))
+	})
+
+	// endregion
+
+	// region CurlOnFailure
+
+	It("logs the curl command of server errors", func() {
+		status = http.StatusServiceUnavailable
+		logger := testlog.New()
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{CurlOnFailure: true}})
+		c.SetHeader("Authorization", "Bearer secret")
+		c.Get(ctx)
+
+		out := string(logger.Bytes())
+		Expect(out).To(ContainSubstring("curl=curl -X GET '" + server.URL + "'"))
+		Expect(out).ToNot(ContainSubstring("Bearer secret"))
+	})
+
+	It("logs the curl command of transport errors", func() {
+		logger := testlog.New()
+		c, _ := New(ClientOptions{Endpoint: "http://127.0.0.1:1", Logger: logger, RequestLogging: &RequestLogging{CurlOnFailure: true}})
+		c.Get(ctx)
+		Expect(string(logger.Bytes())).To(ContainSubstring("curl=curl -X GET 'http://127.0.0.1:1'"))
+	})
+
+	It("does not log the curl command of successes", func() {
+		logger := testlog.New()
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger, RequestLogging: &RequestLogging{CurlOnFailure: true}})
+		c.Get(ctx)
+		Expect(strings.Contains(string(logger.Bytes()), "curl=")).To(BeFalse())
+	})
+
+	// endregion
+})
@@ -0,0 +1,94 @@
+package blaster
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// serviceScheme is the scheme of endpoints that name a called service
+// rather than a host, e.g. svc://user-service/v1/users.  They are
+// resolved to live instances on every request.
+const serviceScheme = "svc"
+
+// errNoService is returned when a resolver knows nothing about a service
+func errNoService(service string) error {
+	return fmt.Errorf("no endpoints found for service %s", service)
+}
+
+// resolveName is the name handed to the resolver.  For svc:// endpoints
+// this is the host part of the url, otherwise it is the called service.
+func (c *Client) resolveName() string {
+	if c.endpoint != nil && c.endpoint.Scheme == serviceScheme {
+		return c.endpoint.Host
+	}
+
+	return c.calledService
+}
+
+// reuseEndpoints carries endpoints that are still present, unchanged, over
+// from the previous set, so that state kept on them survives a refresh
+func reuseEndpoints(previous []*Endpoint, next []*Endpoint) []*Endpoint {
+	known := make(map[string]*Endpoint, len(previous))
+	for _, ep := range previous {
+		known[ep.URL.String()] = ep
+	}
+
+	for i, ep := range next {
+		if prev, ok := known[ep.URL.String()]; ok && prev.Weight == ep.Weight {
+			next[i] = prev
+		}
+	}
+
+	return next
+}
+
+// sameEndpoints determines whether two sets hold the same urls in the same order
+func sameEndpoints(a []*Endpoint, b []*Endpoint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].URL.String() != b[i].URL.String() || a[i].weight() != b[i].weight() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pollWatch calls resolve on every interval and publishes the set of
+// endpoints whenever it changes.  The current set is published first.
+// The channel is closed once the context is done.
+func pollWatch(ctx context.Context, clock Clock, interval time.Duration, resolve func() ([]*Endpoint, error)) <-chan []*Endpoint {
+	updates := make(chan []*Endpoint, 1)
+
+	go func() {
+		defer close(updates)
+
+		ticker := clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last []*Endpoint
+		first := true
+		for {
+			if endpoints, err := resolve(); err == nil && (first || !sameEndpoints(last, endpoints)) {
+				select {
+				case updates <- endpoints:
+					last = endpoints
+					first = false
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates
+}
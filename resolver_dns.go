@@ -0,0 +1,229 @@
+package blaster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDNSTTL is how long SRV answers are cached when no TTL is given
+	defaultDNSTTL = 30 * time.Second
+
+	// defaultDNSLookupTimeout bounds a lookup when no LookupTimeout is given
+	defaultDNSLookupTimeout = 5 * time.Second
+)
+
+// dnsCacheEntry is a cached SRV answer
+type dnsCacheEntry struct {
+	endpoints []*Endpoint
+	expires   time.Time
+}
+
+// dnsLookup is an SRV lookup in flight, shared by every caller that
+// resolves the service until it completes
+type dnsLookup struct {
+	done      chan struct{}
+	endpoints []*Endpoint
+	err       error
+}
+
+// DNSResolver resolves called services with DNS SRV lookups.  Answers are
+// cached for the TTL.  If a lookup fails after the TTL has expired, the
+// previous answer keeps being served until a lookup succeeds again.
+type DNSResolver struct {
+	// Scheme is the scheme of the instance urls.  Defaults to http.
+	Scheme string
+
+	// Proto is the SRV protocol.  Defaults to tcp.
+	Proto string
+
+	// Domain is appended to the service to form the SRV name, which is
+	// then _service._proto.domain.  If it is empty, the service is looked
+	// up as a full SRV name instead.
+	Domain string
+
+	// TTL is how long answers are cached.  Defaults to 30 seconds.
+	TTL time.Duration
+
+	// Clock tells when answers expire.  Defaults to Defaults.Clock.
+	Clock Clock
+
+	// LookupTimeout bounds a single SRV lookup.  Defaults to 5 seconds.
+	LookupTimeout time.Duration
+
+	// lookupSRV is swapped out in tests
+	lookupSRV func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+
+	balancers balancerSet
+
+	mu       sync.Mutex
+	cache    map[string]dnsCacheEntry
+	inflight map[string]*dnsLookup
+}
+
+// NewDNSResolver returns a resolver that looks up _service._tcp.domain SRV
+// records, caching the answers for the ttl
+func NewDNSResolver(domain string, ttl time.Duration) *DNSResolver {
+	return &DNSResolver{
+		Domain: domain,
+		TTL:    ttl,
+	}
+}
+
+// Resolve implements EndpointResolver.  The lookup runs outside the lock,
+// and callers that resolve the same service meanwhile wait for it rather
+// than sending their own.  It runs detached from the context of the
+// caller that started it, so the context of each caller only ends the
+// wait of that caller.
+func (r *DNSResolver) Resolve(ctx context.Context, service string) ([]*Endpoint, error) {
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = map[string]dnsCacheEntry{}
+		r.inflight = map[string]*dnsLookup{}
+	}
+
+	clock := clockOrDefault(r.Clock)
+	cached, ok := r.cache[service]
+	if ok && clock.Now().Before(cached.expires) {
+		r.mu.Unlock()
+		return cached.endpoints, nil
+	}
+
+	pending, running := r.inflight[service]
+	if !running {
+		pending = &dnsLookup{done: make(chan struct{})}
+		r.inflight[service] = pending
+		go r.runLookup(service, pending)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-pending.done:
+	case <-ctx.Done():
+		if ok && len(cached.endpoints) > 0 {
+			return cached.endpoints, nil
+		}
+		return nil, ctx.Err()
+	}
+
+	if pending.err != nil {
+		if ok && len(cached.endpoints) > 0 {
+			return cached.endpoints, nil
+		}
+		return nil, pending.err
+	}
+	if len(pending.endpoints) == 0 {
+		return nil, errNoService(service)
+	}
+
+	return pending.endpoints, nil
+}
+
+// runLookup looks the service up, publishes the answer to the cache and
+// to the callers waiting on the lookup
+func (r *DNSResolver) runLookup(service string, pending *dnsLookup) {
+	timeout := r.LookupTimeout
+	if timeout <= 0 {
+		timeout = defaultDNSLookupTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	endpoints, err := r.lookup(ctx, service)
+
+	r.mu.Lock()
+	if err == nil && len(endpoints) > 0 {
+		endpoints = reuseEndpoints(r.cache[service].endpoints, endpoints)
+		r.cache[service] = dnsCacheEntry{
+			endpoints: endpoints,
+			expires:   clockOrDefault(r.Clock).Now().Add(r.ttl()),
+		}
+	}
+	pending.endpoints, pending.err = endpoints, err
+	delete(r.inflight, service)
+	r.mu.Unlock()
+
+	close(pending.done)
+}
+
+// Balancer implements BalancerSource
+func (r *DNSResolver) Balancer(strategy BalancingStrategy) Balancer {
+	return r.balancers.get(strategy)
+}
+
+// Watch implements EndpointWatcher
+func (r *DNSResolver) Watch(ctx context.Context, service string) (<-chan []*Endpoint, error) {
+	return pollWatch(ctx, clockOrDefault(r.Clock), r.ttl(), func() ([]*Endpoint, error) {
+		return r.Resolve(ctx, service)
+	}), nil
+}
+
+// lookup runs the SRV query and turns the answer into endpoints.  Only
+// the records with the best (lowest) priority are used, SRV weights
+// carry over as endpoint weights.
+func (r *DNSResolver) lookup(ctx context.Context, service string) ([]*Endpoint, error) {
+	lookupSRV := r.lookupSRV
+	if lookupSRV == nil {
+		lookupSRV = net.DefaultResolver.LookupSRV
+	}
+
+	var (
+		records []*net.SRV
+		err     error
+	)
+	if r.Domain == "" {
+		_, records, err = lookupSRV(ctx, "", "", service)
+	} else {
+		_, records, err = lookupSRV(ctx, service, r.proto(), r.Domain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]*Endpoint, 0, len(records))
+	for _, record := range records {
+		if record.Priority != records[0].Priority {
+			continue
+		}
+
+		host := strings.TrimSuffix(record.Target, ".")
+		endpoints = append(endpoints, &Endpoint{
+			URL: &url.URL{
+				Scheme: r.scheme(),
+				Host:   net.JoinHostPort(host, fmt.Sprintf("%d", record.Port)),
+			},
+			Weight: int(record.Weight),
+		})
+	}
+
+	return endpoints, nil
+}
+
+func (r *DNSResolver) scheme() string {
+	if r.Scheme == "" {
+		return "http"
+	}
+
+	return r.Scheme
+}
+
+func (r *DNSResolver) proto() string {
+	if r.Proto == "" {
+		return "tcp"
+	}
+
+	return r.Proto
+}
+
+func (r *DNSResolver) ttl() time.Duration {
+	if r.TTL <= 0 {
+		return defaultDNSTTL
+	}
+
+	return r.TTL
+}
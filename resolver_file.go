@@ -0,0 +1,169 @@
+package blaster
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// defaultFileCheckInterval is how often the file is checked for changes
+const defaultFileCheckInterval = 5 * time.Second
+
+// fileInstance is a single instance as it appears in the file
+type fileInstance struct {
+	URL    string `json:"url" yaml:"url"`
+	Weight int    `json:"weight" yaml:"weight"`
+}
+
+// FileResolver resolves called services from a JSON or YAML file that maps
+// each service name to its instances, e.g.
+//
+//	user-service:
+//	  - url: http://10.0.0.1:8080
+//	    weight: 2
+//	  - url: http://10.0.0.2:8080
+//
+// Files ending in .yaml or .yml are read as YAML, anything else as JSON.
+// The file is re-read when its modification time or size changes.  If a
+// re-read fails, the last good set of instances keeps being served.
+type FileResolver struct {
+	// Clock times the checks for changes.  Defaults to Defaults.Clock.
+	Clock Clock
+
+	balancers balancerSet
+
+	path     string
+	interval time.Duration
+
+	mu       sync.RWMutex
+	checked  time.Time
+	modTime  time.Time
+	size     int64
+	services map[string][]*Endpoint
+}
+
+// NewFileResolver loads the file at path and returns a resolver for it.
+// The file is checked for changes at most once per interval, which
+// defaults to 5 seconds.
+func NewFileResolver(path string, interval time.Duration) (*FileResolver, error) {
+	if interval <= 0 {
+		interval = defaultFileCheckInterval
+	}
+
+	r := &FileResolver{
+		path:     path,
+		interval: interval,
+	}
+
+	if err := r.refresh(true); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Resolve implements EndpointResolver
+func (r *FileResolver) Resolve(ctx context.Context, service string) ([]*Endpoint, error) {
+	r.mu.RLock()
+	stale := isStale(clockOrDefault(r.Clock), r.checked, r.interval)
+	r.mu.RUnlock()
+
+	if stale {
+		// keep serving the last good set if the file went bad
+		r.refresh(false)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	endpoints, ok := r.services[service]
+	if !ok || len(endpoints) == 0 {
+		return nil, errNoService(service)
+	}
+
+	return endpoints, nil
+}
+
+// Balancer implements BalancerSource
+func (r *FileResolver) Balancer(strategy BalancingStrategy) Balancer {
+	return r.balancers.get(strategy)
+}
+
+// Watch implements EndpointWatcher
+func (r *FileResolver) Watch(ctx context.Context, service string) (<-chan []*Endpoint, error) {
+	return pollWatch(ctx, clockOrDefault(r.Clock), r.interval, func() ([]*Endpoint, error) {
+		return r.Resolve(ctx, service)
+	}), nil
+}
+
+// refresh re-reads the file if it changed since it was last read
+func (r *FileResolver) refresh(force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checked = clockOrDefault(r.Clock).Now()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	if !force && info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return nil
+	}
+
+	services, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	for name, endpoints := range services {
+		services[name] = reuseEndpoints(r.services[name], endpoints)
+	}
+
+	r.services = services
+	r.modTime = info.ModTime()
+	r.size = info.Size()
+
+	return nil
+}
+
+// load reads and parses the file
+func (r *FileResolver) load() (map[string][]*Endpoint, error) {
+	data, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances map[string][]fileInstance
+	switch strings.ToLower(filepath.Ext(r.path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &instances)
+	default:
+		err = json.Unmarshal(data, &instances)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	services := make(map[string][]*Endpoint, len(instances))
+	for name, list := range instances {
+		endpoints := make([]*Endpoint, 0, len(list))
+		for _, instance := range list {
+			ep, err := NewEndpoint(instance.URL, instance.Weight)
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, ep)
+		}
+		services[name] = endpoints
+	}
+
+	return services, nil
+}
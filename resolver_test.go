@@ -0,0 +1,296 @@
+package blaster
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolvers", func() {
+	var (
+		ctx context.Context
+		dir string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+
+		var err error
+		dir, err = ioutil.TempDir("", "blaster")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	// region FileResolver
+	Describe("FileResolver", func() {
+		Context("json", func() {
+			It("resolves the instances of a service", func() {
+				path := filepath.Join(dir, "services.json")
+				Expect(ioutil.WriteFile(path, []byte(`{"user-service":[{"url":"http://10.0.0.1:8080","weight":2},{"url":"http://10.0.0.2:8080"}]}`), 0644)).To(Succeed())
+
+				r, err := NewFileResolver(path, time.Millisecond)
+				Expect(err).To(BeNil())
+
+				endpoints, err := r.Resolve(ctx, "user-service")
+				Expect(err).To(BeNil())
+				Expect(endpoints).To(HaveLen(2))
+				Expect(endpoints[0].URL.Host).To(Equal("10.0.0.1:8080"))
+				Expect(endpoints[0].Weight).To(Equal(2))
+
+				_, err = r.Resolve(ctx, "other-service")
+				Expect(err).ToNot(BeNil())
+			})
+		})
+		Context("yaml", func() {
+			It("re-reads the file when it changes", func() {
+				path := filepath.Join(dir, "services.yaml")
+				Expect(ioutil.WriteFile(path, []byte("user-service:\n  - url: http://10.0.0.1:8080\n"), 0644)).To(Succeed())
+
+				r, err := NewFileResolver(path, time.Millisecond)
+				Expect(err).To(BeNil())
+
+				before, err := r.Resolve(ctx, "user-service")
+				Expect(err).To(BeNil())
+				Expect(before).To(HaveLen(1))
+
+				Expect(ioutil.WriteFile(path, []byte("user-service:\n  - url: http://10.0.0.1:8080\n  - url: http://10.0.0.3:8080\n"), 0644)).To(Succeed())
+				time.Sleep(5 * time.Millisecond)
+
+				after, err := r.Resolve(ctx, "user-service")
+				Expect(err).To(BeNil())
+				Expect(after).To(HaveLen(2))
+				Expect(after[0]).To(BeIdenticalTo(before[0]))
+			})
+			It("keeps the last good instances when the file goes bad", func() {
+				path := filepath.Join(dir, "services.yml")
+				Expect(ioutil.WriteFile(path, []byte("user-service:\n  - url: http://10.0.0.1:8080\n"), 0644)).To(Succeed())
+
+				r, err := NewFileResolver(path, time.Millisecond)
+				Expect(err).To(BeNil())
+
+				Expect(ioutil.WriteFile(path, []byte(":::not yaml at all"), 0644)).To(Succeed())
+				time.Sleep(5 * time.Millisecond)
+
+				endpoints, err := r.Resolve(ctx, "user-service")
+				Expect(err).To(BeNil())
+				Expect(endpoints).To(HaveLen(1))
+			})
+		})
+		Context("watch", func() {
+			It("publishes changes until the context is done", func() {
+				path := filepath.Join(dir, "services.json")
+				Expect(ioutil.WriteFile(path, []byte(`{"user-service":[{"url":"http://10.0.0.1:8080"}]}`), 0644)).To(Succeed())
+
+				r, err := NewFileResolver(path, time.Millisecond)
+				Expect(err).To(BeNil())
+
+				watchCtx, cancel := context.WithCancel(ctx)
+				updates, err := r.Watch(watchCtx, "user-service")
+				Expect(err).To(BeNil())
+				Eventually(updates).Should(Receive(HaveLen(1)))
+
+				Expect(ioutil.WriteFile(path, []byte(`{"user-service":[{"url":"http://10.0.0.1:8080"},{"url":"http://10.0.0.2:8080"}]}`), 0644)).To(Succeed())
+				Eventually(updates).Should(Receive(HaveLen(2)))
+
+				cancel()
+				Eventually(updates).Should(BeClosed())
+			})
+		})
+		Context("missing file", func() {
+			It("returns an error", func() {
+				_, err := NewFileResolver(filepath.Join(dir, "nope.json"), 0)
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+	// endregion
+
+	// region DNSResolver
+	Describe("DNSResolver", func() {
+		var (
+			r       *DNSResolver
+			lookups int
+			records []*net.SRV
+			fail    bool
+		)
+
+		BeforeEach(func() {
+			lookups = 0
+			fail = false
+			records = []*net.SRV{
+				{Target: "a.example.com.", Port: 8080, Priority: 1, Weight: 10},
+				{Target: "b.example.com.", Port: 8081, Priority: 1, Weight: 5},
+				{Target: "backup.example.com.", Port: 8080, Priority: 2, Weight: 1},
+			}
+			r = NewDNSResolver("example.com", time.Hour)
+			r.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+				lookups++
+				Expect(service).To(Equal("user-service"))
+				Expect(proto).To(Equal("tcp"))
+				Expect(name).To(Equal("example.com"))
+				if fail {
+					return "", nil, errors.New("SERVFAIL")
+				}
+				return "", records, nil
+			}
+		})
+
+		It("uses the best priority records", func() {
+			endpoints, err := r.Resolve(ctx, "user-service")
+			Expect(err).To(BeNil())
+			Expect(endpoints).To(HaveLen(2))
+			Expect(endpoints[0].URL.String()).To(Equal("http://a.example.com:8080"))
+			Expect(endpoints[1].Weight).To(Equal(5))
+		})
+
+		It("caches answers for the ttl", func() {
+			r.Resolve(ctx, "user-service")
+			r.Resolve(ctx, "user-service")
+			Expect(lookups).To(Equal(1))
+		})
+
+		It("serves the previous answer when a lookup fails", func() {
+			r.TTL = time.Nanosecond
+			before, err := r.Resolve(ctx, "user-service")
+			Expect(err).To(BeNil())
+
+			fail = true
+			time.Sleep(time.Millisecond)
+			after, err := r.Resolve(ctx, "user-service")
+			Expect(err).To(BeNil())
+			Expect(after).To(Equal(before))
+			Expect(lookups).To(Equal(2))
+		})
+
+		It("returns the error when there is nothing to fall back on", func() {
+			fail = true
+			_, err := r.Resolve(ctx, "user-service")
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("shares a single lookup per service, outside the lock", func() {
+			var started int32
+			release := make(chan struct{})
+			r.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+				if service == "user-service" {
+					atomic.AddInt32(&started, 1)
+					<-release
+				}
+				return "", records, nil
+			}
+
+			results := make(chan []*Endpoint, 3)
+			for i := 0; i < 3; i++ {
+				go func() {
+					endpoints, _ := r.Resolve(ctx, "user-service")
+					results <- endpoints
+				}()
+			}
+			Eventually(func() int32 { return atomic.LoadInt32(&started) }).Should(Equal(int32(1)))
+
+			// other services resolve while the lookup is blocked
+			endpoints, err := r.Resolve(ctx, "order-service")
+			Expect(err).To(BeNil())
+			Expect(endpoints).To(HaveLen(2))
+
+			close(release)
+			first := <-results
+			Expect(first).To(HaveLen(2))
+			Expect(<-results).To(Equal(first))
+			Expect(<-results).To(Equal(first))
+			Expect(atomic.LoadInt32(&started)).To(Equal(int32(1)))
+		})
+
+		It("keeps the shared lookup going when the caller that started it gives up", func() {
+			release := make(chan struct{})
+			lookupErrs := make(chan error, 1)
+			r.lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+				<-release
+				lookupErrs <- ctx.Err()
+				return "", records, nil
+			}
+
+			first, cancel := context.WithCancel(ctx)
+			firstErr := make(chan error, 1)
+			go func() {
+				_, err := r.Resolve(first, "user-service")
+				firstErr <- err
+			}()
+			Eventually(func() int {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				return len(r.inflight)
+			}).Should(Equal(1))
+
+			second := make(chan []*Endpoint, 1)
+			go func() {
+				endpoints, _ := r.Resolve(ctx, "user-service")
+				second <- endpoints
+			}()
+
+			cancel()
+			Eventually(firstErr).Should(Receive(Equal(context.Canceled)))
+			close(release)
+			Eventually(second).Should(Receive(HaveLen(2)))
+			Expect(<-lookupErrs).To(BeNil())
+		})
+	})
+	// endregion
+
+	// region service endpoints
+	Describe("svc:// endpoints", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/users" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+			pkgEndpointResolver = nil
+		})
+
+		It("resolves the service on every request", func() {
+			path := filepath.Join(dir, "services.json")
+			Expect(ioutil.WriteFile(path, []byte(`{"user-service":[{"url":"`+server.URL+`"}]}`), 0644)).To(Succeed())
+			r, err := NewFileResolver(path, 0)
+			Expect(err).To(BeNil())
+			pkgEndpointResolver = r
+
+			c, err := New(ClientOptions{Endpoint: "svc://user-service/v1/users"})
+			Expect(err).To(BeNil())
+			Expect(c.calledService).To(Equal("user-service"))
+
+			code, err := c.Get(ctx)
+			Expect(err).To(BeNil())
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(c.SelectedEndpoint().String()).To(Equal(server.URL + "/v1/users"))
+		})
+
+		It("requires a resolver", func() {
+			_, err := New(ClientOptions{Endpoint: "svc://user-service/v1/users"})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+	// endregion
+})
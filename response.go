@@ -0,0 +1,96 @@
+package blaster
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Response describes the outcome of a request that was launched
+type Response struct {
+	// StatusCode is the status code returned by the endpoint,
+	// or 0 if no response was received
+	StatusCode int
+
+	// Header holds the response headers
+	Header http.Header
+
+	// Body is the response body, after decompression
+	Body []byte
+
+	// Endpoint is the url the last attempt was sent to
+	Endpoint *url.URL
+
+	// Attempts is the number of attempts made, including failovers
+	Attempts int
+
+	// Duration is the length of time the whole request took
+	Duration time.Duration
+
+	// Timings breaks the last attempt down into its network phases
+	Timings Timings
+
+	// Fault names the faults injected into the last attempt, e.g.
+	// latency+timeout, or is empty if none were, see FaultInjector
+	Fault string
+
+	// redaction and maxBody apply to Dump
+	redaction Redaction
+	maxBody   int
+}
+
+// Response returns the outcome of the request.  It is nil until a
+// request has been launched, or if the request could not be launched.
+func (c *Client) Response() *Response {
+	return c.response
+}
+
+// buildResponse records the outcome of the last attempt
+func (c *Client) buildResponse(response *http.Response, body []byte, attempts int) {
+	r := &Response{
+		Endpoint: c.selectedEndpoint,
+		Attempts: attempts,
+		Body:     body,
+		Timings:  c.Timings(),
+		Fault:    c.fault,
+	}
+	r.redaction, r.maxBody = c.redaction()
+	if response != nil {
+		r.StatusCode = response.StatusCode
+		r.Header = response.Header
+	}
+
+	c.response = r
+}
+
+// Dump renders the response as status line, headers and body, with
+// secrets redacted and the body truncated as configured by RequestLogging
+func (r *Response) Dump() string {
+	if r.StatusCode == 0 {
+		return "no response received"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d %s\n", r.StatusCode, http.StatusText(r.StatusCode))
+
+	header := r.redaction.headers(r.Header)
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+
+	if len(r.Body) > 0 {
+		fmt.Fprintf(&b, "\n%s", truncateBody(r.redaction.body(r.Body), r.maxBody))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,341 @@
+package blaster
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatsdTagFormat is the wire format used for tags
+type StatsdTagFormat int
+
+const (
+	// DogStatsDTags sends tags the DogStatsD way, e.g. name:1|c|#key:value
+	DogStatsDTags StatsdTagFormat = iota
+
+	// PlainStatsdTags is for servers without tag support.  Each key:value
+	// tag is folded into the name, e.g. name.key.value:1|c
+	PlainStatsdTags
+)
+
+const (
+	defaultStatsdPacketSize    = 1432 // fits an ethernet MTU with room for the IP and UDP headers
+	defaultStatsdFlushInterval = 100 * time.Millisecond
+	defaultStatsdQueueSize     = 64
+)
+
+// errStatsdClosed is returned once the client has been closed
+var errStatsdClosed = errors.New("statsd client is closed")
+
+// StatsdConfig configures a StatsdClient
+type StatsdConfig struct {
+	// Address is the host:port of the statsd server
+	Address string
+
+	// Prefix is prepended to every metric name, e.g. "myservice."
+	Prefix string
+
+	// Tags are sent with every metric
+	Tags []string
+
+	// TagFormat defaults to DogStatsDTags
+	TagFormat StatsdTagFormat
+
+	// MaxPacketSize is the largest UDP payload sent.  Defaults to 1432 bytes.
+	MaxPacketSize int
+
+	// FlushInterval is how often a partly filled packet is sent.  Defaults to 100ms.
+	FlushInterval time.Duration
+
+	// QueueSize is the number of full packets that may wait to be sent.
+	// Once the queue is full, packets are dropped rather than blocking
+	// the caller.  Defaults to 64.
+	QueueSize int
+
+	// Clock ticks the flush interval.  Defaults to Defaults.Clock.
+	Clock Clock
+}
+
+// StatsdClient is a buffered UDP statsd client.  Metrics are batched into
+// packets of up to MaxPacketSize bytes, which are sent from a background
+// goroutine, so recording a metric never waits on the network.  It
+// satisfies StatsdClientPrototype, and has the Gauge and Histogram
+// functions used by NewStatsdMetrics.
+type StatsdClient struct {
+	conn      net.Conn
+	prefix    string
+	tags      []string
+	tagFormat StatsdTagFormat
+	maxSize   int
+
+	mu     sync.Mutex
+	buf    []byte
+	closed bool
+
+	queue   chan []byte
+	pool    sync.Pool
+	stop    chan struct{}
+	done    sync.WaitGroup
+	dropped int64
+}
+
+// NewStatsdClient returns a client that sends to the configured address,
+// and starts its background flushing.  Close it to send what is buffered.
+func NewStatsdClient(config StatsdConfig) (*StatsdClient, error) {
+	if config.MaxPacketSize <= 0 {
+		config.MaxPacketSize = defaultStatsdPacketSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultStatsdFlushInterval
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultStatsdQueueSize
+	}
+
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &StatsdClient{
+		conn:      conn,
+		prefix:    config.Prefix,
+		tags:      config.Tags,
+		tagFormat: config.TagFormat,
+		maxSize:   config.MaxPacketSize,
+		queue:     make(chan []byte, config.QueueSize),
+		stop:      make(chan struct{}),
+	}
+	c.pool.New = func() interface{} {
+		return make([]byte, 0, c.maxSize)
+	}
+	c.buf = c.pool.Get().([]byte)
+
+	c.done.Add(2)
+	go c.send()
+	go c.flushEvery(clockOrDefault(config.Clock).NewTicker(config.FlushInterval))
+
+	return c, nil
+}
+
+// Incr implements StatsdClientPrototype
+func (c *StatsdClient) Incr(name string, tags []string, rate float64) error {
+	return c.Count(name, 1, tags, rate)
+}
+
+// Count adds value to a counter
+func (c *StatsdClient) Count(name string, value int64, tags []string, rate float64) error {
+	return c.record(name, "c", func(b []byte) []byte {
+		return strconv.AppendInt(b, value, 10)
+	}, tags, rate)
+}
+
+// Timing implements StatsdClientPrototype.  The value is sent in milliseconds.
+func (c *StatsdClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return c.record(name, "ms", func(b []byte) []byte {
+		return strconv.AppendFloat(b, value.Seconds()*1000, 'f', -1, 64)
+	}, tags, rate)
+}
+
+// Gauge sets a gauge
+func (c *StatsdClient) Gauge(name string, value float64, tags []string, rate float64) error {
+	return c.record(name, "g", func(b []byte) []byte {
+		return strconv.AppendFloat(b, value, 'f', -1, 64)
+	}, tags, rate)
+}
+
+// Histogram records a value in a histogram.  Plain statsd has no
+// histograms, so there it is sent as a timing.
+func (c *StatsdClient) Histogram(name string, value float64, tags []string, rate float64) error {
+	metricType := "h"
+	if c.tagFormat == PlainStatsdTags {
+		metricType = "ms"
+	}
+
+	return c.record(name, metricType, func(b []byte) []byte {
+		return strconv.AppendFloat(b, value, 'f', -1, 64)
+	}, tags, rate)
+}
+
+// Dropped returns the number of packets dropped because the queue was full
+// or the server could not be written to
+func (c *StatsdClient) Dropped() int64 {
+	return atomic.LoadInt64(&c.dropped)
+}
+
+// Flush hands the buffered metrics to the background sender
+func (c *StatsdClient) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.flushLocked()
+	}
+}
+
+// Close sends what is buffered and releases the connection.
+// It is safe to call more than once.
+func (c *StatsdClient) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.flushLocked()
+	c.mu.Unlock()
+
+	close(c.stop)
+	close(c.queue)
+	c.done.Wait()
+
+	return c.conn.Close()
+}
+
+// record samples, formats and buffers a single metric.  A rate of 0 or
+// less, or 1 or more, means every value is sent.
+func (c *StatsdClient) record(name, metricType string, appendValue func([]byte) []byte, tags []string, rate float64) error {
+	sampled := rate > 0 && rate < 1
+	if sampled && rand.Float64() >= rate {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return errStatsdClosed
+	}
+
+	start := len(c.buf)
+	if start > 0 {
+		c.buf = append(c.buf, '\n')
+	}
+	c.buf = c.appendName(c.buf, name, tags)
+	c.buf = append(c.buf, ':')
+	c.buf = appendValue(c.buf)
+	c.buf = append(c.buf, '|')
+	c.buf = append(c.buf, metricType...)
+	if sampled {
+		c.buf = append(c.buf, "|@"...)
+		c.buf = strconv.AppendFloat(c.buf, rate, 'f', -1, 64)
+	}
+	if c.tagFormat == DogStatsDTags {
+		c.buf = appendDogStatsDTags(c.buf, c.tags, tags)
+	}
+
+	// the line did not fit, send the packet without it and start a new one
+	if len(c.buf) > c.maxSize && start > 0 {
+		line := append(c.pool.Get().([]byte)[:0], c.buf[start+1:]...)
+		c.queueLocked(c.buf[:start])
+		c.buf = line
+	}
+
+	return nil
+}
+
+// appendName appends the prefixed name, with the tags folded in for plain statsd
+func (c *StatsdClient) appendName(b []byte, name string, tags []string) []byte {
+	b = append(b, c.prefix...)
+	b = append(b, name...)
+	if c.tagFormat != PlainStatsdTags {
+		return b
+	}
+
+	for _, list := range [][]string{c.tags, tags} {
+		for _, tag := range list {
+			for _, part := range strings.SplitN(tag, ":", 2) {
+				b = append(b, '.')
+				b = appendSanitized(b, part)
+			}
+		}
+	}
+
+	return b
+}
+
+// appendDogStatsDTags appends the |#tag,tag section, if there are any tags
+func appendDogStatsDTags(b []byte, lists ...[]string) []byte {
+	first := true
+	for _, list := range lists {
+		for _, tag := range list {
+			if first {
+				b = append(b, "|#"...)
+				first = false
+			} else {
+				b = append(b, ',')
+			}
+			b = append(b, tag...)
+		}
+	}
+
+	return b
+}
+
+// appendSanitized appends s with the characters that have a meaning
+// in the statsd line protocol, or in a metric path, replaced by _
+func appendSanitized(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '.', ':', '|', '@', '#', ',', ' ', '\n':
+			b = append(b, '_')
+		default:
+			b = append(b, s[i])
+		}
+	}
+
+	return b
+}
+
+// flushLocked queues the current packet.  c.mu must be held.
+func (c *StatsdClient) flushLocked() {
+	if len(c.buf) == 0 {
+		return
+	}
+
+	c.queueLocked(c.buf)
+	c.buf = c.pool.Get().([]byte)[:0]
+}
+
+// queueLocked hands a packet to the sender, or drops it if the queue
+// is full, so the caller never blocks.  c.mu must be held.
+func (c *StatsdClient) queueLocked(packet []byte) {
+	select {
+	case c.queue <- packet:
+	default:
+		atomic.AddInt64(&c.dropped, 1)
+		c.pool.Put(packet[:0])
+	}
+}
+
+// send writes queued packets until the queue is closed
+func (c *StatsdClient) send() {
+	defer c.done.Done()
+
+	for packet := range c.queue {
+		if _, err := c.conn.Write(packet); err != nil {
+			atomic.AddInt64(&c.dropped, 1)
+		}
+		c.pool.Put(packet[:0])
+	}
+}
+
+// flushEvery flushes partly filled packets on every tick
+func (c *StatsdClient) flushEvery(ticker Ticker) {
+	defer c.done.Done()
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			c.Flush()
+		case <-c.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package blaster
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StatsdClient", func() {
+	var (
+		listener net.PacketConn
+		config   StatsdConfig
+		client   *StatsdClient
+	)
+
+	// read returns the next packet, or "" if none arrives in time
+	read := func() string {
+		buf := make([]byte, 65536)
+		listener.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			return ""
+		}
+		return string(buf[:n])
+	}
+
+	BeforeEach(func() {
+		var err error
+		listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).To(BeNil())
+
+		config = StatsdConfig{
+			Address:       listener.LocalAddr().String(),
+			FlushInterval: time.Hour,
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		client, err = NewStatsdClient(config)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		listener.Close()
+	})
+
+	// region format
+	Describe("format", func() {
+		It("writes every metric type with DogStatsD tags", func() {
+			client.Incr("requests", []string{"route:/v1"}, 1)
+			client.Count("bytes", 42, nil, 1)
+			client.Timing("duration", 1500*time.Microsecond, nil, 1)
+			client.Gauge("in_flight", 3, nil, 1)
+			client.Histogram("size", 2.5, nil, 1)
+			client.Flush()
+
+			Expect(strings.Split(read(), "\n")).To(Equal([]string{
+				"requests:1|c|#route:/v1",
+				"bytes:42|c",
+				"duration:1.5|ms",
+				"in_flight:3|g",
+				"size:2.5|h",
+			}))
+		})
+
+		Context("with a prefix and global tags", func() {
+			BeforeEach(func() {
+				config.Prefix = "svc."
+				config.Tags = []string{"env:test"}
+			})
+
+			It("applies both", func() {
+				client.Incr("requests", []string{"route:/v1"}, 1)
+				client.Flush()
+				Expect(read()).To(Equal("svc.requests:1|c|#env:test,route:/v1"))
+			})
+		})
+
+		Context("with plain statsd tags", func() {
+			BeforeEach(func() {
+				config.TagFormat = PlainStatsdTags
+				config.Tags = []string{"env:test"}
+			})
+
+			It("folds the tags into the name", func() {
+				client.Incr("requests", []string{"route:/v1/users", "verb:GET"}, 1)
+				client.Histogram("size", 10, nil, 1)
+				client.Flush()
+				Expect(read()).To(Equal("requests.env.test.route./v1/users.verb.GET:1|c\nsize.env.test:10|ms"))
+			})
+
+			It("escapes characters that break the protocol", func() {
+				client.Incr("requests", []string{"host:10.0.0.1:8080"}, 1)
+				client.Flush()
+				Expect(read()).To(Equal("requests.env.test.host.10_0_0_1_8080:1|c"))
+			})
+		})
+	})
+	// endregion
+
+	// region sampling
+	Describe("sampling", func() {
+		It("sends everything for a rate of 0 or 1", func() {
+			client.Incr("a", nil, 0)
+			client.Incr("b", nil, 1)
+			client.Flush()
+			Expect(read()).To(Equal("a:1|c\nb:1|c"))
+		})
+		It("samples and marks the rate", func() {
+			for i := 0; i < 1000; i++ {
+				client.Incr("a", nil, 0.1)
+			}
+			client.Close()
+
+			lines := 0
+			for packet := read(); packet != ""; packet = read() {
+				for _, line := range strings.Split(packet, "\n") {
+					Expect(line).To(Equal("a:1|c|@0.1"))
+					lines++
+				}
+			}
+			Expect(lines).To(BeNumerically("~", 100, 50))
+		})
+	})
+	// endregion
+
+	// region batching
+	Describe("batching", func() {
+		BeforeEach(func() {
+			config.MaxPacketSize = 64
+		})
+
+		It("never sends a packet larger than the max size", func() {
+			for i := 0; i < 20; i++ {
+				client.Incr("requests.total", []string{"route:/v1"}, 1)
+			}
+			client.Close()
+
+			lines := 0
+			for packet := read(); packet != ""; packet = read() {
+				Expect(len(packet)).To(BeNumerically("<=", 64))
+				lines += len(strings.Split(packet, "\n"))
+			}
+			Expect(lines).To(Equal(20))
+		})
+
+		Context("with a short flush interval", func() {
+			BeforeEach(func() {
+				config.FlushInterval = 10 * time.Millisecond
+			})
+
+			It("sends partly filled packets", func() {
+				client.Incr("requests", nil, 1)
+				Expect(read()).To(Equal("requests:1|c"))
+			})
+		})
+	})
+	// endregion
+
+	// region lifecycle
+	Describe("lifecycle", func() {
+		It("refuses metrics once closed", func() {
+			Expect(client.Close()).To(Succeed())
+			Expect(client.Close()).To(Succeed())
+			Expect(client.Incr("a", nil, 1)).ToNot(Succeed())
+		})
+
+		Context("when the queue is full", func() {
+			BeforeEach(func() {
+				config.MaxPacketSize = 16
+				config.QueueSize = 1
+			})
+
+			It("drops packets rather than blocking", func() {
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					for i := 0; i < 10000; i++ {
+						client.Incr("requests", nil, 1)
+					}
+				}()
+				Eventually(done).Should(BeClosed())
+			})
+		})
+	})
+	// endregion
+
+	// region client
+	Describe("with the client", func() {
+		It("reports through NewStatsdMetrics", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+			pkgRequireHeaders = false
+
+			c, _ := New(ClientOptions{
+				Endpoint:      server.URL,
+				CalledService: "user-service",
+				Metrics:       NewStatsdMetrics(client, "api", nil),
+			})
+			_, err := c.Get(context.Background())
+			Expect(err).To(BeNil())
+			client.Flush()
+
+			packet := read()
+			Expect(packet).To(ContainSubstring("api.in_flight:1|g"))
+			Expect(packet).To(MatchRegexp(`api:[0-9.]+\|ms\|#http-verb:GET,called-service:user-service,route:,response-code:204,response-type:2xx`))
+		})
+	})
+	// endregion
+})
@@ -0,0 +1,227 @@
+package blaster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultMaxTagValues is the number of distinct values a context tag may
+// take before new values are folded into overflowTagValue
+const defaultMaxTagValues = 100
+
+// defaultMaxTagKeys is the number of distinct context tag keys that are
+// reported before tags with new keys are dropped
+const defaultMaxTagKeys = 20
+
+// overflowTagValue replaces the values of a context tag past its limit
+const overflowTagValue = "other"
+
+// tagOutcome is what the guard did with a context tag
+type tagOutcome int
+
+const (
+	// tagKept means the tag is reported as is
+	tagKept tagOutcome = iota
+
+	// tagFolded means the value of the tag is reported as overflowTagValue
+	tagFolded
+
+	// tagDropped means the tag is not reported
+	tagDropped
+)
+
+// tagsContextKey is the context key for the statsd tags
+type tagsContextKey struct{}
+
+// logFieldsContextKey is the context key for the log fields
+type logFieldsContextKey struct{}
+
+// WithTags returns a context that carries statsd tags, given as
+// "key:value", e.g. the tier of the tenant or a feature flag.  The tags
+// are added to the statsd metrics of every request made with the
+// context, on top of the tags already carried, and passed to Metrics as
+// MetricLabels.Tags.
+//
+// Every distinct tag is a new series in the metrics backend, so never
+// tag with per-request values such as user or request ids; use
+// WithLogFields for those.  To bound the damage, only the keys in
+// Defaults.AllowedTagKeys are reported if it is set, and only the first
+// Defaults.MaxTagKeys keys otherwise.  Each key may take up to
+// Defaults.MaxTagValues distinct values, further values are reported as
+// "key:other".  See DroppedContextTags.
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	carried := TagsFromContext(ctx)
+	merged := make([]string, 0, len(carried)+len(tags))
+	merged = append(merged, carried...)
+	merged = append(merged, tags...)
+	return context.WithValue(ctx, tagsContextKey{}, merged)
+}
+
+// TagsFromContext returns the statsd tags carried by the context, if any
+func TagsFromContext(ctx context.Context) []string {
+	tags, _ := ctx.Value(tagsContextKey{}).([]string)
+	return tags
+}
+
+// WithLogFields returns a context that carries log fields.  The fields
+// are added to every line logged for requests made with the context, on
+// top of the fields already carried.
+func WithLogFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	carried := LogFieldsFromContext(ctx)
+	merged := make(map[string]interface{}, len(carried)+len(fields))
+	for k, v := range carried {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, logFieldsContextKey{}, merged)
+}
+
+// LogFieldsFromContext returns the log fields carried by the context, if any
+func LogFieldsFromContext(ctx context.Context) map[string]interface{} {
+	fields, _ := ctx.Value(logFieldsContextKey{}).(map[string]interface{})
+	return fields
+}
+
+// tagGuard keeps track of the context tag keys and of the distinct
+// values of each, so that a tag fed with e.g. user ids cannot flood the
+// metrics backend
+type tagGuard struct {
+	mu      sync.Mutex
+	values  map[string]map[string]struct{}
+	warned  map[string]bool
+	dropped uint64
+}
+
+// tagLimits bounds the context tags admitted by the guard
+type tagLimits struct {
+	maxKeys   int
+	maxValues int
+	allowed   []string
+}
+
+// pkgTagGuard is shared by all clients, since they report to the same backend
+var pkgTagGuard = newTagGuard()
+
+// newTagGuard creates an empty guard
+func newTagGuard() *tagGuard {
+	return &tagGuard{values: map[string]map[string]struct{}{}, warned: map[string]bool{}}
+}
+
+// DroppedContextTags returns the number of context tags that were dropped
+// or had their value folded into "other" since the process started, see
+// WithTags
+func DroppedContextTags() uint64 {
+	pkgTagGuard.mu.Lock()
+	defer pkgTagGuard.mu.Unlock()
+
+	return pkgTagGuard.dropped
+}
+
+// admit returns the tag, or the tag with its value replaced by
+// overflowTagValue once its key has taken the max distinct values, or
+// drops it when its key is not allowed or past the max keys.  first tells
+// that a tag of the key was folded or dropped for the first time.
+func (g *tagGuard) admit(tag string, limits tagLimits) (admitted string, outcome tagOutcome, first bool) {
+	key, value := tag, ""
+	if i := strings.Index(tag, ":"); i >= 0 {
+		key, value = tag[:i], tag[i+1:]
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	values, ok := g.values[key]
+	if !ok {
+		if !tagKeyAllowed(key, limits.allowed) || (len(limits.allowed) == 0 && len(g.values) >= limits.maxKeys) {
+			return "", tagDropped, g.countDropped(key)
+		}
+		values = map[string]struct{}{}
+		g.values[key] = values
+	}
+	if _, ok := values[value]; ok {
+		return tag, tagKept, false
+	}
+	if len(values) >= limits.maxValues {
+		return fmt.Sprintf("%s:%s", key, overflowTagValue), tagFolded, g.countDropped(key)
+	}
+
+	values[value] = struct{}{}
+	return tag, tagKept, false
+}
+
+// countDropped counts a folded or dropped tag, and tells whether it is the
+// first of its key.  Must be called with the lock held.
+func (g *tagGuard) countDropped(key string) bool {
+	g.dropped++
+	first := !g.warned[key]
+	g.warned[key] = true
+	return first
+}
+
+// tagKeyAllowed tells whether the key is in the allowlist, if there is one
+func tagKeyAllowed(key string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, k := range allowed {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyContextTags keeps the statsd tags carried by the context, guarded
+// against unbounded cardinality
+func (c *Client) applyContextTags(ctx context.Context) {
+	tags := TagsFromContext(ctx)
+	if len(tags) == 0 {
+		c.contextTags = nil
+		return
+	}
+
+	limits := tagLimits{maxKeys: pkgMaxTagKeys, maxValues: pkgMaxTagValues, allowed: pkgAllowedTagKeys}
+	if limits.maxKeys <= 0 {
+		limits.maxKeys = defaultMaxTagKeys
+	}
+	if limits.maxValues <= 0 {
+		limits.maxValues = defaultMaxTagValues
+	}
+
+	c.contextTags = make([]string, 0, len(tags))
+	for _, tag := range tags {
+		admitted, outcome, first := pkgTagGuard.admit(tag, limits)
+		if first {
+			fields := map[string]interface{}{
+				"type": NAME,
+				"tag":  tag,
+			}
+			if outcome == tagFolded {
+				fields["tag"] = admitted
+				c.logger.WithFields(fields).Warnf("context tag exceeded %d distinct values, further values are reported as %s", limits.maxValues, overflowTagValue)
+			} else {
+				c.logger.WithFields(fields).Warnf("context tag dropped, its key is not allowed or past the first %d keys", limits.maxKeys)
+			}
+		}
+		if outcome != tagDropped {
+			c.contextTags = append(c.contextTags, admitted)
+		}
+	}
+}
+
+// tagsWith returns the client tags, the context tags, the fault tag and
+// the given tags
+func (c *Client) tagsWith(tags ...string) []string {
+	all := make([]string, 0, len(c.statsdTags)+len(c.contextTags)+len(tags)+1)
+	all = append(all, c.statsdTags...)
+	all = append(all, c.contextTags...)
+	if c.fault != "" {
+		all = append(all, fmt.Sprintf("fault:%s", c.fault))
+	}
+	return append(all, tags...)
+}
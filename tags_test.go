@@ -0,0 +1,201 @@
+package blaster
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/InVisionApp/go-logger/shims/testlog"
+	"github.com/joelhill/go-rest-http-blaster/fakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Context tags and log fields", func() {
+	var (
+		ctx    context.Context
+		server *httptest.Server
+		statsd *fakes.FakeStatsdClientPrototype
+		logger *testlog.TestLogger
+	)
+
+	// durationTags returns the tags of the last request duration timing
+	durationTags := func() []string {
+		var tags []string
+		for i := 0; i < statsd.TimingCallCount(); i++ {
+			name, _, t, _ := statsd.TimingArgsForCall(i)
+			if name == "api" {
+				tags = t
+			}
+		}
+		return tags
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pkgRequireHeaders = false
+		pkgTagGuard = newTagGuard()
+		statsd = &fakes.FakeStatsdClientPrototype{}
+		logger = testlog.New()
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		pkgMaxTagValues = 0
+		pkgMaxTagKeys = 0
+		pkgAllowedTagKeys = nil
+	})
+
+	// region context
+
+	It("carries tags on top of the ones already carried", func() {
+		Expect(TagsFromContext(ctx)).To(BeEmpty())
+
+		outer := WithTags(ctx, "tier:gold")
+		inner := WithTags(outer, "flag:new-checkout")
+		Expect(TagsFromContext(outer)).To(Equal([]string{"tier:gold"}))
+		Expect(TagsFromContext(inner)).To(Equal([]string{"tier:gold", "flag:new-checkout"}))
+	})
+
+	It("carries log fields on top of the ones already carried", func() {
+		Expect(LogFieldsFromContext(ctx)).To(BeEmpty())
+
+		outer := WithLogFields(ctx, map[string]interface{}{"tenant": "acme", "tier": "gold"})
+		inner := WithLogFields(outer, map[string]interface{}{"tier": "silver"})
+		Expect(LogFieldsFromContext(outer)).To(Equal(map[string]interface{}{"tenant": "acme", "tier": "gold"}))
+		Expect(LogFieldsFromContext(inner)).To(Equal(map[string]interface{}{"tenant": "acme", "tier": "silver"}))
+	})
+
+	// endregion
+
+	// region reporting
+
+	It("adds the context tags to the statsd metrics", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.SetStatsdDelegate(statsd, "api", []string{"env:test"})
+		c.Get(WithTags(ctx, "tier:gold"))
+
+		tags := durationTags()
+		Expect(tags).To(ContainElement("env:test"))
+		Expect(tags).To(ContainElement("tier:gold"))
+		Expect(tags).To(ContainElement("http-verb:GET"))
+
+		for i := 0; i < statsd.TimingCallCount(); i++ {
+			_, _, t, _ := statsd.TimingArgsForCall(i)
+			Expect(t).To(ContainElement("tier:gold"))
+		}
+	})
+
+	It("only adds the context tags to the request made with the context", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.SetStatsdDelegate(statsd, "api", nil)
+		c.Get(WithTags(ctx, "tier:gold"))
+		c.Get(ctx)
+		Expect(durationTags()).ToNot(ContainElement("tier:gold"))
+	})
+
+	It("adds the context tags to the labels of the metrics", func() {
+		metrics := &recordingMetrics{}
+		c, _ := New(ClientOptions{Endpoint: server.URL, Metrics: metrics})
+		c.Get(WithTags(ctx, "tier:gold", "flag:new-checkout"))
+		c.Get(ctx)
+
+		Expect(metrics.durations).To(HaveLen(2))
+		Expect(metrics.durations[0].ContextTags()).To(Equal([]string{"tier:gold", "flag:new-checkout"}))
+		Expect(metrics.durations[1].ContextTags()).To(BeEmpty())
+
+		NewStatsdMetrics(statsd, "api", nil).Retry(metrics.durations[0])
+		_, tags, _ := statsd.IncrArgsForCall(0)
+		Expect(tags).To(ContainElement("tier:gold"))
+		Expect(tags).To(ContainElement("flag:new-checkout"))
+	})
+
+	It("adds the context fields to the log lines of the request", func() {
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger})
+		c.Get(WithLogFields(ctx, map[string]interface{}{"tenant": "acme"}))
+
+		for _, line := range strings.Split(strings.TrimSpace(string(logger.Bytes())), "\n") {
+			Expect(line).To(ContainSubstring("tenant=acme"))
+		}
+
+		logger.Reset()
+		c.Get(ctx)
+		Expect(string(logger.Bytes())).ToNot(ContainSubstring("tenant=acme"))
+	})
+
+	// endregion
+
+	// region cardinality
+
+	It("folds the values of a tag past its limit", func() {
+		pkgMaxTagValues = 2
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger})
+		c.SetStatsdDelegate(statsd, "api", nil)
+
+		for _, user := range []string{"1", "2", "1", "3", "4"} {
+			c.Get(WithTags(ctx, "user:"+user, "tier:gold"))
+		}
+
+		var users []string
+		for i := 0; i < statsd.TimingCallCount(); i++ {
+			name, _, tags, _ := statsd.TimingArgsForCall(i)
+			if name != "api" {
+				continue
+			}
+			Expect(tags).To(ContainElement("tier:gold"))
+			for _, tag := range tags {
+				if strings.HasPrefix(tag, "user:") {
+					users = append(users, tag)
+				}
+			}
+		}
+		Expect(users).To(Equal([]string{"user:1", "user:2", "user:1", "user:other", "user:other"}))
+		Expect(strings.Count(string(logger.Bytes()), "context tag exceeded")).To(Equal(1))
+	})
+
+	It("defaults to 100 values per tag", func() {
+		guard := newTagGuard()
+		limits := tagLimits{maxKeys: defaultMaxTagKeys, maxValues: defaultMaxTagValues}
+		for i := 0; i < defaultMaxTagValues; i++ {
+			admitted, outcome, _ := guard.admit("user:"+strings.Repeat("x", i+1), limits)
+			Expect(admitted).ToNot(Equal("user:other"))
+			Expect(outcome).To(Equal(tagKept))
+		}
+		admitted, outcome, first := guard.admit("user:y", limits)
+		Expect(admitted).To(Equal("user:other"))
+		Expect(outcome).To(Equal(tagFolded))
+		Expect(first).To(BeTrue())
+		_, _, first = guard.admit("user:z", limits)
+		Expect(first).To(BeFalse())
+	})
+
+	It("drops the tags with keys past the limit and counts them", func() {
+		pkgMaxTagKeys = 2
+		c, _ := New(ClientOptions{Endpoint: server.URL, Logger: logger})
+		c.SetStatsdDelegate(statsd, "api", nil)
+
+		c.Get(WithTags(ctx, "tier:gold", "flag:new", "request:1", "request:2"))
+		Expect(durationTags()).To(ContainElement("tier:gold"))
+		Expect(durationTags()).To(ContainElement("flag:new"))
+		Expect(durationTags()).ToNot(ContainElement(ContainSubstring("request:")))
+		Expect(DroppedContextTags()).To(Equal(uint64(2)))
+		Expect(strings.Count(string(logger.Bytes()), "context tag dropped")).To(Equal(1))
+	})
+
+	It("only reports the allowed tag keys", func() {
+		pkgAllowedTagKeys = []string{"tier"}
+		c, _ := New(ClientOptions{Endpoint: server.URL})
+		c.SetStatsdDelegate(statsd, "api", nil)
+
+		c.Get(WithTags(ctx, "tier:gold", "user:42"))
+		Expect(durationTags()).To(ContainElement("tier:gold"))
+		Expect(durationTags()).ToNot(ContainElement("user:42"))
+		Expect(DroppedContextTags()).To(Equal(uint64(1)))
+	})
+
+	// endregion
+})